@@ -0,0 +1,382 @@
+// Package config loads vcrlive settings from a YAML file of named profiles with environment variable overrides
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvConfig names the configuration file when -config is not given
+	EnvConfig = "VCRLIVE_CONFIG"
+
+	// EnvProfile names the profile when -profile is not given
+	EnvProfile = "VCRLIVE_PROFILE"
+
+	// DefaultFile is used if it exists and no file is named
+	DefaultFile = "vcrlive.yaml"
+)
+
+// Profile is one complete set of vcrlive settings
+type Profile struct {
+	URL         string            `yaml:"url"`      // POST payloads here
+	File        string            `yaml:"file"`     // test data instead of the simulator
+	Wait        time.Duration     `yaml:"wait"`     // wait for iRacing data
+	MinInterval time.Duration     `yaml:"interval"` // minimum time between posts when positions change
+	Refresh     time.Duration     `yaml:"refresh"`  // post at least this often
+	Redact      bool              `yaml:"redact"`
+	Supervise   bool              `yaml:"supervise"`
+	Listen      string            `yaml:"listen"`     // local server address for serve
+	Metrics     string            `yaml:"metrics"`    // serve Prometheus metrics on this address, off if empty
+	TrackMaps   string            `yaml:"track_maps"` // directory of learned track outlines for serve
+	LogLevel    string            `yaml:"log_level"`  // debug, info, warn or error
+	LogFormat   string            `yaml:"log_format"` // text or json
+	Auth        Auth              `yaml:"auth"`       // for the URL
+	Headers     map[string]string `yaml:"headers"`
+	Sinks       []Sink            `yaml:"sinks"`
+
+	Championship *Championship `yaml:"championship"` // score races into a championship table, off if absent
+}
+
+// Championship rules and where the results of each round are kept
+type Championship struct {
+	History    string        `yaml:"history"` // JSON file of the rounds so far, updated after each race
+	Points     []int         `yaml:"points"`  // for 1st, 2nd, 3rd... in class
+	Classes    map[int][]int `yaml:"classes"` // points tables for particular classes, by car class ID
+	FastestLap int           `yaml:"fastest_lap"`
+	Pole       int           `yaml:"pole"`
+	Drops      int           `yaml:"drops"` // worst rounds not counted
+	Penalties  []Penalty     `yaml:"penalties"`
+}
+
+// Penalty deducts points in the championship
+type Penalty struct {
+	UserID   int    `yaml:"user_id"`
+	SeasonID int    `yaml:"season_id"` // any season if omitted
+	RaceWeek int    `yaml:"race_week"`
+	Points   int    `yaml:"points"`
+	Reason   string `yaml:"reason"`
+}
+
+// Auth for an HTTP endpoint
+type Auth struct {
+	Type     string `yaml:"type"` // basic, bearer or apikey
+	UserName string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+	Key      string `yaml:"key"`
+	Prefix   string `yaml:"prefix"`
+}
+
+// Sink is an additional destination, either a spec as accepted by -sink or the individual fields
+type Sink struct {
+	Name     string            `yaml:"name"`
+	Spec     string            `yaml:"spec"`
+	Type     string            `yaml:"type"`
+	Target   string            `yaml:"target"`
+	Topic    string            `yaml:"topic"`
+	Kinds    []string          `yaml:"kinds"`
+	Sessions []string          `yaml:"sessions"`
+	Interval time.Duration     `yaml:"interval"`
+	Encoding string            `yaml:"encoding"` // json, cbor or msgpack
+	Compress string            `yaml:"compress"` // gzip or zstd, HTTP only
+	Timeout  time.Duration     `yaml:"timeout"`  // per message, HTTP only
+	Rate     float64           `yaml:"rate"`     // requests per second, HTTP only
+	Breaker  int               `yaml:"breaker"`  // failures in a row opening the circuit, HTTP only
+	Cooldown time.Duration     `yaml:"cooldown"` // time the circuit stays open, HTTP only
+	Auth     Auth              `yaml:"auth"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+// File is the layout of the configuration file. Every profile starts from defaults.
+type File struct {
+	DefaultProfile string             `yaml:"default_profile"`
+	Defaults       Profile            `yaml:"defaults"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// document is File with the profiles kept as YAML so they can be layered over the defaults
+type document struct {
+	DefaultProfile string               `yaml:"default_profile"`
+	Defaults       yaml.Node            `yaml:"defaults"`
+	Profiles       map[string]yaml.Node `yaml:"profiles"`
+}
+
+// Default returns the built in settings, the same as the command line defaults
+func Default() Profile {
+	return Profile{
+		Wait:        100 * time.Millisecond, //nolint:mnd // default
+		MinInterval: time.Second,
+		Refresh:     10 * time.Second, //nolint:mnd // default
+		Listen:      "127.0.0.1:8080",
+		TrackMaps:   "trackmaps",
+		LogLevel:    "info",
+		LogFormat:   "text",
+	}
+}
+
+// Path returns the configuration file to use, from the -config flag, the environment or the default file if it
+// exists. An empty path means there is no configuration file.
+func Path(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+
+	if env := os.Getenv(EnvConfig); env != "" {
+		return env
+	}
+
+	if _, err := os.Stat(DefaultFile); err == nil {
+		return DefaultFile
+	}
+
+	return ""
+}
+
+// Load reads the named profile from path, or the file's default_profile if name is empty, then applies environment
+// variable overrides. With no path only the built in defaults and the environment are used.
+func Load(path string, name string) (*Profile, error) {
+	profile := Default()
+
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path) //nolint:gosec // user supplied configuration
+		if err != nil {
+			return nil, err
+		}
+
+		profile, err = Parse(b, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if name != "" {
+		return nil, fmt.Errorf("profile %q requested but there is no configuration file", name)
+	}
+
+	err := ApplyEnv(&profile, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// Parse decodes a configuration file and returns the named profile layered over the defaults.
+// ${VAR} references are replaced from the environment so secrets need not be stored in the file.
+func Parse(b []byte, name string) (Profile, error) {
+	profile := Default()
+
+	b, err := expand(b)
+	if err != nil {
+		return profile, err
+	}
+
+	// Strict decode first for helpful errors about misspelt settings
+	var file File
+
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&file); err != nil {
+		return profile, fmt.Errorf("invalid configuration, %w", err)
+	}
+
+	var doc document
+
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return profile, err
+	}
+
+	if name == "" {
+		name = doc.DefaultProfile
+	}
+
+	if !doc.Defaults.IsZero() {
+		if err := doc.Defaults.Decode(&profile); err != nil {
+			return profile, err
+		}
+	}
+
+	if name == "" {
+		return profile, nil
+	}
+
+	node, ok := doc.Profiles[name]
+	if !ok {
+		return profile, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(ProfileNames(&file), ", "))
+	}
+
+	if err := node.Decode(&profile); err != nil {
+		return profile, err
+	}
+
+	return profile, nil
+}
+
+// envReference is a ${VAR} reference to the environment
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expand replaces ${VAR} references in the values of a configuration file from the environment. Keys, comments and
+// any other $, such as in a password, are left alone.
+func expand(b []byte) ([]byte, error) {
+	var root yaml.Node
+
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("invalid configuration, %w", err)
+	}
+
+	if !expandValues(&root) {
+		// Unchanged, so errors point at the lines as written
+		return b, nil
+	}
+
+	return yaml.Marshal(&root)
+}
+
+// expandValues replaces references in the scalar values under n, reporting whether any were found
+func expandValues(n *yaml.Node) bool {
+	if n.Kind == yaml.ScalarNode {
+		if !envReference.MatchString(n.Value) {
+			return false
+		}
+
+		n.Value = envReference.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			return os.Getenv(ref[2 : len(ref)-1])
+		})
+
+		// A plain value is typed by what it expands to, e.g. rate: ${RATE}
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+
+		return true
+	}
+
+	expanded := false
+
+	for i, child := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			continue // a key
+		}
+
+		expanded = expandValues(child) || expanded
+	}
+
+	return expanded
+}
+
+// ProfileNames lists the profiles in a file
+func ProfileNames(file *File) []string {
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// envOverride sets a profile field from an environment variable
+type envOverride struct {
+	name string
+	set  func(p *Profile, value string) error
+}
+
+var envOverrides = []envOverride{
+	{"VCRLIVE_URL", func(p *Profile, v string) error { p.URL = v; return nil }},
+	{"VCRLIVE_FILE", func(p *Profile, v string) error { p.File = v; return nil }},
+	{"VCRLIVE_WAIT", func(p *Profile, v string) error { return setDuration(&p.Wait, v) }},
+	{"VCRLIVE_INTERVAL", func(p *Profile, v string) error { return setDuration(&p.MinInterval, v) }},
+	{"VCRLIVE_REFRESH", func(p *Profile, v string) error { return setDuration(&p.Refresh, v) }},
+	{"VCRLIVE_REDACT", func(p *Profile, v string) error { return setBool(&p.Redact, v) }},
+	{"VCRLIVE_SUPERVISE", func(p *Profile, v string) error { return setBool(&p.Supervise, v) }},
+	{"VCRLIVE_LISTEN", func(p *Profile, v string) error { p.Listen = v; return nil }},
+	{"VCRLIVE_METRICS", func(p *Profile, v string) error { p.Metrics = v; return nil }},
+	{"VCRLIVE_TRACK_MAPS", func(p *Profile, v string) error { p.TrackMaps = v; return nil }},
+	{"VCRLIVE_LOG_LEVEL", func(p *Profile, v string) error { p.LogLevel = v; return nil }},
+	{"VCRLIVE_LOG_FORMAT", func(p *Profile, v string) error { p.LogFormat = v; return nil }},
+	{"VCRLIVE_AUTH_TYPE", func(p *Profile, v string) error { p.Auth.Type = v; return nil }},
+	{"VCRLIVE_AUTH_USERNAME", func(p *Profile, v string) error { p.Auth.UserName = v; return nil }},
+	{"VCRLIVE_AUTH_PASSWORD", func(p *Profile, v string) error { p.Auth.Password = v; return nil }},
+	{"VCRLIVE_AUTH_TOKEN", func(p *Profile, v string) error { p.Auth.Token = v; return nil }},
+	{"VCRLIVE_AUTH_KEY", func(p *Profile, v string) error { p.Auth.Key = v; return nil }},
+}
+
+// EnvNames lists the environment variables that override profile settings
+func EnvNames() []string {
+	names := []string{EnvConfig, EnvProfile}
+	for _, o := range envOverrides {
+		names = append(names, o.name)
+	}
+
+	return names
+}
+
+// ApplyEnv overrides profile settings from environment variables, lookup is usually os.LookupEnv
+func ApplyEnv(p *Profile, lookup func(string) (string, bool)) error {
+	var errs []error
+
+	for _, o := range envOverrides {
+		if value, ok := lookup(o.name); ok {
+			if err := o.set(p, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", o.name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func setDuration(d *time.Duration, value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+func setBool(b *bool, value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	*b = parsed
+
+	return nil
+}
+
+// ListProfiles returns the names of the profiles in a configuration file
+func ListProfiles(b []byte) ([]string, error) {
+	var file File
+
+	b, err := expand(b)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid configuration, %w", err)
+	}
+
+	return ProfileNames(&file), nil
+}
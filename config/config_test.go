@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/sinks"
+	"github.com/ianhaycox/vcrlive/standings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+default_profile: league-night
+
+defaults:
+  refresh: 5s
+  headers:
+    X-League: VCR
+
+profiles:
+  league-night:
+    url: https://example.com/live
+    supervise: true
+    auth:
+      type: bearer
+      token: ${TEST_VCR_TOKEN}
+    sinks:
+      - spec: file:///tmp/live.jsonl?kinds=event
+      - type: mqtt
+        target: broker:1883
+        topic: league
+        sessions: [Race]
+        interval: 2s
+      - spec: https://example.com/standings?breaker=10
+        cooldown: 1m
+    championship:
+      history: championship.json
+      points: [25, 18, 15]
+      classes:
+        83: [10, 6, 3]
+      pole: 1
+      drops: 2
+      penalties:
+        - user_id: 996799
+          race_week: 3
+          points: 5
+          reason: Avoidable contact
+
+  testing:
+    file: race.bin
+    redact: true
+    refresh: 1s
+`
+
+func TestParse(t *testing.T) {
+	t.Setenv("TEST_VCR_TOKEN", "secret")
+
+	t.Run("Default profile should be layered over the defaults", func(t *testing.T) {
+		p, err := Parse([]byte(testConfig), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "https://example.com/live", p.URL)
+		assert.Equal(t, 5*time.Second, p.Refresh)
+		assert.Equal(t, 100*time.Millisecond, p.Wait)
+		assert.Equal(t, "trackmaps", p.TrackMaps)
+		assert.True(t, p.Supervise)
+		assert.Equal(t, "secret", p.Auth.Token)
+		assert.Equal(t, map[string]string{"X-League": "VCR"}, p.Headers)
+		assert.NoError(t, p.Validate())
+
+		configs, err := p.SinkConfigs()
+		require.NoError(t, err)
+		require.Len(t, configs, 4)
+		assert.Equal(t, sinks.Auth{Type: "bearer", Token: "secret"}, configs[0].Auth)
+		assert.Equal(t, []string{"event"}, configs[1].Kinds)
+		assert.Equal(t, &sinks.Config{Name: "mqtt:broker:1883", Type: "mqtt", Target: "broker:1883", Topic: "league",
+			SessionTypes: []string{"Race"}, MinInterval: 2 * time.Second, Auth: sinks.Auth{}}, configs[2])
+		assert.Equal(t, 10, configs[3].Breaker)
+		assert.Equal(t, time.Minute, configs[3].Cooldown)
+
+		require.NotNil(t, p.Championship)
+		assert.Equal(t, "championship.json", p.Championship.History)
+		assert.Equal(t, standings.Rules{
+			Points:      []int{25, 18, 15},
+			ClassPoints: map[int][]int{83: {10, 6, 3}},
+			Pole:        1,
+			Drops:       2,
+			Penalties:   []standings.Penalty{{UserID: 996799, RaceWeek: 3, Points: 5, Reason: "Avoidable contact"}},
+		}, p.Championship.Rules())
+	})
+
+	t.Run("Named profile should be selected", func(t *testing.T) {
+		p, err := Parse([]byte(testConfig), "testing")
+		require.NoError(t, err)
+
+		assert.Equal(t, "race.bin", p.File)
+		assert.Equal(t, time.Second, p.Refresh)
+		assert.Empty(t, p.URL)
+	})
+
+	t.Run("Only ${VAR} values should be replaced from the environment", func(t *testing.T) {
+		t.Setenv("TEST_VCR_RATE", "2")
+		t.Setenv("TEST_VCR_HEADER", "a: b # c")
+
+		p, err := Parse([]byte(`
+# Costs $5 a month, see ${TEST_VCR_TOKEN}
+defaults:
+  auth:
+    type: basic
+    username: $USER
+    password: pa$$word
+  headers:
+    X-Token: "${TEST_VCR_TOKEN}-$HOME"
+    X-Header: ${TEST_VCR_HEADER}
+  sinks:
+    - spec: https://example.com/?rate=${TEST_VCR_RATE}
+      rate: ${TEST_VCR_RATE}
+`), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "$USER", p.Auth.UserName)
+		assert.Equal(t, "pa$$word", p.Auth.Password)
+		assert.Equal(t, map[string]string{"X-Token": "secret-$HOME", "X-Header": "a: b # c"}, p.Headers)
+		assert.Equal(t, "https://example.com/?rate=2", p.Sinks[0].Spec)
+		assert.InDelta(t, 2, p.Sinks[0].Rate, 0)
+
+		names, err := ListProfiles([]byte("profiles:\n  pa$$word: {}\n  ${TEST_VCR_TOKEN}: {}\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"${TEST_VCR_TOKEN}", "pa$$word"}, names, "keys are not replaced")
+	})
+
+	t.Run("Unknown profiles and settings should be reported", func(t *testing.T) {
+		_, err := Parse([]byte(testConfig), "qualifying")
+		assert.ErrorContains(t, err, `unknown profile "qualifying", expected one of league-night, testing`)
+
+		_, err = Parse([]byte("profiles:\n  a:\n    refesh: 1s\n"), "a")
+		assert.ErrorContains(t, err, "field refesh not found")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	p := Default()
+	p.Wait = 0
+	p.MinInterval = time.Minute
+	p.URL = "example.com"
+	p.Listen = "8080"
+	p.Metrics = "9090"
+	p.LogLevel = "verbose"
+	p.LogFormat = "xml"
+	p.Auth = Auth{Type: "basic", UserName: "me"}
+	p.Sinks = []Sink{{Type: "carrier-pigeon", Target: "loft"}, {Type: "file"}, {Spec: "mqtt://broker", Kinds: []string{"gossip"}},
+		{Type: "mqtt", Target: "broker", Encoding: "yaml"}, {Type: "stdout", Encoding: "cbor"},
+		{Type: "http", Target: "https://example.com", Compress: "zip"}, {Type: "http", Target: "https://example.com", Rate: -1},
+		{Type: "http", Target: "https://example.com", Breaker: -1}}
+	p.Championship = &Championship{Classes: map[int][]int{83: {10, -5}}, Drops: -1, Penalties: []Penalty{{RaceWeek: 2, Points: 5}}}
+
+	err := p.Validate()
+	assert.ErrorContains(t, err, "wait: must be greater than 0")
+	assert.ErrorContains(t, err, "interval: 1m0s is longer than refresh 10s")
+	assert.ErrorContains(t, err, `url: "example.com" is not an http or https URL`)
+	assert.ErrorContains(t, err, `listen: "8080" is not host:port`)
+	assert.ErrorContains(t, err, `metrics: "9090" is not host:port`)
+	assert.ErrorContains(t, err, `log_level: unknown level "verbose", expected one of debug, info, warn, error`)
+	assert.ErrorContains(t, err, `log_format: unknown format "xml", expected one of text, json`)
+	assert.ErrorContains(t, err, "auth.password: required for basic auth")
+	assert.ErrorContains(t, err, `sinks[0]: type: unknown sink type "carrier-pigeon"`)
+	assert.ErrorContains(t, err, "sinks[1]: target: required for a file sink")
+	assert.ErrorContains(t, err, `sinks[2]: kinds: unknown kind "gossip"`)
+	assert.ErrorContains(t, err, `sinks[3]: encoding: unknown encoding "yaml", expected one of cbor, json, msgpack`)
+	assert.ErrorContains(t, err, "sinks[4]: encoding: cbor can not be printed to the console")
+	assert.ErrorContains(t, err, `sinks[5]: compress: unknown compression "zip", expected one of gzip, zstd`)
+	assert.ErrorContains(t, err, "sinks[6]: rate: can not be negative")
+	assert.ErrorContains(t, err, "sinks[7]: breaker: can not be negative")
+	assert.ErrorContains(t, err, "championship.points: at least one position must score")
+	assert.ErrorContains(t, err, "championship.classes.83: -5 can not be negative")
+	assert.ErrorContains(t, err, "championship.drops: can not be negative")
+	assert.ErrorContains(t, err, "championship.penalties[0].user_id: required")
+
+	valid := Default()
+	assert.NoError(t, valid.Validate())
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vcrlive.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfig), 0o600))
+
+	t.Setenv("VCRLIVE_PROFILE", "testing")
+	t.Setenv("VCRLIVE_REFRESH", "30s")
+	t.Setenv("VCRLIVE_REDACT", "false")
+
+	p, err := Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "race.bin", p.File)
+	assert.Equal(t, 30*time.Second, p.Refresh)
+	assert.False(t, p.Redact)
+
+	t.Setenv("VCRLIVE_WAIT", "soon")
+
+	_, err = Load(path, "testing")
+	assert.ErrorContains(t, err, "VCRLIVE_WAIT")
+
+	_, err = Load("", "testing")
+	assert.ErrorContains(t, err, "no configuration file")
+
+	assert.Equal(t, "given.yaml", Path("given.yaml"))
+}
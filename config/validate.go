@@ -0,0 +1,318 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/sinks"
+	"github.com/ianhaycox/vcrlive/logging"
+	"github.com/ianhaycox/vcrlive/standings"
+)
+
+var (
+	authTypes = []string{sinks.AuthNone, sinks.AuthBasic, sinks.AuthBearer, sinks.AuthAPIKey}
+	kinds     = []string{sinks.KindPositions, sinks.KindEvent}
+)
+
+// Validate checks every setting, returning all the problems found rather than just the first
+func (p *Profile) Validate() error {
+	var errs []error
+
+	check := func(ok bool, field string, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(p.Wait > 0, "wait", "must be greater than 0, e.g. 100ms")
+	check(p.MinInterval >= 0, "interval", "can not be negative")
+	check(p.Refresh >= time.Second, "refresh", "must be at least 1s")
+	check(p.MinInterval <= p.Refresh, "interval", "%s is longer than refresh %s", p.MinInterval, p.Refresh)
+
+	if p.URL != "" {
+		check(isHTTPURL(p.URL), "url", "%q is not an http or https URL", p.URL)
+	}
+
+	if p.Listen != "" {
+		_, _, err := net.SplitHostPort(p.Listen)
+		check(err == nil, "listen", "%q is not host:port, e.g. 127.0.0.1:8080", p.Listen)
+	}
+
+	if p.Metrics != "" {
+		_, _, err := net.SplitHostPort(p.Metrics)
+		check(err == nil, "metrics", "%q is not host:port, e.g. 127.0.0.1:9090", p.Metrics)
+	}
+
+	if p.LogLevel != "" {
+		check(slices.Contains(logging.Levels(), p.LogLevel), "log_level", "unknown level %q, expected one of %s", p.LogLevel,
+			strings.Join(logging.Levels(), ", "))
+	}
+
+	if p.LogFormat != "" {
+		check(slices.Contains(logging.Formats(), p.LogFormat), "log_format", "unknown format %q, expected one of %s", p.LogFormat,
+			strings.Join(logging.Formats(), ", "))
+	}
+
+	errs = append(errs, p.Auth.validate("auth")...)
+
+	for i := range p.Sinks {
+		_, err := p.Sinks[i].Config()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sinks[%d]: %w", i, err))
+		}
+	}
+
+	if p.Championship != nil {
+		errs = append(errs, p.Championship.validate("championship")...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// SinkConfigs returns the URL, if any, followed by the additional sinks
+func (p *Profile) SinkConfigs() ([]*sinks.Config, error) {
+	var configs []*sinks.Config
+
+	if p.URL != "" {
+		cfg, err := sinks.ParseSpec(p.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Auth = p.Auth.sinkAuth()
+		cfg.Headers = p.Headers
+		configs = append(configs, cfg)
+	}
+
+	for i := range p.Sinks {
+		cfg, err := p.Sinks[i].Config()
+		if err != nil {
+			return nil, fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// Config converts the sink to the form used by the sinks package
+func (s *Sink) Config() (*sinks.Config, error) {
+	cfg := &sinks.Config{}
+
+	if s.Spec != "" {
+		if s.Type != "" || s.Target != "" {
+			return nil, errors.New("use either spec or type and target, not both")
+		}
+
+		parsed, err := sinks.ParseSpec(s.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg = parsed
+	} else {
+		if !slices.Contains(sinks.Types(), s.Type) {
+			return nil, fmt.Errorf("type: unknown sink type %q, expected one of %s", s.Type, strings.Join(sinks.Types(), ", "))
+		}
+
+		if s.Type != "stdout" && s.Target == "" {
+			return nil, fmt.Errorf("target: required for a %s sink", s.Type)
+		}
+
+		if s.Type == "http" && !isHTTPURL(s.Target) {
+			return nil, fmt.Errorf("target: %q is not an http or https URL", s.Target)
+		}
+
+		cfg.Type = s.Type
+		cfg.Target = s.Target
+		cfg.Name = s.Type + ":" + s.Target
+	}
+
+	for _, kind := range s.Kinds {
+		if !slices.Contains(kinds, kind) {
+			return nil, fmt.Errorf("kinds: unknown kind %q, expected one of %s", kind, strings.Join(kinds, ", "))
+		}
+	}
+
+	if s.Interval < 0 {
+		return nil, errors.New("interval: can not be negative")
+	}
+
+	if s.Encoding != "" && !slices.Contains(sinks.Encodings(), s.Encoding) {
+		return nil, fmt.Errorf("encoding: unknown encoding %q, expected one of %s", s.Encoding, strings.Join(sinks.Encodings(), ", "))
+	}
+
+	if s.Encoding != "" && s.Encoding != sinks.EncodingJSON && cfg.Type == "stdout" {
+		return nil, fmt.Errorf("encoding: %s can not be printed to the console", s.Encoding)
+	}
+
+	if s.Compress != "" && !slices.Contains(sinks.Compressions(), s.Compress) {
+		return nil, fmt.Errorf("compress: unknown compression %q, expected one of %s", s.Compress, strings.Join(sinks.Compressions(), ", "))
+	}
+
+	if s.Timeout < 0 {
+		return nil, errors.New("timeout: can not be negative")
+	}
+
+	if s.Rate < 0 {
+		return nil, errors.New("rate: can not be negative")
+	}
+
+	if s.Breaker < 0 {
+		return nil, errors.New("breaker: can not be negative")
+	}
+
+	if s.Cooldown < 0 {
+		return nil, errors.New("cooldown: can not be negative")
+	}
+
+	if errs := s.Auth.validate("auth"); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if s.Name != "" {
+		cfg.Name = s.Name
+	}
+
+	if s.Topic != "" {
+		cfg.Topic = s.Topic
+	}
+
+	cfg.Kinds = append(cfg.Kinds, s.Kinds...)
+	cfg.SessionTypes = append(cfg.SessionTypes, s.Sessions...)
+	cfg.Headers = s.Headers
+	cfg.Auth = s.Auth.sinkAuth()
+
+	if s.Interval > 0 {
+		cfg.MinInterval = s.Interval
+	}
+
+	if s.Encoding != "" {
+		cfg.Encoding = s.Encoding
+	}
+
+	if s.Compress != "" {
+		cfg.Compression = s.Compress
+	}
+
+	if s.Timeout > 0 {
+		cfg.Timeout = s.Timeout
+	}
+
+	if s.Rate > 0 {
+		cfg.RateLimit = s.Rate
+	}
+
+	if s.Breaker > 0 {
+		cfg.Breaker = s.Breaker
+	}
+
+	if s.Cooldown > 0 {
+		cfg.Cooldown = s.Cooldown
+	}
+
+	return cfg, nil
+}
+
+func (c *Championship) validate(field string) []error {
+	var errs []error
+
+	check := func(ok bool, name string, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s.%s: %s", field, name, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	points := func(name string, table []int) {
+		check(len(table) > 0, name, "at least one position must score")
+
+		for _, p := range table {
+			check(p >= 0, name, "%d can not be negative", p)
+		}
+	}
+
+	points("points", c.Points)
+
+	for _, classID := range slices.Sorted(maps.Keys(c.Classes)) {
+		points(fmt.Sprintf("classes.%d", classID), c.Classes[classID])
+	}
+
+	check(c.FastestLap >= 0, "fastest_lap", "can not be negative")
+	check(c.Pole >= 0, "pole", "can not be negative")
+	check(c.Drops >= 0, "drops", "can not be negative")
+
+	for i, penalty := range c.Penalties {
+		check(penalty.UserID > 0, fmt.Sprintf("penalties[%d].user_id", i), "required")
+		check(penalty.RaceWeek >= 0, fmt.Sprintf("penalties[%d].race_week", i), "can not be negative")
+		check(penalty.Points > 0, fmt.Sprintf("penalties[%d].points", i), "must be greater than 0")
+	}
+
+	return errs
+}
+
+// Rules converts the championship to the form used by the standings package
+func (c *Championship) Rules() standings.Rules {
+	rules := standings.Rules{
+		Points:      c.Points,
+		ClassPoints: c.Classes,
+		FastestLap:  c.FastestLap,
+		Pole:        c.Pole,
+		Drops:       c.Drops,
+	}
+
+	for _, penalty := range c.Penalties {
+		rules.Penalties = append(rules.Penalties, standings.Penalty(penalty))
+	}
+
+	return rules
+}
+
+func (a *Auth) validate(field string) []error {
+	var errs []error
+
+	if !slices.Contains(authTypes, a.Type) {
+		return []error{fmt.Errorf("%s.type: unknown auth type %q, expected basic, bearer or apikey", field, a.Type)}
+	}
+
+	required := func(value string, name string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s.%s: required for %s auth", field, name, a.Type))
+		}
+	}
+
+	switch a.Type {
+	case sinks.AuthBasic:
+		required(a.UserName, "username")
+		required(a.Password, "password")
+	case sinks.AuthBearer:
+		required(a.Token, "token")
+	case sinks.AuthAPIKey:
+		required(a.Key, "key")
+	}
+
+	return errs
+}
+
+func (a *Auth) sinkAuth() sinks.Auth {
+	return sinks.Auth{
+		Type:     a.Type,
+		UserName: a.UserName,
+		Password: a.Password,
+		Token:    a.Token,
+		Key:      a.Key,
+		Prefix:   a.Prefix,
+	}
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -5,21 +5,18 @@ package api
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"golang.org/x/oauth2"
 )
 
 // APIClient manages communication over HTTP
 type APIClient struct {
-	cfg *Configuration
+	cfg    *Configuration
+	limits *limits
 }
 
 type APIClientInterface interface {
@@ -33,23 +30,75 @@ type APIClientInterface interface {
 // optionally, a custom http.Client to allow for advanced features such as caching.
 func NewAPIClient(cfg *Configuration) *APIClient {
 	if cfg.HTTPClient == nil {
-		cfg.HTTPClient = http.DefaultClient
+		// The timeout is applied to each call, including any wait for the rate limit
+		cfg.HTTPClient = &http.Client{}
 	}
 
 	return &APIClient{
-		cfg: cfg,
+		cfg:    cfg,
+		limits: newLimits(cfg),
 	}
 }
 
-// CallAPI do the request.
+// CallAPI do the request. A gzip or zstd compressed response body is decompressed.
+// Requests are rate limited per endpoint, wait for any Retry-After the server asked for and fail straight away with
+// a CircuitOpenError while the endpoint keeps failing. The wait and the request, up to the body being closed, share
+// the Timeout budget.
 func (c *APIClient) CallAPI(request *http.Request) (*http.Response, error) {
-	return c.cfg.HTTPClient.Do(request)
+	cancel := context.CancelFunc(func() {})
+
+	if c.cfg.Timeout > 0 {
+		var ctx context.Context
+
+		ctx, cancel = context.WithTimeout(request.Context(), c.cfg.Timeout)
+		request = request.WithContext(ctx)
+	}
+
+	ep, err := c.limits.acquire(request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	response, err := c.cfg.HTTPClient.Do(request)
+	c.limits.done(ep, response, err)
+
+	if err != nil {
+		cancel()
+		return response, err
+	}
+
+	response.Body = &budgetBody{ReadCloser: response.Body, cancel: cancel}
+
+	err = decompress(response)
+	if err != nil {
+		BodyClose(response)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// budgetBody ends the timeout budget of a call once the body is closed
+type budgetBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *budgetBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
 }
 
 // PrepareRequest build the request
 func (c *APIClient) PrepareRequest(ctx context.Context, path string, method string, queryParams url.Values, postBody any,
 ) (request *http.Request, err error) {
-	var body *bytes.Buffer
+	var (
+		body            *bytes.Buffer
+		contentType     string
+		contentEncoding string
+	)
 
 	// Setup path and query parameters, path should have a leading '/', e.g. /bookings
 	parsedURL, err := url.Parse(c.cfg.BasePath + path)
@@ -76,12 +125,22 @@ func (c *APIClient) PrepareRequest(ctx context.Context, path string, method stri
 		if reader, ok := postBody.(io.Reader); ok {
 			_, err = body.ReadFrom(reader)
 		} else {
-			err = json.NewEncoder(body).Encode(postBody)
+			contentType = c.cfg.contentType()
+			err = Encode(body, contentType, postBody)
 		}
 
 		if err != nil {
 			return nil, err
 		}
+
+		if c.cfg.Compression != "" && body.Len() >= c.cfg.CompressionThreshold {
+			contentEncoding = c.cfg.Compression
+
+			err = compress(body, contentEncoding)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Generate a new request
@@ -98,6 +157,16 @@ func (c *APIClient) PrepareRequest(ctx context.Context, path string, method stri
 	// Add the user agent to the request.
 	request.Header.Add("User-Agent", c.cfg.UserAgent)
 
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	if contentEncoding != "" {
+		request.Header.Set("Content-Encoding", contentEncoding)
+	}
+
+	request.Header.Set("Accept-Encoding", acceptEncoding)
+
 	if ctx != nil {
 		request, err = setHeadersFromContext(ctx, request)
 		if err != nil {
@@ -112,22 +181,9 @@ func (c *APIClient) PrepareRequest(ctx context.Context, path string, method stri
 	return request, nil
 }
 
+// Decode a response body of JSON, XML, CBOR or MessagePack
 func (c *APIClient) Decode(v any, b []byte, contentType string) (err error) {
-	if strings.Contains(contentType, "application/xml") {
-		if err = xml.Unmarshal(b, v); err != nil {
-			return err
-		}
-
-		return nil
-	} else if strings.Contains(contentType, "application/json") {
-		if err = json.Unmarshal(b, v); err != nil {
-			return err
-		}
-
-		return nil
-	}
-
-	return errors.New("undefined Content-Type in response")
+	return Unmarshal(contentType, b, v)
 }
 
 func (c *APIClient) ReportError(response *http.Response, body []byte) error {
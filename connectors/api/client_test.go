@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"testing"
@@ -52,6 +53,26 @@ func TestPrepareRequest(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("POST body should be encoded with the configured content type", func(t *testing.T) {
+		cfg := NewConfiguration(testBasePath)
+		cfg.SetContentType(ContentTypeCBOR)
+
+		api := NewAPIClient(cfg)
+
+		request, err := api.PrepareRequest(context.TODO(), "/", http.MethodPost, nil, testResult{ID: "test"})
+		assert.NoError(t, err)
+		assert.Equal(t, ContentTypeCBOR, request.Header.Get("Content-Type"))
+		assert.Equal(t, "application/cbor, application/json;q=0.9", request.Header.Get("Accept"))
+
+		body, _ := io.ReadAll(request.Body)
+		assert.Equal(t, []byte{0xa1, 0x62, 'i', 'd', 0x64, 't', 'e', 's', 't'}, body)
+
+		request, err = NewAPIClient(NewConfiguration(testBasePath)).PrepareRequest(context.TODO(), "/", http.MethodPost, nil, testResult{ID: "test"})
+		assert.NoError(t, err)
+		assert.Equal(t, ContentTypeJSON, request.Header.Get("Content-Type"))
+		assert.Equal(t, ContentTypeJSON, request.Header.Get("Accept"))
+	})
+
 	t.Run("Check OAuth2 authentication added", func(t *testing.T) {
 		api := NewAPIClient(NewConfiguration(testBasePath))
 		ctx := context.WithValue(context.Background(), ContextOAuth2, &testToken{})
@@ -157,6 +178,21 @@ func TestDecode(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("decodes CBOR and MessagePack successfully", func(t *testing.T) {
+		api := NewAPIClient(NewConfiguration(""))
+
+		for _, contentType := range []string{"application/cbor", "application/msgpack", "application/x-msgpack", "application/json; charset=utf-8"} {
+			b, err := Marshal(contentType, testResult{ID: "test"})
+			assert.NoError(t, err)
+
+			result := testResult{}
+			err = api.Decode(&result, b, contentType)
+
+			assert.NoError(t, err, contentType)
+			assert.Equal(t, testResult{ID: "test"}, result, contentType)
+		}
+	})
+
 	t.Run("returns error for unknown content type", func(t *testing.T) {
 		api := NewAPIClient(NewConfiguration(""))
 
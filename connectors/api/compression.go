@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings of request and response bodies
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// DefaultCompressionThreshold is the smallest request body worth compressing, below it the saving is lost in the
+// compression headers
+const DefaultCompressionThreshold = 1024
+
+// acceptEncoding is sent with every request, responses are decompressed in CallAPI
+const acceptEncoding = EncodingGzip + ", " + EncodingZstd
+
+// compress replaces body with its compressed form
+func compress(body *bytes.Buffer, encoding string) error {
+	var (
+		compressed bytes.Buffer
+		w          io.WriteCloser
+		err        error
+	)
+
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&compressed)
+	case EncodingZstd:
+		w, err = zstd.NewWriter(&compressed)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported compression %q, expected %s or %s", encoding, EncodingGzip, EncodingZstd)
+	}
+
+	_, err = body.WriteTo(w)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	*body = compressed
+
+	return nil
+}
+
+// decompress wraps the body of a compressed response so it reads as it was before compression. A response without a
+// body is left alone, there is nothing to decompress.
+func decompress(response *http.Response) error {
+	if response.ContentLength == 0 || response.StatusCode == http.StatusNoContent || response.StatusCode == http.StatusNotModified ||
+		(response.Request != nil && response.Request.Method == http.MethodHead) {
+		return nil
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+
+	var (
+		r   io.ReadCloser
+		err error
+	)
+
+	switch encoding {
+	case "", "identity":
+		return nil
+	case EncodingGzip, "x-gzip":
+		r, err = gzip.NewReader(response.Body)
+	case EncodingZstd:
+		var d *zstd.Decoder
+
+		d, err = zstd.NewReader(response.Body)
+		if err == nil {
+			r = d.IOReadCloser()
+		}
+	default:
+		return fmt.Errorf("unsupported Content-Encoding %q in response", encoding)
+	}
+
+	if err != nil {
+		return fmt.Errorf("can not decompress %s response, err:%w", encoding, err)
+	}
+
+	response.Body = &decompressed{r: r, body: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+
+	return nil
+}
+
+// decompressed closes both the decompressor and the original body
+type decompressed struct {
+	r    io.ReadCloser
+	body io.ReadCloser
+}
+
+func (d *decompressed) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+func (d *decompressed) Close() error {
+	return errors.Join(d.r.Close(), d.body.Close())
+}
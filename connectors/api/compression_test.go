@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	large := testResult{ID: strings.Repeat("driver ", 500)}
+
+	// echo decompresses the request and sends it back compressed as the client asked, zstd if it accepts it
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+
+		switch r.Header.Get("Content-Encoding") {
+		case EncodingGzip:
+			gz, err := gzip.NewReader(r.Body)
+			assert.NoError(t, err)
+
+			body = gz
+		case EncodingZstd:
+			d, err := zstd.NewReader(r.Body)
+			assert.NoError(t, err)
+
+			body = d
+		}
+
+		b, err := io.ReadAll(body)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Request-Encoding", r.Header.Get("Content-Encoding"))
+
+		if strings.Contains(r.Header.Get("Accept-Encoding"), EncodingZstd) {
+			w.Header().Set("Content-Encoding", EncodingZstd)
+
+			e, _ := zstd.NewWriter(w)
+			_, _ = e.Write(b)
+			_ = e.Close()
+
+			return
+		}
+
+		_, _ = w.Write(b)
+	}))
+	defer echo.Close()
+
+	call := func(t *testing.T, cfg *Configuration, postBody any) (string, testResult) {
+		t.Helper()
+
+		client := NewAPIClient(cfg)
+
+		request, err := client.PrepareRequest(context.TODO(), "/", http.MethodPost, nil, postBody)
+		require.NoError(t, err)
+
+		response, err := client.CallAPI(request)
+		require.NoError(t, err)
+
+		defer BodyClose(response)
+
+		b, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		assert.Empty(t, response.Header.Get("Content-Encoding"))
+
+		var result testResult
+
+		require.NoError(t, client.Decode(&result, b, response.Header.Get("Content-Type")))
+
+		return response.Header.Get("X-Request-Encoding"), result
+	}
+
+	t.Run("Request bodies over the threshold should be compressed and responses decompressed", func(t *testing.T) {
+		for _, compression := range []string{EncodingGzip, EncodingZstd} {
+			cfg := NewConfiguration(echo.URL)
+			cfg.Compression = compression
+
+			encoding, result := call(t, cfg, large)
+			assert.Equal(t, compression, encoding)
+			assert.Equal(t, large, result)
+		}
+	})
+
+	t.Run("Small bodies should be sent as they are", func(t *testing.T) {
+		cfg := NewConfiguration(echo.URL)
+		cfg.Compression = EncodingGzip
+
+		encoding, result := call(t, cfg, testResult{ID: "test"})
+		assert.Empty(t, encoding)
+		assert.Equal(t, testResult{ID: "test"}, result)
+	})
+
+	t.Run("Compression should shrink repetitive payloads", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		_, _ = body.WriteString(large.ID)
+		size := body.Len()
+
+		require.NoError(t, compress(body, EncodingZstd))
+		assert.Less(t, body.Len(), size/10)
+	})
+
+	t.Run("Unknown encodings should be rejected", func(t *testing.T) {
+		assert.Error(t, compress(&bytes.Buffer{}, "brotli"))
+		assert.Error(t, decompress(&http.Response{ContentLength: -1, Header: http.Header{"Content-Encoding": []string{"br"}}}))
+	})
+
+	t.Run("Responses without a body should not be decompressed", func(t *testing.T) {
+		empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", EncodingGzip)
+
+			switch r.URL.Path {
+			case "/none":
+				w.WriteHeader(http.StatusNoContent)
+			case "/empty":
+				w.Header().Set("Content-Length", "0")
+			}
+		}))
+		defer empty.Close()
+
+		client := NewAPIClient(NewConfiguration(empty.URL))
+
+		for _, test := range []struct{ method, path string }{
+			{http.MethodPost, "/none"}, {http.MethodPost, "/empty"}, {http.MethodHead, "/"},
+		} {
+			request, err := client.PrepareRequest(context.TODO(), test.path, test.method, nil, nil)
+			require.NoError(t, err)
+
+			response, err := client.CallAPI(request)
+			require.NoError(t, err, test)
+
+			b, err := io.ReadAll(response.Body)
+			require.NoError(t, err)
+			assert.Empty(t, b)
+
+			BodyClose(response)
+		}
+
+		assert.NoError(t, decompress(&http.Response{StatusCode: http.StatusNotModified, ContentLength: -1,
+			Header: http.Header{"Content-Encoding": []string{EncodingGzip}}}))
+	})
+}
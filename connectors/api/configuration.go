@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"time"
 )
 
 // contextKeys are used to identify the type of value in the context.
@@ -52,17 +53,30 @@ type APIKey struct {
 }
 
 type Configuration struct {
-	BasePath      string            `json:"basePath,omitempty"` // no trailing '/'
-	DefaultHeader map[string]string `json:"defaultHeader,omitempty"`
-	UserAgent     string            `json:"userAgent,omitempty"`
-	HTTPClient    *http.Client
+	BasePath             string            `json:"basePath,omitempty"` // no trailing '/'
+	DefaultHeader        map[string]string `json:"defaultHeader,omitempty"`
+	UserAgent            string            `json:"userAgent,omitempty"`
+	ContentType          string            `json:"contentType,omitempty"`          // of request bodies, JSON if empty
+	Compression          string            `json:"compression,omitempty"`          // of request bodies, gzip, zstd or none if empty
+	CompressionThreshold int               `json:"compressionThreshold,omitempty"` // smallest request body in bytes to compress
+	Timeout              time.Duration     `json:"timeout,omitempty"`              // budget for a call, from waiting to be allowed to send to closing the response
+	RateLimit            float64           `json:"rateLimit,omitempty"`            // requests per second to each endpoint, unlimited if 0
+	RateBurst            int               `json:"rateBurst,omitempty"`            // requests allowed at once before the rate limit applies
+	BreakerThreshold     int               `json:"breakerThreshold,omitempty"`     // consecutive failures opening the circuit, never if 0
+	BreakerCooldown      time.Duration     `json:"breakerCooldown,omitempty"`      // how long the circuit stays open before a trial request
+	HTTPClient           *http.Client
 }
 
 func NewConfiguration(basePath string) *Configuration {
 	cfg := &Configuration{
-		BasePath:      basePath,
-		DefaultHeader: map[string]string{"Accept": "application/json"},
-		UserAgent:     "vcrlive/1.0.0/go",
+		BasePath:             basePath,
+		DefaultHeader:        map[string]string{"Accept": "application/json"},
+		UserAgent:            "vcrlive/1.0.0/go",
+		CompressionThreshold: DefaultCompressionThreshold,
+		Timeout:              DefaultTimeout,
+		RateBurst:            1,
+		BreakerThreshold:     DefaultBreakerThreshold,
+		BreakerCooldown:      DefaultBreakerCooldown,
 	}
 
 	return cfg
@@ -71,3 +85,21 @@ func NewConfiguration(basePath string) *Configuration {
 func (c *Configuration) AddDefaultHeader(key string, value string) {
 	c.DefaultHeader[key] = value
 }
+
+// SetContentType encodes request bodies as contentType, e.g. ContentTypeCBOR, and asks for responses in it too with
+// JSON as a fallback for servers that do not support it
+func (c *Configuration) SetContentType(contentType string) {
+	c.ContentType = contentType
+
+	if contentType != ContentTypeJSON {
+		c.DefaultHeader["Accept"] = contentType + ", " + ContentTypeJSON + ";q=0.9"
+	}
+}
+
+func (c *Configuration) contentType() string {
+	if c.ContentType == "" {
+		return ContentTypeJSON
+	}
+
+	return c.ContentType
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Content types of request and response bodies
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeXML     = "application/xml"
+	ContentTypeCBOR    = "application/cbor"    // RFC 8949
+	ContentTypeMsgPack = "application/msgpack" // also accepted as application/x-msgpack
+)
+
+// cborMode writes times as RFC 3339 strings, the same as the JSON payloads
+var cborMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode() //nolint:errcheck // constant options
+
+// Encode writes v to w as contentType. Struct fields are named by their json tags whatever the encoding.
+func Encode(w io.Writer, contentType string, v any) error {
+	switch mediaType(contentType) {
+	case ContentTypeJSON, "":
+		return json.NewEncoder(w).Encode(v)
+	case ContentTypeXML:
+		return xml.NewEncoder(w).Encode(v)
+	case ContentTypeCBOR:
+		return cborMode.NewEncoder(w).Encode(v)
+	case ContentTypeMsgPack, "application/x-msgpack":
+		enc := msgpack.NewEncoder(w)
+		enc.SetCustomStructTag("json")
+
+		return enc.Encode(v)
+	}
+
+	return errors.New("unsupported Content-Type " + contentType)
+}
+
+// Marshal returns v encoded as contentType, without the newline Encode writes after JSON
+func Marshal(contentType string, v any) ([]byte, error) {
+	if mediaType(contentType) == ContentTypeJSON {
+		return json.Marshal(v)
+	}
+
+	var b bytes.Buffer
+
+	err := Encode(&b, contentType, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes b of contentType into v
+func Unmarshal(contentType string, b []byte, v any) error {
+	switch mediaType(contentType) {
+	case ContentTypeJSON:
+		return json.Unmarshal(b, v)
+	case ContentTypeXML:
+		return xml.Unmarshal(b, v)
+	case ContentTypeCBOR:
+		return cbor.Unmarshal(b, v)
+	case ContentTypeMsgPack, "application/x-msgpack":
+		dec := msgpack.NewDecoder(bytes.NewReader(b))
+		dec.SetCustomStructTag("json")
+
+		return dec.Decode(v)
+	}
+
+	return errors.New("undefined Content-Type in response")
+}
+
+// mediaType strips parameters such as charset, and treats the +json and +cbor structured suffixes as the base type
+func mediaType(contentType string) string {
+	mt, _, _ := strings.Cut(contentType, ";")
+	mt = strings.ToLower(strings.TrimSpace(mt))
+
+	switch {
+	case strings.HasSuffix(mt, "+json"):
+		return ContentTypeJSON
+	case strings.HasSuffix(mt, "+xml"):
+		return ContentTypeXML
+	case strings.HasSuffix(mt, "+cbor"):
+		return ContentTypeCBOR
+	case mt == "text/xml":
+		return ContentTypeXML
+	}
+
+	return mt
+}
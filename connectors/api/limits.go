@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/logging"
+	"github.com/ianhaycox/vcrlive/metrics"
+)
+
+var (
+	logger      = logging.Component("api")
+	circuitOpen = metrics.NewGauge("vcrlive_api_circuit_open", "1 while the circuit breaker stops requests to the endpoint", "endpoint")
+)
+
+// Defaults protecting a small server from many clients
+const (
+	DefaultTimeout          = 10 * time.Second
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+
+	// defaultRetryAfter is how long to hold off after a 429 without a Retry-After header
+	defaultRetryAfter = time.Second
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // requests flow
+	BreakerOpen     = "open"      // requests fail straight away until the cooldown has passed
+	BreakerHalfOpen = "half-open" // one trial request decides whether to close or open again
+)
+
+// CircuitOpenError is returned without calling the server while it is failing
+type CircuitOpenError struct {
+	Endpoint string
+	Until    time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s after repeated failures, retrying after %s", e.Endpoint, e.Until.Format(time.TimeOnly))
+}
+
+// RateLimitError is returned when a request could not be sent within the timeout budget, because of the rate limit
+// or the server asking to retry later
+type RateLimitError struct {
+	Endpoint string
+	Until    time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited for %s until %s", e.Endpoint, e.Until.Format(time.TimeOnly))
+}
+
+// endpoint is the rate limit and circuit breaker state of one URL
+type endpoint struct {
+	name     string
+	tokens   float64
+	refilled time.Time
+	retryAt  time.Time // from Retry-After
+	failures int
+	state    string
+	openedAt time.Time
+	trial    bool // a half-open trial request is in flight
+}
+
+// limits tracks every endpoint an APIClient calls
+type limits struct {
+	cfg       *Configuration
+	now       func() time.Time
+	sleep     func(ctx context.Context, d time.Duration) error
+	mux       sync.Mutex
+	endpoints map[string]*endpoint
+}
+
+func newLimits(cfg *Configuration) *limits {
+	return &limits{
+		cfg:       cfg,
+		now:       time.Now,
+		sleep:     sleep,
+		endpoints: map[string]*endpoint{},
+	}
+}
+
+// acquire waits for the request to be allowed, failing straight away if the circuit is open or the wait would be
+// longer than the timeout budget
+func (l *limits) acquire(request *http.Request) (*endpoint, error) {
+	l.mux.Lock()
+
+	ep := l.endpoint(request)
+	now := l.now()
+
+	err := l.allow(ep, now)
+	if err != nil {
+		l.mux.Unlock()
+		return nil, err
+	}
+
+	until := l.reserve(ep, now)
+	l.mux.Unlock()
+
+	wait := until.Sub(now)
+	if wait <= 0 {
+		return ep, nil
+	}
+
+	if l.cfg.Timeout > 0 && wait > l.cfg.Timeout {
+		l.release(ep)
+		return nil, &RateLimitError{Endpoint: ep.name, Until: until}
+	}
+
+	err = l.sleep(request.Context(), wait)
+	if err != nil {
+		l.release(ep)
+		return nil, err
+	}
+
+	return ep, nil
+}
+
+// allow applies the circuit breaker
+func (l *limits) allow(ep *endpoint, now time.Time) error {
+	switch ep.state {
+	case BreakerOpen:
+		until := ep.openedAt.Add(l.cfg.BreakerCooldown)
+		if now.Before(until) {
+			return &CircuitOpenError{Endpoint: ep.name, Until: until}
+		}
+
+		l.transition(ep, BreakerHalfOpen)
+
+		fallthrough
+	case BreakerHalfOpen:
+		if ep.trial {
+			return &CircuitOpenError{Endpoint: ep.name, Until: now.Add(l.cfg.BreakerCooldown)}
+		}
+
+		ep.trial = true
+	}
+
+	return nil
+}
+
+// reserve takes a token, returning when the request may be sent
+func (l *limits) reserve(ep *endpoint, now time.Time) time.Time {
+	at := now
+	if ep.retryAt.After(at) {
+		at = ep.retryAt
+	}
+
+	if l.cfg.RateLimit <= 0 {
+		return at
+	}
+
+	burst := float64(max(1, l.cfg.RateBurst))
+
+	if ep.refilled.IsZero() {
+		ep.tokens = burst
+	} else {
+		ep.tokens = min(burst, ep.tokens+now.Sub(ep.refilled).Seconds()*l.cfg.RateLimit)
+	}
+
+	ep.refilled = now
+	ep.tokens--
+
+	if ep.tokens < 0 {
+		next := now.Add(time.Duration(-ep.tokens / l.cfg.RateLimit * float64(time.Second)))
+		if next.After(at) {
+			at = next
+		}
+	}
+
+	return at
+}
+
+// release gives back a reservation that was not used
+func (l *limits) release(ep *endpoint) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	ep.trial = false
+
+	if l.cfg.RateLimit > 0 {
+		ep.tokens++
+	}
+}
+
+// done records the outcome of a request. Network errors, server errors and 429s count towards opening the circuit.
+func (l *limits) done(ep *endpoint, response *http.Response, err error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	ep.trial = false
+
+	if response != nil && (response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable) {
+		retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After"), now)
+		if !ok && response.StatusCode == http.StatusTooManyRequests {
+			retryAfter = defaultRetryAfter
+		}
+
+		ep.retryAt = now.Add(retryAfter)
+	}
+
+	if err == nil && response != nil && response.StatusCode < http.StatusInternalServerError && response.StatusCode != http.StatusTooManyRequests {
+		ep.failures = 0
+
+		if ep.state != BreakerClosed {
+			l.transition(ep, BreakerClosed)
+		}
+
+		return
+	}
+
+	ep.failures++
+
+	if l.cfg.BreakerThreshold > 0 && (ep.state == BreakerHalfOpen || ep.failures >= l.cfg.BreakerThreshold) {
+		ep.openedAt = now
+		l.transition(ep, BreakerOpen)
+	}
+}
+
+func (l *limits) endpoint(request *http.Request) *endpoint {
+	name := request.URL.Scheme + "://" + request.URL.Host + request.URL.Path
+
+	ep, ok := l.endpoints[name]
+	if !ok {
+		ep = &endpoint{name: name, state: BreakerClosed}
+		l.endpoints[name] = ep
+	}
+
+	return ep
+}
+
+func (l *limits) transition(ep *endpoint, state string) {
+	switch state {
+	case BreakerOpen:
+		circuitOpen.Set(1, ep.name)
+		logger.Warn("Circuit open, pausing requests", "endpoint", ep.name, "failures", ep.failures, "cooldown", l.cfg.BreakerCooldown)
+	case BreakerClosed:
+		circuitOpen.Set(0, ep.name)
+		logger.Info("Circuit closed, requests resumed", "endpoint", ep.name)
+	}
+
+	ep.state = state
+}
+
+// parseRetryAfter reads a Retry-After of either seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(0, seconds)) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, at.Sub(now)), true
+	}
+
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a fake time source, sleeping moves it forward
+type clock struct {
+	now    time.Time
+	slept  []time.Duration
+	client *APIClient
+}
+
+func newTestClient(t *testing.T, cfg *Configuration) (*APIClient, *clock) {
+	t.Helper()
+
+	c := &clock{now: time.Date(2025, 7, 12, 19, 0, 0, 0, time.UTC), client: NewAPIClient(cfg)}
+	c.client.limits.now = func() time.Time { return c.now }
+	c.client.limits.sleep = func(_ context.Context, d time.Duration) error {
+		c.slept = append(c.slept, d)
+		c.now = c.now.Add(d)
+
+		return nil
+	}
+
+	return c.client, c
+}
+
+func call(t *testing.T, client *APIClient) (int, error) {
+	t.Helper()
+
+	request, err := client.PrepareRequest(context.TODO(), "/live", http.MethodPost, nil, testResult{ID: "test"})
+	require.NoError(t, err)
+
+	response, err := client.CallAPI(request)
+	if err != nil {
+		return 0, err
+	}
+
+	BodyClose(response)
+
+	return response.StatusCode, nil
+}
+
+// breakerState is the state of the circuit to the /live endpoint
+func breakerState(client *APIClient) string {
+	client.limits.mux.Lock()
+	defer client.limits.mux.Unlock()
+
+	return client.limits.endpoints[client.cfg.BasePath+"/live"].state
+}
+
+func TestLimits(t *testing.T) {
+	var (
+		hits   atomic.Int32
+		status atomic.Int32
+		header atomic.Value
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+
+		if retryAfter, ok := header.Load().(string); ok && retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	reset := func(code int, retryAfter string) {
+		hits.Store(0)
+		status.Store(int32(code)) //nolint:gosec // HTTP status
+		header.Store(retryAfter)
+	}
+
+	t.Run("Requests over the rate should wait their turn", func(t *testing.T) {
+		reset(http.StatusOK, "")
+
+		cfg := NewConfiguration(server.URL)
+		cfg.RateLimit = 2
+		cfg.RateBurst = 2
+
+		client, clock := newTestClient(t, cfg)
+
+		for range 4 {
+			code, err := call(t, client)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, code)
+		}
+
+		assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, clock.slept)
+	})
+
+	t.Run("Waits longer than the timeout should fail", func(t *testing.T) {
+		reset(http.StatusOK, "")
+
+		cfg := NewConfiguration(server.URL)
+		cfg.RateLimit = 0.1
+		cfg.Timeout = time.Second
+
+		client, _ := newTestClient(t, cfg)
+
+		_, err := call(t, client)
+		require.NoError(t, err)
+
+		_, err = call(t, client)
+
+		var rateLimited *RateLimitError
+
+		require.ErrorAs(t, err, &rateLimited)
+		assert.Equal(t, int32(1), hits.Load())
+	})
+
+	t.Run("Retry-After should hold off the next request", func(t *testing.T) {
+		reset(http.StatusTooManyRequests, "3")
+
+		client, clock := newTestClient(t, NewConfiguration(server.URL))
+
+		code, err := call(t, client)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, code)
+
+		reset(http.StatusOK, "")
+
+		code, err = call(t, client)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []time.Duration{3 * time.Second}, clock.slept)
+	})
+
+	t.Run("The circuit should open after repeated failures and close once the server recovers", func(t *testing.T) {
+		reset(http.StatusInternalServerError, "")
+
+		cfg := NewConfiguration(server.URL)
+		cfg.BreakerThreshold = 3
+
+		client, clock := newTestClient(t, cfg)
+
+		for range 3 {
+			code, err := call(t, client)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusInternalServerError, code)
+		}
+
+		assert.Equal(t, BreakerOpen, breakerState(client))
+
+		_, err := call(t, client)
+
+		var circuitOpen *CircuitOpenError
+
+		require.ErrorAs(t, err, &circuitOpen)
+		assert.Contains(t, err.Error(), "/live")
+		assert.Equal(t, int32(3), hits.Load(), "no request while open")
+
+		// The trial request fails so it opens again
+		clock.now = clock.now.Add(cfg.BreakerCooldown)
+
+		_, err = call(t, client)
+		require.NoError(t, err)
+		assert.Equal(t, BreakerOpen, breakerState(client))
+
+		reset(http.StatusOK, "")
+
+		clock.now = clock.now.Add(cfg.BreakerCooldown)
+
+		code, err := call(t, client)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, BreakerClosed, breakerState(client))
+	})
+
+	t.Run("Client errors should not open the circuit", func(t *testing.T) {
+		reset(http.StatusBadRequest, "")
+
+		cfg := NewConfiguration(server.URL)
+		cfg.BreakerThreshold = 1
+
+		client, _ := newTestClient(t, cfg)
+
+		for range 3 {
+			_, err := call(t, client)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, BreakerClosed, breakerState(client))
+	})
+
+	t.Run("Slow servers should time out", func(t *testing.T) {
+		release := make(chan struct{})
+
+		slow := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+
+		cfg := NewConfiguration(slow.URL)
+		cfg.Timeout = 50 * time.Millisecond
+
+		_, err := call(t, NewAPIClient(cfg))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+	})
+
+	t.Run("Waiting for the rate limit should use the same budget as the request", func(t *testing.T) {
+		var calls atomic.Int32
+
+		slow := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			if calls.Add(1) > 1 {
+				time.Sleep(100 * time.Millisecond)
+			}
+		}))
+		defer slow.Close()
+
+		cfg := NewConfiguration(slow.URL)
+		cfg.RateLimit = 10 // the second call waits 100ms
+		cfg.Timeout = 150 * time.Millisecond
+
+		client := NewAPIClient(cfg)
+
+		_, err := call(t, client)
+		require.NoError(t, err)
+
+		_, err = call(t, client)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 7, 12, 19, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, d)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,329 @@
+// Package server serves the latest live positions locally for browser source overlays
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/sinks"
+	"github.com/ianhaycox/vcrlive/logging"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+var logger = logging.Component("server")
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+	subscriberBuffer  = 16
+)
+
+// Server keeps the latest payload and pushes every update to SSE and WebSocket subscribers.
+// It is a sinks.Sink so it can be fed by the same fan out as the remote destinations.
+type Server struct {
+	mux         *http.ServeMux
+	mu          sync.RWMutex
+	latest      model.LivePositions
+	subscribers map[chan update]struct{}
+	closed      bool
+}
+
+// update is a message already encoded for subscribers
+type update struct {
+	kind string
+	data []byte
+}
+
+func NewServer() *Server {
+	s := &Server{
+		mux:         http.NewServeMux(),
+		subscribers: make(map[chan update]struct{}),
+	}
+
+	s.mux.HandleFunc("GET /positions", s.handlePositions)
+	s.mux.HandleFunc("GET /session", s.handleSession)
+	s.mux.HandleFunc("GET /weekend", s.handleWeekend)
+	s.mux.HandleFunc("GET /drivers", s.handleDrivers)
+	s.mux.HandleFunc("GET /stream", s.handleStream)
+	s.mux.HandleFunc("GET /ws", s.handleWebSocket)
+
+	return s
+}
+
+// Handle adds another endpoint, e.g. /metrics
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// JSON is a handler returning the current value from get, e.g. the fuel estimate
+func JSON[T any](get func() T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, get())
+	})
+}
+
+// Query is a handler returning the value from get for the request's query parameters, e.g. the relative timing
+// board around a car. An error from get is a bad request.
+func Query[T any](get func(url.Values) (T, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := get(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, v)
+	})
+}
+
+// Stream is a handler sending the current value from get as Server-Sent Events named kind every interval, when it
+// has changed, e.g. the track map many times a second
+func Stream[T any](kind string, get func() T, every time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		eventStream(w)
+
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		var last []byte
+
+		for {
+			data, err := json.Marshal(get())
+			if err != nil {
+				logger.Warn("Can not stream", "kind", kind, "err", err)
+				return
+			}
+
+			if !bytes.Equal(data, last) {
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, data)
+				if err != nil {
+					return
+				}
+
+				flusher.Flush()
+
+				last = data
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// ServeHTTP allows browser sources loaded from disk or another port to read the endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on address until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("Serving live positions", "url", "http://"+address+"/positions")
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Send implements sinks.Sink
+func (s *Server) Send(_ context.Context, msg *sinks.Message) error {
+	if livePositions, ok := msg.Payload.(*model.LivePositions); ok {
+		s.mu.Lock()
+
+		// The final payload of a session only carries the session, keep the last known weekend and drivers
+		if livePositions.Drivers == nil && livePositions.Weekend == (model.Weekend{}) {
+			s.latest.Session = livePositions.Session
+		} else {
+			s.latest = *livePositions
+		}
+
+		s.mu.Unlock()
+	}
+
+	data, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return err
+	}
+
+	s.broadcast(update{kind: msg.Kind, data: data})
+
+	return nil
+}
+
+// Close disconnects all subscribers
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for subscriber := range s.subscribers {
+		close(subscriber)
+		delete(s.subscribers, subscriber)
+	}
+
+	s.closed = true
+
+	return nil
+}
+
+func (s *Server) broadcast(u update) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- u:
+		default:
+			// Slow overlay, it will catch up with the next update
+		}
+	}
+}
+
+func (s *Server) subscribe() (chan update, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, errors.New("server closed")
+	}
+
+	subscriber := make(chan update, subscriberBuffer)
+	s.subscribers[subscriber] = struct{}{}
+
+	return subscriber, nil
+}
+
+func (s *Server) unsubscribe(subscriber chan update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[subscriber]; ok {
+		close(subscriber)
+		delete(s.subscribers, subscriber)
+	}
+}
+
+func (s *Server) snapshot() model.LivePositions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.latest
+}
+
+func (s *Server) handlePositions(w http.ResponseWriter, _ *http.Request) {
+	latest := s.snapshot()
+	writeJSON(w, &latest)
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.snapshot().Session)
+}
+
+func (s *Server) handleWeekend(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.snapshot().Weekend)
+}
+
+func (s *Server) handleDrivers(w http.ResponseWriter, _ *http.Request) {
+	drivers := s.snapshot().Drivers
+	if drivers == nil {
+		drivers = []model.Driver{}
+	}
+
+	writeJSON(w, drivers)
+}
+
+// handleStream sends Server-Sent Events, the event name is the message kind
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	subscriber, err := s.subscribe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	defer s.unsubscribe(subscriber)
+
+	eventStream(w)
+
+	// Start with the current positions so an overlay has something to draw straight away
+	latest := s.snapshot()
+
+	data, _ := json.Marshal(&latest)
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", sinks.KindPositions, data)
+
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case u, ok := <-subscriber:
+			if !ok {
+				return
+			}
+
+			_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", u.kind, u.data)
+			if err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+func eventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logger.Warn("Can not write response", "err", err)
+	}
+}
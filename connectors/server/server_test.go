@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/sinks"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	livePositions := &model.LivePositions{
+		Weekend: model.Weekend{TrackID: 168},
+		Session: model.Session{SessionNum: 2, SessionState: "Racing"},
+		Drivers: []model.Driver{{CarIdx: 1, UserName: "Driver 1", ClassPosition: 1}},
+	}
+
+	t.Run("Endpoints should return the latest payload", func(t *testing.T) {
+		s := NewServer()
+		ts := httptest.NewServer(s)
+
+		defer ts.Close()
+
+		assert.NoError(t, s.Send(context.TODO(), &sinks.Message{Kind: sinks.KindPositions, Payload: livePositions}))
+
+		var drivers []model.Driver
+
+		get(t, ts.URL+"/drivers", &drivers)
+		assert.Equal(t, livePositions.Drivers, drivers)
+
+		var weekend model.Weekend
+
+		get(t, ts.URL+"/weekend", &weekend)
+		assert.Equal(t, 168, weekend.TrackID)
+
+		// Final payload only replaces the session
+		assert.NoError(t, s.Send(context.TODO(), &sinks.Message{Kind: sinks.KindPositions,
+			Payload: &model.LivePositions{Session: model.Session{SessionNum: 2, SessionState: "Cool Down"}}}))
+
+		var latest model.LivePositions
+
+		get(t, ts.URL+"/positions", &latest)
+		assert.Equal(t, "Cool Down", latest.Session.SessionState)
+		assert.Equal(t, 168, latest.Weekend.TrackID)
+		assert.Len(t, latest.Drivers, 1)
+
+		var session model.Session
+
+		get(t, ts.URL+"/session", &session)
+		assert.Equal(t, "Cool Down", session.SessionState)
+	})
+
+	t.Run("SSE stream should send the current positions then updates", func(t *testing.T) {
+		s := NewServer()
+		ts := httptest.NewServer(s)
+
+		defer ts.Close()
+
+		req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, ts.URL+"/stream", nil)
+		response, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer response.Body.Close()
+
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		r := bufio.NewReader(response.Body)
+		assert.Equal(t, "event: positions\n", readLine(r))
+		readLine(r)
+		readLine(r)
+
+		assert.NoError(t, s.Send(context.TODO(), &sinks.Message{Kind: sinks.KindEvent, Payload: &model.Event{Type: model.EventSessionStart}}))
+		assert.Equal(t, "event: event\n", readLine(r))
+		assert.Contains(t, readLine(r), `"type":"session_start"`)
+	})
+
+	t.Run("WebSocket should upgrade and push updates", func(t *testing.T) {
+		s := NewServer()
+		ts := httptest.NewServer(s)
+
+		defer ts.Close()
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+		require.NoError(t, err)
+
+		defer conn.Close()
+
+		_, _ = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+		r := bufio.NewReader(conn)
+		assert.Equal(t, "HTTP/1.1 101 Switching Protocols\r\n", readLine(r))
+
+		for readLine(r) != "\r\n" {
+		}
+
+		// RFC 6455 example key
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+
+		first := readFrame(t, r)
+		assert.Equal(t, sinks.KindPositions, first.Kind)
+
+		assert.NoError(t, s.Send(context.TODO(), &sinks.Message{Kind: sinks.KindPositions, Payload: livePositions}))
+
+		second := readFrame(t, r)
+		assert.Contains(t, string(second.Data), `"track_id":168`)
+	})
+
+	t.Run("Non upgrade requests to the WebSocket should be rejected", func(t *testing.T) {
+		ts := httptest.NewServer(NewServer())
+		defer ts.Close()
+
+		req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, ts.URL+"/ws", nil)
+		response, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer response.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	t.Run("Added JSON endpoints should return the current value", func(t *testing.T) {
+		s := NewServer()
+		laps := 3
+		s.Handle("GET /laps", JSON(func() map[string]int { return map[string]int{"laps": laps} }))
+
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		laps = 4
+
+		var value map[string]int
+
+		get(t, ts.URL+"/laps", &value)
+		assert.Equal(t, map[string]int{"laps": 4}, value)
+	})
+
+	t.Run("Added query endpoints should return the value for the parameters", func(t *testing.T) {
+		s := NewServer()
+		s.Handle("GET /car", Query(func(query url.Values) (map[string]string, error) {
+			if query.Get("car") == "" {
+				return nil, errors.New("car is required")
+			}
+
+			return map[string]string{"car": query.Get("car")}, nil
+		}))
+
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		var value map[string]string
+
+		get(t, ts.URL+"/car?car=7", &value)
+		assert.Equal(t, map[string]string{"car": "7"}, value)
+
+		req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, ts.URL+"/car", nil)
+		response, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer response.Body.Close()
+
+		body, _ := io.ReadAll(response.Body)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		assert.Equal(t, "car is required\n", string(body))
+	})
+
+	t.Run("Added streams should send the value when it changes", func(t *testing.T) {
+		s := NewServer()
+
+		var laps atomic.Int32
+
+		laps.Store(3)
+		s.Handle("GET /laps/stream", Stream("laps", func() map[string]int32 { return map[string]int32{"laps": laps.Load()} }, time.Millisecond))
+
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, ts.URL+"/laps/stream", nil)
+		response, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		defer response.Body.Close()
+
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		r := bufio.NewReader(response.Body)
+		assert.Equal(t, "event: laps\n", readLine(r))
+		assert.Equal(t, "data: {\"laps\":3}\n", readLine(r))
+		readLine(r)
+
+		laps.Store(4)
+		assert.Equal(t, "event: laps\n", readLine(r))
+		assert.Equal(t, "data: {\"laps\":4}\n", readLine(r))
+	})
+}
+
+func get(t *testing.T, url string, v any) {
+	req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
+	response, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer response.Body.Close()
+
+	assert.Equal(t, "*", response.Header.Get("Access-Control-Allow-Origin"))
+	require.NoError(t, json.NewDecoder(response.Body).Decode(v))
+}
+
+func readLine(r *bufio.Reader) string {
+	line, _ := r.ReadString('\n')
+	return line
+}
+
+func readFrame(t *testing.T, r *bufio.Reader) wsMessage {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(r, header)
+	require.NoError(t, err)
+	assert.Equal(t, byte(finalFrame|opText), header[0])
+
+	length := int(header[1])
+	if length == 126 {
+		extended := make([]byte, 2)
+		_, _ = io.ReadFull(r, extended)
+		length = int(extended[0])<<8 | int(extended[1])
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+
+	var msg wsMessage
+	require.NoError(t, json.Unmarshal(payload, &msg))
+
+	return msg
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // required by the WebSocket handshake
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/sinks"
+)
+
+// WebSocket framing from RFC 6455. The server only ever sends text frames, anything the client sends is read and
+// discarded until it closes the connection.
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	opText        = 0x1
+	opClose       = 0x8
+	finalFrame    = 0x80
+	writeTimeout  = 10 * time.Second
+)
+
+// wsMessage tells WebSocket clients what kind of message they received
+type wsMessage struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	subscriber, err := s.subscribe()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	defer s.unsubscribe(subscriber)
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " +
+		acceptKey(key) + "\r\n\r\n")
+
+	latest := s.snapshot()
+	data, _ := json.Marshal(&latest)
+
+	if writeMessage(conn, rw.Writer, sinks.KindPositions, data) != nil {
+		return
+	}
+
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+		discardFrames(rw.Reader)
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case u, ok := <-subscriber:
+			if !ok {
+				_ = writeFrame(conn, rw.Writer, opClose, nil)
+				return
+			}
+
+			if writeMessage(conn, rw.Writer, u.kind, u.data) != nil {
+				return
+			}
+		}
+	}
+}
+
+func acceptKey(key string) string {
+	h := sha1.New() //nolint:gosec // required by the WebSocket handshake
+	_, _ = io.WriteString(h, key+websocketGUID)
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func writeMessage(conn net.Conn, w *bufio.Writer, kind string, data []byte) error {
+	payload, err := json.Marshal(wsMessage{Kind: kind, Data: data})
+	if err != nil {
+		return err
+	}
+
+	return writeFrame(conn, w, opText, payload)
+}
+
+//nolint:mnd // frame header layout
+func writeFrame(conn net.Conn, w *bufio.Writer, opcode byte, payload []byte) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	header := []byte{finalFrame | opcode}
+
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	_, _ = w.Write(header)
+	_, _ = w.Write(payload)
+
+	return w.Flush()
+}
+
+// discardFrames reads client frames until a close frame or error
+//
+//nolint:mnd // frame header layout
+func discardFrames(r *bufio.Reader) {
+	header := make([]byte, 2)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+
+		if header[0]&0x0f == opClose {
+			return
+		}
+
+		length := uint64(header[1] & 0x7f)
+
+		switch length {
+		case 126:
+			extended := make([]byte, 2)
+			if _, err := io.ReadFull(r, extended); err != nil {
+				return
+			}
+
+			length = uint64(binary.BigEndian.Uint16(extended))
+		case 127:
+			extended := make([]byte, 8)
+			if _, err := io.ReadFull(r, extended); err != nil {
+				return
+			}
+
+			length = binary.BigEndian.Uint64(extended)
+		}
+
+		// Client frames are always masked
+		if header[1]&0x80 != 0 {
+			length += 4
+		}
+
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil { //nolint:gosec // bounded by the connection
+			return
+		}
+	}
+}
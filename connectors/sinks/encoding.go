@@ -0,0 +1,55 @@
+package sinks
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ianhaycox/vcrlive/connectors/api"
+)
+
+// Payload encodings. CBOR and MessagePack are smaller and quicker to parse than JSON for full fields at a high rate.
+const (
+	EncodingJSON    = "json"
+	EncodingCBOR    = "cbor"
+	EncodingMsgPack = "msgpack"
+)
+
+var encodings = map[string]string{
+	EncodingJSON:    api.ContentTypeJSON,
+	EncodingCBOR:    api.ContentTypeCBOR,
+	EncodingMsgPack: api.ContentTypeMsgPack,
+}
+
+// Encodings lists the payload encodings
+func Encodings() []string {
+	return slices.Sorted(maps.Keys(encodings))
+}
+
+// contentType of an encoding, JSON if empty
+func contentType(encoding string) (string, error) {
+	if encoding == "" {
+		return api.ContentTypeJSON, nil
+	}
+
+	ct, ok := encodings[encoding]
+	if !ok {
+		return "", fmt.Errorf("unknown encoding %q, expected one of %s", encoding, strings.Join(Encodings(), ", "))
+	}
+
+	return ct, nil
+}
+
+// Compressions lists the compressions of HTTP request bodies
+func Compressions() []string {
+	return []string{api.EncodingGzip, api.EncodingZstd}
+}
+
+func checkCompression(compression string) error {
+	if compression != "" && !slices.Contains(Compressions(), compression) {
+		return fmt.Errorf("unknown compression %q, expected one of %s", compression, strings.Join(Compressions(), ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/api"
+	"github.com/ianhaycox/vcrlive/logging"
+	"github.com/ianhaycox/vcrlive/metrics"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+var logger = logging.Component("sinks")
+
+var (
+	postsSent    = metrics.NewCounter("vcrlive_posts_sent_total", "Messages delivered by each sink", "sink", "kind")
+	postsFailed  = metrics.NewCounter("vcrlive_posts_failed_total", "Messages a sink could not deliver, including while its circuit is open", "sink", "kind")
+	postsDropped = metrics.NewCounter("vcrlive_posts_dropped_total", "Messages dropped because a sink was not keeping up", "sink", "kind")
+	outboxDepth  = metrics.NewGauge("vcrlive_outbox_depth", "Messages queued for each sink", "sink")
+)
+
+// queueLength is how many messages a slow sink may fall behind before messages are dropped
+const queueLength = 64
+
+// Fanout delivers every message to all its sinks. Each sink has its own queue and goroutine so a slow or
+// failing sink does not hold up the others.
+type Fanout struct {
+	workers []*worker
+	wg      sync.WaitGroup
+}
+
+type worker struct {
+	cfg         *Config
+	sink        Sink
+	queue       chan *Message
+	lastSent    time.Time
+	lastSession *model.Session
+	held        *Message // the latest positions inside the minimum interval, sent when it expires
+}
+
+func NewFanout() *Fanout {
+	return &Fanout{}
+}
+
+// Add starts delivering messages to sink, filtered and rate limited as described by cfg
+func (f *Fanout) Add(cfg *Config, sink Sink) {
+	w := &worker{
+		cfg:   cfg,
+		sink:  sink,
+		queue: make(chan *Message, queueLength),
+	}
+
+	f.workers = append(f.workers, w)
+	f.wg.Add(1)
+
+	go func() {
+		defer f.wg.Done()
+		w.run()
+	}()
+}
+
+// Post implements vcrstandings.VcrStandingsAPI
+func (f *Fanout) Post(_ context.Context, livePositions *model.LivePositions) error {
+	f.publish(&Message{Kind: KindPositions, Payload: livePositions})
+
+	return nil
+}
+
+// PostEvent implements vcrstandings.VcrStandingsAPI
+func (f *Fanout) PostEvent(_ context.Context, event *model.Event) error {
+	f.publish(&Message{Kind: KindEvent, Payload: event})
+
+	return nil
+}
+
+// Close delivers any queued messages and closes every sink
+func (f *Fanout) Close() error {
+	for _, w := range f.workers {
+		close(w.queue)
+	}
+
+	f.wg.Wait()
+
+	return nil
+}
+
+func (f *Fanout) publish(msg *Message) {
+	for _, w := range f.workers {
+		if !w.cfg.accepts(msg) {
+			continue
+		}
+
+		select {
+		case w.queue <- msg:
+			outboxDepth.Set(float64(len(w.queue)), w.cfg.Name)
+		default:
+			postsDropped.Inc(w.cfg.Name, msg.Kind)
+			logger.Warn("Sink is not keeping up, dropped message", "sink", w.cfg.Name, "kind", msg.Kind)
+		}
+	}
+}
+
+func (w *worker) run() {
+	// Messages are sent with their own context so queued messages are still delivered during shutdown
+	ctx := context.Background()
+
+	var expired <-chan time.Time
+
+	for {
+		select {
+		case msg, ok := <-w.queue:
+			if !ok {
+				if w.held != nil {
+					w.send(ctx, w.held)
+				}
+
+				err := w.sink.Close()
+				if err != nil {
+					logger.Warn("Sink can not close", "sink", w.cfg.Name, "err", err)
+				}
+
+				return
+			}
+
+			outboxDepth.Set(float64(len(w.queue)), w.cfg.Name)
+
+			if w.limited(msg, time.Now()) {
+				if w.held == nil {
+					expired = time.After(time.Until(w.lastSent.Add(w.cfg.MinInterval)))
+				}
+
+				w.held = msg
+
+				continue
+			}
+
+			// Positions held back are older than these
+			if msg.Kind == KindPositions {
+				w.held, expired = nil, nil
+			}
+
+			w.send(ctx, msg)
+
+		case <-expired:
+			msg := w.held
+			w.held, expired = nil, nil
+
+			w.limited(msg, time.Now())
+			w.send(ctx, msg)
+		}
+	}
+}
+
+func (w *worker) send(ctx context.Context, msg *Message) {
+	err := w.sink.Send(ctx, msg)
+
+	// The API client logs when the circuit opens and closes, not every message dropped in between
+	if err != nil {
+		postsFailed.Inc(w.cfg.Name, msg.Kind)
+	} else {
+		postsSent.Inc(w.cfg.Name, msg.Kind)
+	}
+
+	var circuitOpen *api.CircuitOpenError
+	if err != nil && !errors.As(err, &circuitOpen) {
+		logger.Warn("Sink can not send message", "sink", w.cfg.Name, "kind", msg.Kind, "err", err)
+	}
+}
+
+// limited applies the sink's minimum interval to positions, always letting a change of session state through.
+// Positions let through are recorded as sent at now.
+func (w *worker) limited(msg *Message, now time.Time) bool {
+	livePositions, ok := msg.Payload.(*model.LivePositions)
+	if !ok || w.cfg.MinInterval == 0 {
+		return false
+	}
+
+	if w.lastSession != nil && w.lastSession.SessionNum == livePositions.Session.SessionNum &&
+		w.lastSession.SessionState == livePositions.Session.SessionState && now.Sub(w.lastSent) < w.cfg.MinInterval {
+		return true
+	}
+
+	w.lastSent = now
+	w.lastSession = &livePositions.Session
+
+	return false
+}
@@ -0,0 +1,143 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/metrics"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFanout(t *testing.T) {
+	t.Run("A failing sink should not stop delivery to the others", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		failing := NewMockSink(ctrl)
+		failing.EXPECT().Send(gomock.Any(), gomock.Any()).Return(fmt.Errorf("broken")).Times(2)
+		failing.EXPECT().Close()
+
+		var buf bytes.Buffer
+
+		// The metrics are process wide, so only what this test adds is checked
+		failedEvents := metric(t, `vcrlive_posts_failed_total{sink="failing",kind="event"}`)
+		sentPositions := metric(t, `vcrlive_posts_sent_total{sink="buffer",kind="positions"}`)
+
+		f := NewFanout()
+		f.Add(&Config{Name: "failing"}, failing)
+		f.Add(&Config{Name: "buffer"}, NewWriterSink(&buf, true))
+
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 1}}))
+		assert.NoError(t, f.PostEvent(context.TODO(), &model.Event{Type: model.EventSessionEnd}))
+		assert.NoError(t, f.Close())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"kind":"positions"`)
+		assert.Contains(t, lines[1], `"kind":"event","data":{"schema_version":0,"type":"session_end"`)
+
+		assert.InDelta(t, failedEvents+1, metric(t, `vcrlive_posts_failed_total{sink="failing",kind="event"}`), 0)
+		assert.InDelta(t, sentPositions+1, metric(t, `vcrlive_posts_sent_total{sink="buffer",kind="positions"}`), 0)
+		assert.InDelta(t, 0, metric(t, `vcrlive_outbox_depth{sink="buffer"}`), 0)
+	})
+
+	t.Run("Positions should be rate limited per sink unless the session state changes", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		f := NewFanout()
+		f.Add(&Config{Name: "buffer", MinInterval: time.Hour}, NewWriterSink(&buf, true))
+
+		racing := model.Session{SessionNum: 2, SessionState: "Racing"}
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: racing}))
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: racing}))
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 2, SessionState: "Cool Down"}}))
+		assert.NoError(t, f.Close())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[1], "Cool Down")
+	})
+	t.Run("The latest positions held back by the interval should be sent when it expires", func(t *testing.T) {
+		var buf lockedBuffer
+
+		f := NewFanout()
+		f.Add(&Config{Name: "buffer", MinInterval: 50 * time.Millisecond}, NewWriterSink(&buf, true))
+
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 2, SessionLaps: "1"}}))
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 2, SessionLaps: "2"}}))
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 2, SessionLaps: "3"}}))
+		assert.NoError(t, f.PostEvent(context.TODO(), &model.Event{Type: model.EventSessionEnd}))
+
+		assert.Eventually(t, func() bool { return strings.Count(buf.String(), "\n") == 3 }, time.Second, 10*time.Millisecond)
+		assert.NoError(t, f.Close())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 3)
+		assert.Contains(t, lines[1], `"kind":"event"`)
+		assert.Contains(t, lines[2], `"session_laps":"3"`)
+	})
+
+	t.Run("Positions held back should be sent on close", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		f := NewFanout()
+		f.Add(&Config{Name: "buffer", MinInterval: time.Hour}, NewWriterSink(&buf, true))
+
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 2, SessionLaps: "1"}}))
+		assert.NoError(t, f.Post(context.TODO(), &model.LivePositions{Session: model.Session{SessionNum: 2, SessionLaps: "2"}}))
+		assert.NoError(t, f.Close())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[1], `"session_laps":"2"`)
+	})
+}
+
+// metric is the value of a series in the default registry, 0 if it has not been recorded
+func metric(t *testing.T, series string) float64 {
+	t.Helper()
+
+	var exposition bytes.Buffer
+
+	require.NoError(t, metrics.Default.Write(&exposition))
+
+	for _, line := range strings.Split(exposition.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, series+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+
+			return v
+		}
+	}
+
+	return 0
+}
+
+// lockedBuffer lets a test read what a sink's goroutine is writing
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
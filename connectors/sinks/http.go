@@ -0,0 +1,91 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ianhaycox/vcrlive/connectors/api"
+	"github.com/ianhaycox/vcrlive/connectors/vcrstandings"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// httpSink POSTs messages to the VCR standings endpoint
+type httpSink struct {
+	service vcrstandings.VcrStandingsAPI
+	auth    Auth
+}
+
+func newHTTPSink(cfg *Config) (Sink, error) {
+	ct, err := contentType(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	apiConfig := api.NewConfiguration(cfg.Target)
+	apiConfig.SetContentType(ct)
+
+	err = checkCompression(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	apiConfig.Compression = cfg.Compression
+	apiConfig.RateLimit = cfg.RateLimit
+
+	if cfg.Timeout > 0 {
+		apiConfig.Timeout = cfg.Timeout
+	}
+
+	if cfg.Breaker > 0 {
+		apiConfig.BreakerThreshold = cfg.Breaker
+	}
+
+	if cfg.Cooldown > 0 {
+		apiConfig.BreakerCooldown = cfg.Cooldown
+	}
+
+	for header, value := range cfg.Headers {
+		apiConfig.AddDefaultHeader(header, value)
+	}
+
+	return &httpSink{
+		service: vcrstandings.NewVcrStandingsService(api.NewAPIClient(apiConfig), nil),
+		auth:    cfg.Auth,
+	}, nil
+}
+
+// NewServiceSink adapts a VcrStandingsAPI to a Sink
+func NewServiceSink(service vcrstandings.VcrStandingsAPI) Sink {
+	return &httpSink{service: service}
+}
+
+func (h *httpSink) Send(ctx context.Context, msg *Message) error {
+	ctx = h.auth.apply(ctx)
+
+	switch payload := msg.Payload.(type) {
+	case *model.LivePositions:
+		return h.service.Post(ctx, payload)
+	case *model.Event:
+		return h.service.PostEvent(ctx, payload)
+	}
+
+	return fmt.Errorf("unsupported payload %T", msg.Payload)
+}
+
+func (h *httpSink) Close() error {
+	return nil
+}
+
+// apply adds the credentials to the context where api.APIClient.PrepareRequest picks them up
+func (a *Auth) apply(ctx context.Context) context.Context {
+	switch a.Type {
+	case AuthBasic:
+		return context.WithValue(ctx, api.ContextBasicAuth, api.BasicAuth{UserName: a.UserName, Password: a.Password})
+	case AuthBearer:
+		return context.WithValue(ctx, api.ContextAccessToken, a.Token)
+	case AuthAPIKey:
+		return context.WithValue(ctx, api.ContextAPIKey, api.APIKey{Key: a.Key, Prefix: a.Prefix})
+	}
+
+	return ctx
+}
@@ -0,0 +1,267 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/api"
+)
+
+// MQTT 3.1.1 control packets, see https://docs.oasis-open.org/mqtt/mqtt/v3.1.1/mqtt-v3.1.1.html
+const (
+	mqttConnect    byte = 0x10
+	mqttConnAck    byte = 0x20
+	mqttPublish    byte = 0x30
+	mqttPingReq    byte = 0xc0
+	mqttDisconnect byte = 0xe0
+
+	mqttProtocolLevel byte = 4
+	mqttCleanSession  byte = 0x02
+	mqttDefaultPort        = "1883"
+	mqttDefaultTopic       = "vcrlive"
+
+	// mqttKeepAlive is how long the broker waits without hearing from vcrlive before dropping the connection
+	mqttKeepAlive = 30 * time.Second
+)
+
+// mqttSink publishes each message with QoS 0 to <topic>/<kind>. Only what vcrlive needs of MQTT is implemented,
+// i.e. connect, publish at most once, keep alive and disconnect.
+type mqttSink struct {
+	address     string
+	topic       string
+	clientID    string
+	contentType string
+	keepAlive   time.Duration
+
+	mu   sync.Mutex // guards conn, which the keep alive shares with Send
+	conn net.Conn
+	stop chan struct{} // closed to stop pinging conn
+}
+
+func newMQTTSink(cfg *Config) (Sink, error) {
+	address := cfg.Target
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, mqttDefaultPort)
+	}
+
+	topic := cfg.Topic
+	if topic == "" {
+		topic = mqttDefaultTopic
+	}
+
+	ct, err := contentType(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mqttSink{
+		address:     address,
+		topic:       topic,
+		clientID:    fmt.Sprintf("vcrlive-%d", os.Getpid()),
+		contentType: ct,
+		keepAlive:   mqttKeepAlive,
+	}, nil
+}
+
+func (m *mqttSink) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		err := m.connect(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	payload, err := api.Marshal(m.contentType, msg.Payload)
+	if err != nil {
+		return err
+	}
+
+	return m.write(mqttPacket(mqttPublish, mqttString(m.topic+"/"+msg.Kind), payload))
+}
+
+func (m *mqttSink) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn == nil {
+		return nil
+	}
+
+	conn := m.conn
+
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, _ = conn.Write(mqttPacket(mqttDisconnect))
+
+	m.disconnect()
+
+	return conn.Close()
+}
+
+func (m *mqttSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: dialTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", m.address)
+	if err != nil {
+		return err
+	}
+
+	// Protocol name, level, flags and the keep alive in seconds
+	keepAlive := uint16(m.keepAlive / time.Second)
+	variableHeader := append(mqttString("MQTT"), mqttProtocolLevel, mqttCleanSession, byte(keepAlive>>8), byte(keepAlive)) //nolint:gocritic,mnd // big endian
+
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	_, err = conn.Write(mqttPacket(mqttConnect, variableHeader, mqttString(m.clientID)))
+	if err == nil {
+		err = readConnAck(conn)
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("can not connect to MQTT broker %s, err:%w", m.address, err)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	m.conn = conn
+	m.stop = make(chan struct{})
+
+	go m.ping(conn, m.stop)
+	go m.read(conn)
+
+	return nil
+}
+
+// write sends a packet on the connection, dropping it on failure so the next message reconnects. m.mu must be held.
+func (m *mqttSink) write(packet []byte) error {
+	_ = m.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	_, err := m.conn.Write(packet)
+	if err != nil {
+		_ = m.conn.Close()
+		m.disconnect()
+	}
+
+	return err
+}
+
+// disconnect forgets the connection and stops pinging it. m.mu must be held.
+func (m *mqttSink) disconnect() {
+	close(m.stop)
+	m.conn = nil
+}
+
+// ping sends a PINGREQ every half keep alive, whether or not messages are being published, so read can tell
+// from the broker's PINGRESP that the connection is still alive
+func (m *mqttSink) ping(conn net.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(m.keepAlive / 2) //nolint:mnd // well inside the keep alive
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+
+			if m.conn == conn {
+				_ = m.write(mqttPacket(mqttPingReq))
+			}
+
+			m.mu.Unlock()
+		}
+	}
+}
+
+// read consumes what the broker sends after CONNACK, i.e. PINGRESP, and drops the connection if the broker closes
+// it or goes a keep alive without answering a ping
+func (m *mqttSink) read(conn net.Conn) {
+	buf := make([]byte, 2) //nolint:mnd // PINGRESP
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(m.keepAlive))
+
+		_, err := conn.Read(buf)
+		if err == nil {
+			continue
+		}
+
+		m.mu.Lock()
+
+		if m.conn == conn {
+			logger.Warn("Lost connection to MQTT broker", "address", m.address, "err", err)
+
+			_ = conn.Close()
+			m.disconnect()
+		}
+
+		m.mu.Unlock()
+
+		return
+	}
+}
+
+func readConnAck(r io.Reader) error {
+	ack := make([]byte, 4) //nolint:mnd // fixed header, flags and return code
+
+	_, err := io.ReadFull(bufio.NewReader(r), ack)
+	if err != nil {
+		return err
+	}
+
+	if ack[0] != mqttConnAck {
+		return errors.New("expected CONNACK")
+	}
+
+	if ack[3] != 0 {
+		return fmt.Errorf("connection refused, return code %d", ack[3])
+	}
+
+	return nil
+}
+
+// mqttPacket assembles a fixed header with the remaining length followed by parts
+func mqttPacket(packetType byte, parts ...[]byte) []byte {
+	length := 0
+	for _, part := range parts {
+		length += len(part)
+	}
+
+	packet := []byte{packetType}
+
+	//nolint:mnd // variable length encoding, 7 bits per byte
+	for {
+		b := byte(length % 128)
+		length /= 128
+
+		if length > 0 {
+			b |= 0x80
+		}
+
+		packet = append(packet, b)
+
+		if length == 0 {
+			break
+		}
+	}
+
+	for _, part := range parts {
+		packet = append(packet, part...)
+	}
+
+	return packet
+}
+
+// mqttString is a UTF-8 string prefixed with its 16 bit length
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...) //nolint:mnd,gosec // big endian length
+}
@@ -0,0 +1,151 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMQTT(t *testing.T) {
+	t.Run("Should connect then publish to topic/kind", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		defer listener.Close()
+
+		received := make(chan []byte, 1)
+		failed := make(chan error, 1)
+
+		// Minimal broker, accept the connection and capture the first PUBLISH. Failures are reported to the test
+		// goroutine.
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				failed <- err
+				return
+			}
+
+			defer conn.Close()
+
+			r := bufio.NewReader(conn)
+			if connect := readPacket(r); connect[0] != mqttConnect {
+				failed <- fmt.Errorf("expected CONNECT, got %#x", connect[0])
+				return
+			}
+
+			_, _ = conn.Write([]byte{mqttConnAck, 2, 0, 0})
+
+			received <- readPacket(r)
+		}()
+
+		sink, err := newMQTTSink(&Config{Target: listener.Addr().String(), Topic: "league"})
+		require.NoError(t, err)
+
+		err = sink.Send(context.TODO(), &Message{Kind: KindEvent, Payload: &model.Event{Type: "session_start"}})
+		assert.NoError(t, err)
+
+		var publish []byte
+
+		select {
+		case publish = <-received:
+		case err := <-failed:
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, mqttPublish, publish[0])
+		assert.Contains(t, string(publish), "league/event")
+		assert.Contains(t, string(publish), `"type":"session_start"`)
+
+		assert.NoError(t, sink.Close())
+	})
+
+	t.Run("Should keep the connection alive and reconnect when the broker stops answering", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		defer listener.Close()
+
+		packets := make(chan []byte, 8)
+
+		// Broker that acknowledges each connection and reports the packets that follow, without answering pings
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+
+				r := bufio.NewReader(conn)
+				packets <- readPacket(r)
+
+				_, _ = conn.Write([]byte{mqttConnAck, 2, 0, 0})
+
+				packets <- readPacket(r)
+				packets <- readPacket(r)
+			}
+		}()
+
+		sink, err := newMQTTSink(&Config{Target: listener.Addr().String()})
+		require.NoError(t, err)
+
+		sink.(*mqttSink).keepAlive = time.Second
+
+		assert.NoError(t, sink.Send(context.TODO(), &Message{Kind: KindEvent, Payload: &model.Event{}}))
+
+		connect := <-packets
+		assert.Equal(t, mqttConnect, connect[0])
+		assert.Equal(t, []byte{0, 1}, connect[10:12], "keep alive in seconds")
+		assert.Equal(t, mqttPublish, (<-packets)[0])
+		assert.Equal(t, []byte{mqttPingReq, 0}, <-packets)
+
+		assert.Eventually(t, func() bool {
+			sink.(*mqttSink).mu.Lock()
+			defer sink.(*mqttSink).mu.Unlock()
+
+			return sink.(*mqttSink).conn == nil
+		}, 3*time.Second, 10*time.Millisecond, "no PINGRESP within the keep alive")
+
+		assert.NoError(t, sink.Send(context.TODO(), &Message{Kind: KindEvent, Payload: &model.Event{}}))
+		assert.Equal(t, mqttConnect, (<-packets)[0])
+		assert.Equal(t, mqttPublish, (<-packets)[0])
+
+		assert.NoError(t, sink.Close())
+	})
+
+	t.Run("Should encode long remaining lengths", func(t *testing.T) {
+		packet := mqttPacket(mqttPublish, make([]byte, 321))
+		assert.Equal(t, []byte{mqttPublish, 0xc1, 0x02}, packet[:3])
+		assert.Len(t, packet, 324)
+	})
+}
+
+func readPacket(r *bufio.Reader) []byte {
+	packet := make([]byte, 1)
+	_, _ = io.ReadFull(r, packet)
+
+	length, multiplier := 0, 1
+
+	for {
+		b, _ := r.ReadByte()
+		packet = append(packet, b)
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	_, _ = io.ReadFull(r, body)
+
+	return append(packet, body...)
+}
@@ -0,0 +1,249 @@
+//go:generate mockgen -package sinks -destination sinks_mock.go -source sinks.go
+
+// Package sinks fans telemetry messages out to several destinations at once
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// Message kinds
+const (
+	KindPositions = model.KindPositions
+	KindEvent     = model.KindEvent
+)
+
+// Message is a single payload for a sink. Payload is a *model.LivePositions or *model.Event depending on Kind.
+type Message struct {
+	Kind    string
+	Payload any
+}
+
+// Sink is a destination for messages. Send is never called concurrently for the same sink.
+type Sink interface {
+	Send(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Config describes one sink
+type Config struct {
+	Name         string            // for logging, defaults to the target
+	Type         string            // http, file, stdout, unix or mqtt
+	Target       string            // URL, path, socket or broker address depending on Type
+	Topic        string            // MQTT topic prefix
+	Kinds        []string          // only send these message kinds, all if empty
+	SessionTypes []string          // only send messages for these session types, e.g. Race, all if empty
+	MinInterval  time.Duration     // minimum time between positions messages, 0 for no limit
+	Encoding     string            // json, cbor or msgpack, json if empty
+	Compression  string            // HTTP request bodies, gzip or zstd, none if empty
+	Timeout      time.Duration     // HTTP budget for each message, api.DefaultTimeout if 0
+	RateLimit    float64           // HTTP requests per second, unlimited if 0
+	Breaker      int               // HTTP failures in a row opening the circuit, api.DefaultBreakerThreshold if 0
+	Cooldown     time.Duration     // HTTP time the circuit stays open, api.DefaultBreakerCooldown if 0
+	Headers      map[string]string // extra HTTP headers
+	Auth         Auth              // HTTP authentication
+}
+
+// Auth types
+const (
+	AuthNone   = ""
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "apikey"
+)
+
+// Auth is how an HTTP sink authenticates with the endpoint
+type Auth struct {
+	Type     string // none, basic, bearer or apikey
+	UserName string // basic
+	Password string // basic
+	Token    string // bearer
+	Key      string // apikey, sent as X-API-KEY
+	Prefix   string // apikey, optional prefix for the key
+}
+
+// Factory creates a sink from its configuration
+type Factory func(cfg *Config) (Sink, error)
+
+var registry = map[string]Factory{
+	"http":   newHTTPSink,
+	"stdout": newStdoutSink,
+	"file":   newFileSink,
+	"unix":   newUnixSink,
+	"mqtt":   newMQTTSink,
+}
+
+// Types lists the registered sink types
+func Types() []string {
+	return slices.Sorted(maps.Keys(registry))
+}
+
+// Register adds or replaces the factory for a sink type
+func Register(sinkType string, factory Factory) {
+	registry[sinkType] = factory
+}
+
+// Open creates the sink described by cfg
+func Open(cfg *Config) (Sink, error) {
+	factory, ok := registry[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+
+	if cfg.Encoding != "" && cfg.Encoding != EncodingJSON && cfg.Type == "stdout" {
+		return nil, fmt.Errorf("%s encoding can not be printed to the console", cfg.Encoding)
+	}
+
+	return factory(cfg)
+}
+
+// ParseSpec turns a command line sink specification into a Config. Specifications are URLs,
+//
+//	https://example.com/live              POST to a URL
+//	stdout                                print to the console
+//	file:///tmp/live.jsonl                append JSON lines to a file
+//	unix:///tmp/live.sock                 write JSON lines to a Unix socket
+//	mqtt://broker:1883/vcrlive            publish to an MQTT broker, topics vcrlive/positions and vcrlive/event
+//
+// with optional query parameters kinds=positions,event, sessions=Race, interval=5s, encoding=cbor, compress=gzip,
+// timeout=5s, rate=2, breaker=5 and cooldown=30s which are not passed on.
+func ParseSpec(spec string) (*Config, error) {
+	if spec == "stdout" {
+		return &Config{Name: spec, Type: "stdout"}, nil
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q, err:%w", spec, err)
+	}
+
+	query := u.Query()
+	cfg := &Config{Kinds: splitList(query.Get("kinds")), SessionTypes: splitList(query.Get("sessions"))}
+
+	if interval := query.Get("interval"); interval != "" {
+		cfg.MinInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sink interval %q, err:%w", interval, err)
+		}
+	}
+
+	cfg.Encoding = query.Get("encoding")
+
+	_, err = contentType(cfg.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q, err:%w", spec, err)
+	}
+
+	cfg.Compression = query.Get("compress")
+
+	err = checkCompression(cfg.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q, err:%w", spec, err)
+	}
+
+	if timeout := query.Get("timeout"); timeout != "" {
+		cfg.Timeout, err = time.ParseDuration(timeout)
+		if err != nil || cfg.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid sink timeout %q, expected a positive duration", timeout)
+		}
+	}
+
+	if rate := query.Get("rate"); rate != "" {
+		cfg.RateLimit, err = strconv.ParseFloat(rate, 64)
+		if err != nil || cfg.RateLimit <= 0 {
+			return nil, fmt.Errorf("invalid sink rate %q, expected requests per second greater than 0", rate)
+		}
+	}
+
+	if breaker := query.Get("breaker"); breaker != "" {
+		cfg.Breaker, err = strconv.Atoi(breaker)
+		if err != nil || cfg.Breaker <= 0 {
+			return nil, fmt.Errorf("invalid sink breaker %q, expected failures greater than 0", breaker)
+		}
+	}
+
+	if cooldown := query.Get("cooldown"); cooldown != "" {
+		cfg.Cooldown, err = time.ParseDuration(cooldown)
+		if err != nil || cfg.Cooldown <= 0 {
+			return nil, fmt.Errorf("invalid sink cooldown %q, expected a positive duration", cooldown)
+		}
+	}
+
+	query.Del("kinds")
+	query.Del("sessions")
+	query.Del("interval")
+	query.Del("encoding")
+	query.Del("compress")
+	query.Del("timeout")
+	query.Del("rate")
+	query.Del("breaker")
+	query.Del("cooldown")
+	u.RawQuery = query.Encode()
+
+	switch u.Scheme {
+	case "http", "https":
+		cfg.Type = "http"
+		cfg.Target = u.String()
+	case "stdout":
+		cfg.Type = "stdout"
+	case "file":
+		cfg.Type = "file"
+		cfg.Target = u.Host + u.Path
+	case "unix":
+		cfg.Type = "unix"
+		cfg.Target = u.Host + u.Path
+	case "mqtt":
+		cfg.Type = "mqtt"
+		cfg.Target = u.Host
+		cfg.Topic = strings.Trim(u.Path, "/")
+	default:
+		return nil, fmt.Errorf("invalid sink %q, unknown scheme %q", spec, u.Scheme)
+	}
+
+	if cfg.Type != "stdout" && cfg.Target == "" {
+		return nil, fmt.Errorf("invalid sink %q, missing target", spec)
+	}
+
+	cfg.Name = cfg.Type + ":" + cfg.Target
+
+	return cfg, nil
+}
+
+// accepts applies the kind and session type filters
+func (c *Config) accepts(msg *Message) bool {
+	if len(c.Kinds) > 0 && !slices.Contains(c.Kinds, msg.Kind) {
+		return false
+	}
+
+	if len(c.SessionTypes) == 0 {
+		return true
+	}
+
+	var sessionType string
+
+	switch payload := msg.Payload.(type) {
+	case *model.LivePositions:
+		sessionType = payload.Session.SessionType
+	case *model.Event:
+		sessionType = payload.Session.SessionType
+	}
+
+	return slices.ContainsFunc(c.SessionTypes, func(s string) bool { return strings.EqualFold(s, sessionType) })
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ",")
+}
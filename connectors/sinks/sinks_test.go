@@ -0,0 +1,150 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/api"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpec(t *testing.T) {
+	t.Run("Should parse each sink type", func(t *testing.T) {
+		tests := []struct {
+			spec     string
+			expected Config
+		}{
+			{"stdout", Config{Name: "stdout", Type: "stdout"}},
+			{"https://example.com/live?key=1", Config{Name: "http:https://example.com/live?key=1", Type: "http", Target: "https://example.com/live?key=1"}},
+			{"file:///tmp/live.jsonl", Config{Name: "file:/tmp/live.jsonl", Type: "file", Target: "/tmp/live.jsonl"}},
+			{"unix:///tmp/live.sock", Config{Name: "unix:/tmp/live.sock", Type: "unix", Target: "/tmp/live.sock"}},
+			{"mqtt://broker:1883/league/live", Config{Name: "mqtt:broker:1883", Type: "mqtt", Target: "broker:1883", Topic: "league/live"}},
+		}
+
+		for _, tt := range tests {
+			cfg, err := ParseSpec(tt.spec)
+			assert.NoError(t, err, tt.spec)
+			assert.Equal(t, tt.expected, *cfg, tt.spec)
+		}
+	})
+
+	t.Run("Should take filters and rate from the query", func(t *testing.T) {
+		cfg, err := ParseSpec("https://example.com/live?kinds=event&sessions=Race,Qualify&interval=5s&key=1")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/live?key=1", cfg.Target)
+		assert.Equal(t, []string{"event"}, cfg.Kinds)
+		assert.Equal(t, []string{"Race", "Qualify"}, cfg.SessionTypes)
+		assert.Equal(t, 5*time.Second, cfg.MinInterval)
+
+		cfg, err = ParseSpec("mqtt://broker/league?encoding=cbor")
+		assert.NoError(t, err)
+		assert.Equal(t, EncodingCBOR, cfg.Encoding)
+
+		cfg, err = ParseSpec("https://example.com/live?compress=zstd")
+		assert.NoError(t, err)
+		assert.Equal(t, "zstd", cfg.Compression)
+		assert.Equal(t, "https://example.com/live", cfg.Target)
+
+		cfg, err = ParseSpec("https://example.com/live?timeout=5s&rate=0.5&breaker=3&cooldown=1m")
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Second, cfg.Timeout)
+		assert.InDelta(t, 0.5, cfg.RateLimit, 0)
+		assert.Equal(t, 3, cfg.Breaker)
+		assert.Equal(t, time.Minute, cfg.Cooldown)
+		assert.Equal(t, "https://example.com/live", cfg.Target)
+	})
+
+	t.Run("Should reject bad specifications", func(t *testing.T) {
+		for _, spec := range []string{"ftp://example.com", "file://", "https://example.com?interval=soon", "http://a b", "stdout://?encoding=yaml", "https://example.com?compress=lz4",
+			"https://example.com?timeout=0s", "https://example.com?rate=fast",
+			"https://example.com?breaker=0", "https://example.com?cooldown=-1s"} {
+			_, err := ParseSpec(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	t.Run("File sink should write a sequence of binary envelopes", func(t *testing.T) {
+		for _, encoding := range []string{EncodingCBOR, EncodingMsgPack} {
+			path := filepath.Join(t.TempDir(), "live.bin")
+
+			sink, err := Open(&Config{Type: "file", Target: path, Encoding: encoding})
+			require.NoError(t, err)
+
+			require.NoError(t, sink.Send(context.TODO(), &Message{Kind: KindPositions, Payload: &model.LivePositions{SchemaVersion: 1}}))
+			require.NoError(t, sink.Send(context.TODO(), &Message{Kind: KindEvent, Payload: &model.Event{Type: model.EventSessionStart}}))
+			require.NoError(t, sink.Close())
+
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.False(t, json.Valid(b), encoding)
+
+			var (
+				positions struct {
+					Kind string              `json:"kind"`
+					Data model.LivePositions `json:"data"`
+				}
+				event struct {
+					Kind string      `json:"kind"`
+					Data model.Event `json:"data"`
+				}
+			)
+
+			// Each value is self delimiting so the second starts where the first ends
+			contentType, _ := contentType(encoding)
+			first, err := api.Marshal(contentType, envelope{Kind: KindPositions, Data: &model.LivePositions{SchemaVersion: 1}})
+			require.NoError(t, err)
+
+			require.NoError(t, api.Unmarshal(contentType, b[:len(first)], &positions), encoding)
+			assert.Equal(t, KindPositions, positions.Kind, encoding)
+			assert.Equal(t, 1, positions.Data.SchemaVersion, encoding)
+
+			require.NoError(t, api.Unmarshal(contentType, b[len(first):], &event), encoding)
+			assert.Equal(t, KindEvent, event.Kind, encoding)
+			assert.Equal(t, model.EventSessionStart, event.Data.Type, encoding)
+		}
+	})
+
+	t.Run("Binary encodings should not go to the console", func(t *testing.T) {
+		_, err := Open(&Config{Type: "stdout", Encoding: EncodingMsgPack})
+		assert.ErrorContains(t, err, "console")
+
+		_, err = Open(&Config{Type: "http", Target: "http://localhost", Encoding: "yaml"})
+		assert.ErrorContains(t, err, "unknown encoding")
+	})
+}
+
+func TestAccepts(t *testing.T) {
+	race := &Message{Kind: KindPositions, Payload: &model.LivePositions{Session: model.Session{SessionType: "Race"}}}
+	practice := &Message{Kind: KindEvent, Payload: &model.Event{Session: model.Session{SessionType: "Practice"}}}
+
+	cfg := Config{}
+	assert.True(t, cfg.accepts(race))
+	assert.True(t, cfg.accepts(practice))
+
+	cfg = Config{SessionTypes: []string{"race"}}
+	assert.True(t, cfg.accepts(race))
+	assert.False(t, cfg.accepts(practice))
+
+	cfg = Config{Kinds: []string{KindEvent}}
+	assert.False(t, cfg.accepts(race))
+	assert.True(t, cfg.accepts(practice))
+}
+
+func TestAuth(t *testing.T) {
+	ctx := (&Auth{Type: AuthBearer, Token: "secret"}).apply(context.TODO())
+	assert.Equal(t, "secret", ctx.Value(api.ContextAccessToken))
+
+	ctx = (&Auth{Type: AuthBasic, UserName: "u", Password: "p"}).apply(context.TODO())
+	assert.Equal(t, api.BasicAuth{UserName: "u", Password: "p"}, ctx.Value(api.ContextBasicAuth))
+
+	ctx = (&Auth{}).apply(context.TODO())
+	assert.Nil(t, ctx.Value(api.ContextAPIKey))
+}
@@ -0,0 +1,123 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/connectors/api"
+)
+
+const (
+	// dialTimeout bounds connecting to a Unix socket or MQTT broker
+	dialTimeout = 5 * time.Second
+
+	// writeTimeout bounds writing a message so a listener or broker that stops reading can not hold up the sink
+	writeTimeout = 5 * time.Second
+)
+
+// envelope wraps each JSON line so a reader can tell the message kinds apart
+type envelope struct {
+	Kind string `json:"kind"`
+	Data any    `json:"data"`
+}
+
+// streamSink writes messages to a writer, either as indented JSON like the original console output or as a stream
+// of envelopes, JSON lines or a sequence of CBOR or MessagePack values
+type streamSink struct {
+	w           io.Writer
+	closer      io.Closer
+	lines       bool
+	contentType string
+}
+
+func newStdoutSink(_ *Config) (Sink, error) {
+	return NewWriterSink(os.Stdout, false), nil
+}
+
+func newFileSink(cfg *Config) (Sink, error) {
+	ct, err := contentType(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(cfg.Target, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644) //nolint:gosec // user supplied output file
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamSink{w: f, closer: f, lines: true, contentType: ct}, nil
+}
+
+// NewWriterSink writes messages to w, as JSON lines if lines is set
+func NewWriterSink(w io.Writer, lines bool) Sink {
+	return &streamSink{w: w, lines: lines, contentType: api.ContentTypeJSON}
+}
+
+func (s *streamSink) Send(_ context.Context, msg *Message) error {
+	if !s.lines {
+		_, err := fmt.Fprintln(s.w, msg.Payload)
+		return err
+	}
+
+	return api.Encode(s.w, s.contentType, envelope{Kind: msg.Kind, Data: msg.Payload})
+}
+
+func (s *streamSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// unixSink writes envelopes to a Unix socket like a file sink, reconnecting if the listener goes away
+type unixSink struct {
+	path         string
+	contentType  string
+	writeTimeout time.Duration
+	conn         net.Conn
+}
+
+func newUnixSink(cfg *Config) (Sink, error) {
+	ct, err := contentType(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unixSink{path: cfg.Target, contentType: ct, writeTimeout: writeTimeout}, nil
+}
+
+func (u *unixSink) Send(ctx context.Context, msg *Message) error {
+	if u.conn == nil {
+		dialer := net.Dialer{Timeout: dialTimeout}
+
+		conn, err := dialer.DialContext(ctx, "unix", u.path)
+		if err != nil {
+			return err
+		}
+
+		u.conn = conn
+	}
+
+	_ = u.conn.SetWriteDeadline(time.Now().Add(u.writeTimeout))
+
+	err := api.Encode(u.conn, u.contentType, envelope{Kind: msg.Kind, Data: msg.Payload})
+	if err != nil {
+		_ = u.conn.Close()
+		u.conn = nil
+	}
+
+	return err
+}
+
+func (u *unixSink) Close() error {
+	if u.conn == nil {
+		return nil
+	}
+
+	return u.conn.Close()
+}
@@ -0,0 +1,57 @@
+package sinks
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnix(t *testing.T) {
+	t.Run("A listener that stops reading should not hold up the sink", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "live.sock")
+
+		listener, err := net.Listen("unix", path)
+		require.NoError(t, err)
+
+		defer listener.Close()
+
+		// Accept the connection but never read from it
+		accepted := make(chan net.Conn, 1)
+
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				accepted <- conn
+			}
+		}()
+
+		sink, err := newUnixSink(&Config{Target: path})
+		require.NoError(t, err)
+
+		sink.(*unixSink).writeTimeout = 100 * time.Millisecond
+
+		// Far more than the socket buffers hold
+		big := &Message{Kind: KindEvent, Payload: strings.Repeat("x", 16<<20)}
+
+		start := time.Now()
+		err = sink.Send(context.TODO(), big)
+
+		assert.ErrorContains(t, err, "i/o timeout")
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.Nil(t, sink.(*unixSink).conn, "reconnect for the next message")
+
+		assert.NoError(t, sink.Close())
+
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package telemetry
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// defaultRetireAfter is how long a car must be out of the world before it is retired, longer than most tows
+const defaultRetireAfter = 2 * time.Minute
+
+// reading is what the detector needs of a car from one tick
+type reading struct {
+	position      int
+	classPosition int
+	lapsCompleted int
+	lapDistPct    float64
+	onPitRoad     bool
+	surface       int
+	flags         int // CarIdxSessionFlags
+}
+
+// detected is a race event or race control event before it is wrapped in a model.Event
+type detected struct {
+	eventType string
+	data      any // *model.RaceEvent or *model.RaceControl
+}
+
+// Detector finds race events by comparing successive readings of every car
+type Detector struct {
+	retireAfter  time.Duration
+	subSessionID int
+	sessionNum   int
+	cars         map[int]reading
+	racing       bool              // the previous readings were taken while racing
+	outSince     map[int]time.Time // when a car that had been racing left the world
+	retired      map[int]bool
+}
+
+func NewDetector() *Detector {
+	return &Detector{retireAfter: defaultRetireAfter}
+}
+
+// Detect returns what happened since the previous readings. The first readings taken while racing are the baseline,
+// so cars leaving the grid or pits at the start are not reported. Overtakes and lead changes are only reported in
+// races.
+func (d *Detector) Detect(now time.Time, st *state, cars map[int]reading) []detected {
+	previous := d.cars
+
+	if previous == nil || d.subSessionID != st.weekend.SubSessionID || d.sessionNum != st.session.SessionNum {
+		d.subSessionID, d.sessionNum = st.weekend.SubSessionID, st.session.SessionNum
+		d.outSince, d.retired = map[int]time.Time{}, map[int]bool{}
+		previous = nil
+	}
+
+	wasRacing := d.racing
+	d.cars = cars
+	d.racing = st.session.SessionState == "Racing"
+
+	if previous == nil || !wasRacing || !d.racing {
+		return nil
+	}
+
+	car := func(carIdx int) model.RaceCar {
+		return raceCar(st, cars, carIdx)
+	}
+
+	carIdxs := common(previous, cars)
+
+	var events []detected
+
+	if st.session.SessionType == "Race" {
+		events = append(events, d.overtakes(carIdxs, previous, car)...)
+		events = append(events, d.leadChanges(carIdxs, previous, st.drivers, car)...)
+	}
+
+	for _, carIdx := range carIdxs {
+		was, is := previous[carIdx], cars[carIdx]
+
+		switch {
+		case is.surface == irsdk.TrackNotInWorld || was.surface == irsdk.TrackNotInWorld:
+		case !was.onPitRoad && is.onPitRoad:
+			events = append(events, detected{model.EventPitIn, &model.RaceEvent{Car: car(carIdx)}})
+		case was.onPitRoad && !is.onPitRoad:
+			events = append(events, detected{model.EventPitOut, &model.RaceEvent{Car: car(carIdx)}})
+		}
+
+		if was.surface != irsdk.TrackOffTrack && was.surface != irsdk.TrackNotInWorld && is.surface == irsdk.TrackOffTrack {
+			events = append(events, detected{model.EventOffTrack, &model.RaceEvent{Car: car(carIdx)}})
+		}
+
+		if d.retire(now, carIdx, was, is) {
+			events = append(events, detected{model.EventRetired, &model.RaceEvent{Car: car(carIdx)}})
+		}
+	}
+
+	return events
+}
+
+// overtakes finds cars that gained a position from a car that was ahead and is now behind. Positions changing
+// because a car is in the pits or out of the world are not overtakes.
+func (d *Detector) overtakes(carIdxs []int, previous map[int]reading, car func(int) model.RaceCar) []detected {
+	var passes []model.RaceEvent
+
+	racing := func(r reading) bool {
+		return r.position > 0 && !r.onPitRoad && r.surface != irsdk.TrackNotInWorld
+	}
+
+	for _, a := range carIdxs {
+		wasA, isA := previous[a], d.cars[a]
+		if !racing(wasA) || !racing(isA) || isA.position >= wasA.position {
+			continue
+		}
+
+		for _, b := range carIdxs {
+			wasB, isB := previous[b], d.cars[b]
+			if a == b || !racing(wasB) || !racing(isB) {
+				continue
+			}
+
+			if wasB.position < wasA.position && isB.position > isA.position {
+				other := car(b)
+				passes = append(passes, model.RaceEvent{Car: car(a), Other: &other})
+			}
+		}
+	}
+
+	slices.SortStableFunc(passes, func(x, y model.RaceEvent) int {
+		return cmp.Or(cmp.Compare(x.Car.Position, y.Car.Position), cmp.Compare(x.Other.Position, y.Other.Position))
+	})
+
+	events := make([]detected, 0, len(passes))
+	for i := range passes {
+		events = append(events, detected{model.EventOvertake, &passes[i]})
+	}
+
+	return events
+}
+
+// leadChanges finds a new overall leader and new class leaders
+func (d *Detector) leadChanges(carIdxs []int, previous map[int]reading, drivers model.Drivers, car func(int) model.RaceCar) []detected {
+	var events []detected
+
+	leader := func(cars map[int]reading, classID int) (int, bool) {
+		for _, carIdx := range carIdxs {
+			r := cars[carIdx]
+
+			if classID == 0 && r.position == 1 || classID != 0 && r.classPosition == 1 && drivers[carIdx].CarClassID == classID {
+				return carIdx, true
+			}
+		}
+
+		return 0, false
+	}
+
+	change := func(eventType string, classID int) {
+		was, okWas := leader(previous, classID)
+		is, okIs := leader(d.cars, classID)
+
+		if okWas && okIs && was != is {
+			other := car(was)
+			events = append(events, detected{eventType, &model.RaceEvent{Car: car(is), Other: &other}})
+		}
+	}
+
+	change(model.EventLeadChange, 0)
+
+	classIDs := make([]int, 0)
+
+	for _, carIdx := range carIdxs {
+		if classID := drivers[carIdx].CarClassID; classID != 0 && !slices.Contains(classIDs, classID) {
+			classIDs = append(classIDs, classID)
+		}
+	}
+
+	slices.Sort(classIDs)
+
+	// A single class race has no class leader separate from the leader
+	if len(classIDs) > 1 {
+		for _, classID := range classIDs {
+			change(model.EventClassLeadChange, classID)
+		}
+	}
+
+	return events
+}
+
+// retire reports a car once it has been out of the world for retireAfter, having been in it while racing
+func (d *Detector) retire(now time.Time, carIdx int, was reading, is reading) bool {
+	if is.surface != irsdk.TrackNotInWorld {
+		delete(d.outSince, carIdx)
+		delete(d.retired, carIdx)
+
+		return false
+	}
+
+	since, ok := d.outSince[carIdx]
+	if !ok {
+		if was.surface != irsdk.TrackNotInWorld {
+			d.outSince[carIdx] = now
+		}
+
+		return false
+	}
+
+	if d.retired[carIdx] || now.Sub(since) < d.retireAfter {
+		return false
+	}
+
+	d.retired[carIdx] = true
+
+	return true
+}
+
+// raceCar describes a car from its driver and reading
+func raceCar(st *state, cars map[int]reading, carIdx int) model.RaceCar {
+	driver, r := st.drivers[carIdx], cars[carIdx]
+
+	return model.RaceCar{
+		CarIdx:        carIdx,
+		UserName:      driver.UserName,
+		CarNumberRaw:  driver.CarNumberRaw,
+		CarClassID:    driver.CarClassID,
+		Position:      r.position,
+		ClassPosition: r.classPosition,
+		LapsCompleted: r.lapsCompleted,
+		LapDistPct:    r.lapDistPct,
+	}
+}
+
+// common returns the cars in both readings, in car_idx order
+func common(previous map[int]reading, cars map[int]reading) []int {
+	carIdxs := make([]int, 0, len(cars))
+
+	for carIdx := range cars {
+		if _, ok := previous[carIdx]; ok {
+			carIdxs = append(carIdxs, carIdx)
+		}
+	}
+
+	slices.Sort(carIdxs)
+
+	return carIdxs
+}
@@ -0,0 +1,102 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector(t *testing.T) {
+	start := time.Date(2026, time.March, 14, 18, 0, 0, 0, time.UTC)
+
+	race := func(sessionState string) *state {
+		return &state{
+			weekend: model.Weekend{SubSessionID: 1},
+			session: model.Session{SessionNum: 2, SessionType: "Race", SessionState: sessionState},
+			drivers: model.Drivers{
+				1: {CarIdx: 1, UserName: "A", CarClassID: 84},
+				2: {CarIdx: 2, UserName: "B", CarClassID: 84},
+				3: {CarIdx: 3, UserName: "C", CarClassID: 83},
+			},
+		}
+	}
+
+	onTrack := func(position int, classPosition int, pct float64) reading {
+		return reading{position: position, classPosition: classPosition, lapDistPct: pct, surface: irsdk.TrackOnTrack}
+	}
+
+	types := func(events []detected) []string {
+		var types []string
+		for _, e := range events {
+			types = append(types, e.eventType)
+		}
+
+		return types
+	}
+
+	data := func(e detected) *model.RaceEvent {
+		return e.data.(*model.RaceEvent)
+	}
+
+	t.Run("A pass for the lead should be an overtake and a lead change", func(t *testing.T) {
+		d := NewDetector()
+
+		assert.Empty(t, d.Detect(start, race("Racing"), map[int]reading{1: onTrack(1, 1, 0.5), 2: onTrack(2, 2, 0.49), 3: onTrack(3, 1, 0.2)}))
+
+		events := d.Detect(start.Add(time.Second), race("Racing"), map[int]reading{1: onTrack(2, 2, 0.52), 2: onTrack(1, 1, 0.53), 3: onTrack(3, 1, 0.22)})
+		assert.Equal(t, []string{model.EventOvertake, model.EventLeadChange, model.EventClassLeadChange}, types(events))
+		assert.Equal(t, 2, data(events[0]).Car.CarIdx)
+		assert.Equal(t, 1, data(events[0]).Other.CarIdx)
+		assert.InDelta(t, 0.53, data(events[0]).Car.LapDistPct, 0)
+		assert.Equal(t, 84, data(events[2]).Car.CarClassID)
+	})
+
+	t.Run("Losing places in the pits should not be an overtake", func(t *testing.T) {
+		d := NewDetector()
+		d.Detect(start, race("Racing"), map[int]reading{1: onTrack(1, 1, 0.9), 2: onTrack(2, 2, 0.8), 3: onTrack(3, 1, 0.7)})
+
+		pitting := onTrack(3, 2, 0.95)
+		pitting.onPitRoad = true
+
+		events := d.Detect(start.Add(time.Second), race("Racing"), map[int]reading{1: pitting, 2: onTrack(1, 1, 0.85), 3: onTrack(2, 1, 0.75)})
+		assert.Equal(t, []string{model.EventLeadChange, model.EventClassLeadChange, model.EventPitIn}, types(events))
+
+		events = d.Detect(start.Add(time.Minute), race("Racing"), map[int]reading{1: onTrack(3, 2, 0.05), 2: onTrack(1, 1, 0.9), 3: onTrack(2, 1, 0.8)})
+		assert.Equal(t, []string{model.EventPitOut}, types(events))
+	})
+
+	t.Run("Off tracks and retirements should be reported once", func(t *testing.T) {
+		d := NewDetector()
+		d.Detect(start, race("Racing"), map[int]reading{1: onTrack(1, 1, 0.1), 2: onTrack(2, 2, 0.2)})
+
+		off := onTrack(1, 1, 0.11)
+		off.surface = irsdk.TrackOffTrack
+		out := reading{position: 2, classPosition: 2, surface: irsdk.TrackNotInWorld}
+
+		assert.Equal(t, []string{model.EventOffTrack}, types(d.Detect(start.Add(time.Second), race("Racing"), map[int]reading{1: off, 2: out})))
+		assert.Empty(t, d.Detect(start.Add(time.Minute), race("Racing"), map[int]reading{1: off, 2: out}))
+
+		events := d.Detect(start.Add(3*time.Minute), race("Racing"), map[int]reading{1: onTrack(1, 1, 0.3), 2: out})
+		assert.Equal(t, []string{model.EventRetired}, types(events))
+		assert.Equal(t, 2, data(events[0]).Car.CarIdx)
+
+		assert.Empty(t, d.Detect(start.Add(4*time.Minute), race("Racing"), map[int]reading{1: onTrack(1, 1, 0.4), 2: out}))
+	})
+
+	t.Run("The start and other sessions should not be reported", func(t *testing.T) {
+		d := NewDetector()
+
+		inPits := reading{position: 1, classPosition: 1, onPitRoad: true, surface: irsdk.TrackInPitStall}
+		d.Detect(start, race("Parade Laps"), map[int]reading{1: inPits, 2: onTrack(2, 2, 0.1)})
+		assert.Empty(t, d.Detect(start.Add(time.Second), race("Racing"), map[int]reading{1: onTrack(2, 2, 0.1), 2: onTrack(1, 1, 0.2)}))
+
+		practice := race("Racing")
+		practice.session.SessionNum, practice.session.SessionType = 0, "Practice"
+
+		d.Detect(start, practice, map[int]reading{1: onTrack(1, 1, 0.1), 2: onTrack(2, 2, 0.2)})
+		assert.Empty(t, d.Detect(start.Add(time.Second), practice, map[int]reading{1: onTrack(2, 2, 0.1), 2: onTrack(1, 1, 0.2)}))
+	})
+}
@@ -0,0 +1,219 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/irsdk/scenario"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/ianhaycox/vcrlive/standings"
+	"github.com/ianhaycox/vcrlive/tyres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run in this package with -update after an intended change to the payloads, and check the diff of testdata
+var (
+	update = flag.Bool("update", false, "rewrite the golden files from the current output")
+	record = flag.Bool("record", false, "regenerate the recordings from their scenarios")
+)
+
+// golden is a recording, how it is run and the expected payloads in testdata/<name>.golden.jsonl
+type golden struct {
+	name     string
+	scenario *scenario.Scenario
+	run      func(ctx context.Context, tm *Telemetry) error
+}
+
+// capture is a VcrStandingsAPI keeping everything posted, one JSON line per post
+type capture struct {
+	lines []string
+}
+
+// message is a line of a golden file
+type message struct {
+	Post  *model.LivePositions `json:"post,omitempty"`
+	Event *model.Event         `json:"event,omitempty"`
+}
+
+func (c *capture) Post(_ context.Context, livePositions *model.LivePositions) error {
+	return c.add(message{Post: livePositions})
+}
+
+func (c *capture) PostEvent(_ context.Context, event *model.Event) error {
+	return c.add(message{Event: event})
+}
+
+func (c *capture) add(m message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	c.lines = append(c.lines, string(b))
+
+	return nil
+}
+
+func goldens() []golden {
+	multiclass := func(seed int64) *scenario.Scenario {
+		return scenario.New(seed).
+			TickRate(4).
+			Event(228, 4921, 70000001).
+			Track(168, "Suzuka International Racing Course", "Grand Prix", 5.807).
+			Class(scenario.Class{ID: 84, Name: "GT3", LapTime: 15 * time.Second, Variance: 200 * time.Millisecond}).
+			Class(scenario.Class{ID: 83, Name: "GT4", LapTime: 17 * time.Second, Variance: 300 * time.Millisecond}).
+			Driver(scenario.Driver{CarIdx: 1, Name: "Jürgen Müller", UserID: 123456, CarNumber: 7, IRating: 2650, ClassID: 84}).
+			Field(84, 5).
+			Field(83, 4)
+	}
+
+	return []golden{
+		{
+			name: "race",
+			scenario: multiclass(1).
+				Race(4).
+				Pit(2, 2, 4*time.Second).
+				OffTrack(3, 1, 0.5, 3*time.Second).
+				Disconnect(9, 3, 0.5),
+			run: func(ctx context.Context, tm *Telemetry) error {
+				return tm.Run(ctx, 1, 1000, 10)
+			},
+		},
+		{
+			name: "weekend",
+			scenario: multiclass(2).
+				Practice(30*time.Second).
+				Qualify(30*time.Second).
+				Race(3).
+				Weather(scenario.Weather{AirTemp: 24, TrackTemp: 32, WindVel: 3, Wetness: 1, Skies: 2}).
+				Weather(scenario.Weather{At: 15 * time.Second, AirTemp: 23, TrackTemp: 30, WindVel: 3, Wetness: 1, Skies: 3}).
+				Weather(scenario.Weather{At: 25 * time.Second, AirTemp: 21, TrackTemp: 26, Precipitation: 0.4, WindVel: 6, Wetness: 4, Skies: 3, DeclaredWet: true}).
+				Weather(scenario.Weather{At: 45 * time.Second, AirTemp: 22, TrackTemp: 27, WindVel: 4, Wetness: 2, Skies: 2, DeclaredWet: true}).
+				Disconnect(5, 2, 0.5),
+			run: func(ctx context.Context, tm *Telemetry) error {
+				return tm.Supervise(ctx, 1, 1000, 10)
+			},
+		},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, g := range goldens() {
+		t.Run(g.name, func(t *testing.T) {
+			recording := filepath.Join("testdata", g.name+".vcr")
+			if *record {
+				recordScenario(t, recording, g.scenario)
+			}
+
+			got := replay(t, recording, g.run)
+
+			path := filepath.Join("testdata", g.name+".golden.jsonl")
+			if *update {
+				err := os.WriteFile(path, []byte(strings.Join(got, "\n")+"\n"), 0o644) //nolint:gosec // checked in
+				require.NoError(t, err)
+			}
+
+			want := readLines(t, path)
+			assert.Equal(t, want, got, "payloads differ from %s, run go test -update in this package if intended", path)
+		})
+	}
+}
+
+// replay runs a recording as fast as possible through telemetry with a clock driven by its ticks so the output is
+// the same every time
+func replay(t *testing.T, recording string, run func(ctx context.Context, tm *Telemetry) error) []string {
+	t.Helper()
+
+	f, err := os.Open(recording)
+	require.NoError(t, err)
+
+	player, err := irsdk.NewPlayer(f, 0)
+	require.NoError(t, err)
+
+	sdk := irsdk.NewIrSDK(player)
+	defer sdk.Close()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	go func() {
+		<-player.Done()
+		cancel()
+	}()
+
+	start := time.Date(2026, time.March, 14, 18, 0, 0, 0, time.UTC)
+	tickRate := time.Duration(irsdk.Inspect(player).TickRate)
+
+	championship, err := standings.New(standings.Rules{Points: []int{25, 18, 15, 12, 10, 8, 6, 4, 2, 1}, FastestLap: 1, Pole: 1}, "")
+	require.NoError(t, err)
+
+	service := &capture{}
+	tm := NewTelemetry(sdk, service, false).WithRaceEvents().WithRaceControl().WithChampionship(championship).WithRatingEstimates().
+		WithWeather().WithTyres(tyres.NewTracker())
+	tm.detector.retireAfter = 10 * time.Second // the scenarios are short
+	tm.now = func() time.Time {
+		return start.Add(time.Duration(max(0, sdk.GetLastVersion())) * time.Second / tickRate)
+	}
+
+	require.NoError(t, run(ctx, tm))
+
+	return service.lines
+}
+
+// recordScenario writes a recording of every tick of a scenario
+func recordScenario(t *testing.T, path string, sc *scenario.Scenario) {
+	t.Helper()
+
+	gen, err := sc.Start()
+	require.NoError(t, err)
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	sdk := irsdk.NewIrSDK(gen)
+	defer sdk.Close()
+
+	rec, err := irsdk.NewRecorder(sdk, f)
+	require.NoError(t, err)
+
+	require.NoError(t, rec.Capture())
+
+	for sdk.WaitForData(0) {
+		require.NoError(t, rec.Capture())
+	}
+
+	require.NoError(t, rec.Close())
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err, "run go test -record -update in this package to create it")
+
+	defer f.Close()
+
+	var lines []string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	require.NoError(t, scanner.Err())
+
+	return lines
+}
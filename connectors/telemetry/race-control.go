@@ -0,0 +1,145 @@
+package telemetry
+
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+const (
+	// cautionFlags are shown while a full course caution is out
+	cautionFlags = irsdk.FlagCaution | irsdk.FlagCautionWaving
+
+	// sessionTimePrecision rounds the session time to the millisecond
+	sessionTimePrecision = 1000
+)
+
+// carFlags are the flags shown to a single car that race control reports
+var carFlags = []struct {
+	flag      int
+	eventType string
+}{
+	{irsdk.FlagBlack, model.EventBlackFlag},
+	{irsdk.FlagRepair, model.EventMeatballFlag},
+	{irsdk.FlagDisqualify, model.EventDisqualified},
+}
+
+// RaceControl follows SessionFlags and CarIdxSessionFlags, reporting flags as they are shown
+type RaceControl struct {
+	subSessionID int
+	sessionNum   int
+	started      bool
+	flags        int // SessionFlags at the previous sample
+	cars         map[int]reading
+	caution      *model.Caution // the full course caution that is out
+}
+
+func NewRaceControl() *RaceControl {
+	return &RaceControl{}
+}
+
+// Check returns the flags shown since the previous sample. The first sample of a session is the baseline, so joining
+// part way through does not report flags that are already out.
+func (rc *RaceControl) Check(st *state, sessionFlags int, sessionTime float64, cars map[int]reading) []detected {
+	laps := leaderLaps(st)
+	sessionTime = math.Round(sessionTime*sessionTimePrecision) / sessionTimePrecision
+	previous, was := rc.cars, rc.flags
+
+	rc.cars, rc.flags = cars, sessionFlags
+
+	if !rc.started || rc.subSessionID != st.weekend.SubSessionID || rc.sessionNum != st.session.SessionNum {
+		rc.subSessionID, rc.sessionNum, rc.started = st.weekend.SubSessionID, st.session.SessionNum, true
+		rc.caution = nil
+
+		if cautionOut(sessionFlags) {
+			rc.caution = &model.Caution{FromLap: laps, FromSessionTime: sessionTime}
+		}
+
+		return nil
+	}
+
+	var events []detected
+
+	control := func(eventType string) *model.RaceControl {
+		data := &model.RaceControl{LapsCompleted: laps, SessionTime: sessionTime}
+		events = append(events, detected{eventType, data})
+
+		return data
+	}
+
+	shown := sessionFlags &^ was
+	caution := cautionOut(sessionFlags)
+
+	var ended *model.Caution
+
+	switch {
+	case rc.caution == nil && caution:
+		rc.caution = &model.Caution{FromLap: laps, FromSessionTime: sessionTime}
+		control(model.EventCaution)
+	case rc.caution != nil && !caution:
+		ended, rc.caution = rc.caution, nil
+		ended.ToLap, ended.ToSessionTime = laps, sessionTime
+	}
+
+	switch {
+	case ended != nil && shown&irsdk.FlagCheckered == 0:
+		control(model.EventRestart).Caution = ended
+	case shown&irsdk.FlagGreen != 0:
+		control(model.EventGreenFlag)
+	}
+
+	if shown&irsdk.FlagYellow != 0 && !caution {
+		control(model.EventLocalYellow).Cars = offTrack(st, cars)
+	}
+
+	if shown&irsdk.FlagWhite != 0 {
+		control(model.EventWhiteFlag)
+	}
+
+	// A race finishing under caution ends with the checkered flag rather than a restart
+	if shown&irsdk.FlagCheckered != 0 {
+		control(model.EventCheckeredFlag).Caution = ended
+	}
+
+	for _, carIdx := range common(previous, cars) {
+		for _, f := range carFlags {
+			if cars[carIdx].flags&f.flag != 0 && previous[carIdx].flags&f.flag == 0 {
+				control(f.eventType).Cars = []model.RaceCar{raceCar(st, cars, carIdx)}
+			}
+		}
+	}
+
+	return events
+}
+
+// cautionOut is true while a full course caution is out, which the checkered flag ends
+func cautionOut(sessionFlags int) bool {
+	return sessionFlags&cautionFlags != 0 && sessionFlags&irsdk.FlagCheckered == 0
+}
+
+// offTrack returns the cars off the racing surface, where a local yellow is likely to be
+func offTrack(st *state, cars map[int]reading) []model.RaceCar {
+	var off []model.RaceCar
+
+	for _, carIdx := range slices.Sorted(maps.Keys(cars)) {
+		if cars[carIdx].surface == irsdk.TrackOffTrack {
+			off = append(off, raceCar(st, cars, carIdx))
+		}
+	}
+
+	return off
+}
+
+// leaderLaps is the most laps completed by any driver
+func leaderLaps(st *state) int {
+	laps := 0
+
+	for _, driver := range st.drivers {
+		laps = max(laps, driver.LapsCompleted)
+	}
+
+	return laps
+}
@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaceControl(t *testing.T) {
+	race := func(laps int) *state {
+		return &state{
+			weekend: model.Weekend{SubSessionID: 1},
+			session: model.Session{SessionNum: 2, SessionType: "Race", SessionState: "Racing"},
+			drivers: model.Drivers{
+				1: {CarIdx: 1, UserName: "A", LapsCompleted: laps},
+				2: {CarIdx: 2, UserName: "B", LapsCompleted: laps - 1},
+			},
+		}
+	}
+
+	cars := map[int]reading{1: {position: 1, surface: irsdk.TrackOnTrack}, 2: {position: 2, surface: irsdk.TrackOnTrack}}
+
+	types := func(events []detected) []string {
+		var types []string
+		for _, e := range events {
+			types = append(types, e.eventType)
+		}
+
+		return types
+	}
+
+	data := func(e detected) *model.RaceControl {
+		return e.data.(*model.RaceControl)
+	}
+
+	t.Run("A caution should be reported with its laps and times on the restart", func(t *testing.T) {
+		rc := NewRaceControl()
+
+		assert.Empty(t, rc.Check(race(0), irsdk.FlagStartReady, 100, cars))
+
+		events := rc.Check(race(0), irsdk.FlagGreen|irsdk.FlagStartGo, 101.2504, cars)
+		assert.Equal(t, []string{model.EventGreenFlag}, types(events))
+		assert.InDelta(t, 101.25, data(events[0]).SessionTime, 0)
+
+		assert.Equal(t, []string{model.EventCaution}, types(rc.Check(race(5), irsdk.FlagCautionWaving, 700, cars)))
+		assert.Empty(t, rc.Check(race(6), irsdk.FlagCaution|irsdk.FlagOneLapToGreen, 800, cars))
+
+		events = rc.Check(race(7), irsdk.FlagGreen, 900, cars)
+		require.Equal(t, []string{model.EventRestart}, types(events))
+		assert.Equal(t, &model.Caution{FromLap: 5, ToLap: 7, FromSessionTime: 700, ToSessionTime: 900}, data(events[0]).Caution)
+		assert.Equal(t, 7, data(events[0]).LapsCompleted)
+	})
+
+	t.Run("A race finishing under caution should end with the checkered flag", func(t *testing.T) {
+		rc := NewRaceControl()
+		rc.Check(race(8), 0, 1000, cars)
+
+		assert.Equal(t, []string{model.EventCaution}, types(rc.Check(race(9), irsdk.FlagCaution, 1100, cars)))
+		assert.Equal(t, []string{model.EventWhiteFlag}, types(rc.Check(race(9), irsdk.FlagCaution|irsdk.FlagWhite, 1150, cars)))
+
+		events := rc.Check(race(10), irsdk.FlagCaution|irsdk.FlagCheckered, 1200, cars)
+		require.Equal(t, []string{model.EventCheckeredFlag}, types(events))
+		assert.Equal(t, 9, data(events[0]).Caution.FromLap)
+		assert.Equal(t, 10, data(events[0]).Caution.ToLap)
+	})
+
+	t.Run("A local yellow should locate the cars off track", func(t *testing.T) {
+		rc := NewRaceControl()
+		rc.Check(race(2), 0, 300, cars)
+
+		off := map[int]reading{1: cars[1], 2: {position: 2, lapDistPct: 0.37, surface: irsdk.TrackOffTrack}}
+
+		events := rc.Check(race(2), irsdk.FlagYellow, 310, off)
+		require.Equal(t, []string{model.EventLocalYellow}, types(events))
+		require.Len(t, data(events[0]).Cars, 1)
+		assert.Equal(t, 2, data(events[0]).Cars[0].CarIdx)
+		assert.InDelta(t, 0.37, data(events[0]).Cars[0].LapDistPct, 0)
+
+		assert.Empty(t, rc.Check(race(2), irsdk.FlagYellow, 311, off))
+	})
+
+	t.Run("Flags shown to a car should be reported once", func(t *testing.T) {
+		rc := NewRaceControl()
+		rc.Check(race(2), 0, 300, cars)
+
+		flagged := map[int]reading{1: cars[1], 2: {position: 2, flags: irsdk.FlagServicible | irsdk.FlagBlack | irsdk.FlagRepair}}
+
+		events := rc.Check(race(2), 0, 310, flagged)
+		assert.Equal(t, []string{model.EventBlackFlag, model.EventMeatballFlag}, types(events))
+		assert.Equal(t, "B", data(events[0]).Cars[0].UserName)
+
+		assert.Empty(t, rc.Check(race(2), 0, 311, flagged))
+
+		disqualified := map[int]reading{1: cars[1], 2: {position: 2, flags: irsdk.FlagDisqualify}}
+		assert.Equal(t, []string{model.EventDisqualified}, types(rc.Check(race(3), 0, 400, disqualified)))
+	})
+
+	t.Run("Flags already out when joining a session should not be reported", func(t *testing.T) {
+		rc := NewRaceControl()
+
+		assert.Empty(t, rc.Check(race(4), irsdk.FlagCaution|irsdk.FlagWhite, 500, cars))
+
+		events := rc.Check(race(5), irsdk.FlagGreen, 600, cars)
+		require.Equal(t, []string{model.EventRestart}, types(events))
+		assert.Equal(t, 4, data(events[0]).Caution.FromLap)
+
+		next := race(0)
+		next.session.SessionNum = 3
+		assert.Empty(t, rc.Check(next, irsdk.FlagGreen, 0, cars))
+	})
+}
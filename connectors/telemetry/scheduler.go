@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"time"
+
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// Scheduler decides when a LivePositions payload should be posted.
+//
+// Significant changes (session state, a new class leader, drivers joining or leaving) are posted
+// straight away, bursts of changes are coalesced so posts are never closer together than
+// minInterval, and when nothing is happening a heartbeat is sent every heartbeat interval.
+type Scheduler struct {
+	minInterval time.Duration
+	heartbeat   time.Duration
+	lastPost    time.Time
+	last        *snapshot
+	pending     bool
+}
+
+// snapshot is the part of a payload that triggers an immediate post when it changes
+type snapshot struct {
+	sessionNum   int
+	sessionState string
+	leaders      map[int]int // CarClassID -> CarIdx of the class leader
+	drivers      map[int]struct{}
+}
+
+func NewScheduler(minInterval, heartbeat time.Duration) *Scheduler {
+	return &Scheduler{
+		minInterval: minInterval,
+		heartbeat:   heartbeat,
+	}
+}
+
+// Due reports whether livePositions should be posted now. Call Posted after a successful post.
+func (s *Scheduler) Due(now time.Time, livePositions *model.LivePositions) bool {
+	current := newSnapshot(livePositions)
+
+	if s.last == nil || s.last.changed(current) {
+		s.pending = true
+	}
+
+	s.last = current
+
+	sinceLast := now.Sub(s.lastPost)
+
+	if s.pending && sinceLast >= s.minInterval {
+		return true
+	}
+
+	return sinceLast >= s.heartbeat
+}
+
+// Pending reports whether a change has been seen that has not been posted yet
+func (s *Scheduler) Pending() bool {
+	return s.pending
+}
+
+// Posted records a post at now
+func (s *Scheduler) Posted(now time.Time) {
+	s.lastPost = now
+	s.pending = false
+}
+
+func newSnapshot(livePositions *model.LivePositions) *snapshot {
+	snap := &snapshot{
+		sessionNum:   livePositions.Session.SessionNum,
+		sessionState: livePositions.Session.SessionState,
+		leaders:      make(map[int]int),
+		drivers:      make(map[int]struct{}, len(livePositions.Drivers)),
+	}
+
+	for i := range livePositions.Drivers {
+		driver := &livePositions.Drivers[i]
+
+		snap.drivers[driver.CarIdx] = struct{}{}
+
+		if driver.ClassPosition == 1 {
+			snap.leaders[driver.CarClassID] = driver.CarIdx
+		}
+	}
+
+	return snap
+}
+
+func (s *snapshot) changed(other *snapshot) bool {
+	if s.sessionNum != other.sessionNum || s.sessionState != other.sessionState {
+		return true
+	}
+
+	if len(s.leaders) != len(other.leaders) || len(s.drivers) != len(other.drivers) {
+		return true
+	}
+
+	for classID, carIdx := range s.leaders {
+		if leader, ok := other.leaders[classID]; !ok || leader != carIdx {
+			return true
+		}
+	}
+
+	for carIdx := range s.drivers {
+		if _, ok := other.drivers[carIdx]; !ok {
+			return true
+		}
+	}
+
+	return false
+}
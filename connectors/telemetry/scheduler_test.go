@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	racing := func(leader int) *model.LivePositions {
+		return &model.LivePositions{
+			Session: model.Session{SessionNum: 1, SessionState: "Racing"},
+			Drivers: []model.Driver{
+				{CarIdx: 1, CarClassID: 10, ClassPosition: 3 - leader},
+				{CarIdx: 2, CarClassID: 10, ClassPosition: leader},
+			},
+		}
+	}
+
+	t.Run("First payload should be posted immediately", func(t *testing.T) {
+		s := NewScheduler(time.Second, 10*time.Second)
+		assert.True(t, s.Due(start, racing(1)))
+	})
+
+	t.Run("Unchanged payload should wait for the heartbeat", func(t *testing.T) {
+		s := NewScheduler(time.Second, 10*time.Second)
+		assert.True(t, s.Due(start, racing(1)))
+		s.Posted(start)
+
+		assert.False(t, s.Due(start.Add(5*time.Second), racing(1)))
+		assert.True(t, s.Due(start.Add(10*time.Second), racing(1)))
+	})
+
+	t.Run("Lead change should be posted after the minimum interval", func(t *testing.T) {
+		s := NewScheduler(time.Second, 10*time.Second)
+		assert.True(t, s.Due(start, racing(1)))
+		s.Posted(start)
+
+		assert.False(t, s.Due(start.Add(500*time.Millisecond), racing(2)))
+		assert.True(t, s.Pending())
+
+		// Lead swaps back, still pending until posted
+		assert.True(t, s.Due(start.Add(time.Second), racing(1)))
+		s.Posted(start.Add(time.Second))
+		assert.False(t, s.Pending())
+	})
+
+	t.Run("Session state change should be posted", func(t *testing.T) {
+		s := NewScheduler(time.Second, 10*time.Second)
+		assert.True(t, s.Due(start, racing(1)))
+		s.Posted(start)
+
+		checkered := racing(1)
+		checkered.Session.SessionState = "Checkered"
+		assert.True(t, s.Due(start.Add(2*time.Second), checkered))
+	})
+
+	t.Run("Driver leaving should be posted", func(t *testing.T) {
+		s := NewScheduler(time.Second, 10*time.Second)
+		assert.True(t, s.Due(start, racing(1)))
+		s.Posted(start)
+
+		left := racing(1)
+		left.Drivers = left.Drivers[1:]
+		assert.True(t, s.Due(start.Add(2*time.Second), left))
+	})
+}
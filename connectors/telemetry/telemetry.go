@@ -3,23 +3,71 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"maps"
+	"math"
 	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/ianhaycox/vcrlive/connectors/vcrstandings"
+	"github.com/ianhaycox/vcrlive/fuel"
 	"github.com/ianhaycox/vcrlive/irsdk"
 	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/ianhaycox/vcrlive/logging"
 	"github.com/ianhaycox/vcrlive/model"
+	"github.com/ianhaycox/vcrlive/rating"
+	"github.com/ianhaycox/vcrlive/relative"
+	"github.com/ianhaycox/vcrlive/standings"
+	"github.com/ianhaycox/vcrlive/trackmap"
+	"github.com/ianhaycox/vcrlive/tyres"
+)
+
+var logger = logging.Component("telemetry")
+
+const (
+	// finalPostTimeout bounds the last post made after the context has been cancelled
+	finalPostTimeout = 5 * time.Second
+
+	// lapDistPrecision rounds where a car is on track to about half a metre on a 5km lap
+	lapDistPrecision = 10000
 )
 
 type Telemetry struct {
-	sdk     irsdk.SDK
-	service vcrstandings.VcrStandingsAPI
-	redact  bool
+	sdk          irsdk.SDK
+	service      vcrstandings.VcrStandingsAPI
+	redact       bool
+	now          func() time.Time
+	detector     *Detector
+	raceControl  *RaceControl
+	championship *standings.Championship
+	ratings      bool
+	fuel         *fuel.Calculator
+	tyres        *tyres.Tracker
+	weather      *WeatherTimeline
+	trackMap     *trackmap.Mapper
+	relative     *relative.Board
+}
+
+// state carries what has been read from the simulator between samples
+type state struct {
+	latestTick int
+	irSession  iryaml.IRSession
+	weekend    model.Weekend
+	session    model.Session
+	drivers    model.Drivers
+}
+
+// varError is returned when a telemetry variable can not be read
+type varError struct {
+	name string
+	err  error
+}
+
+func (e *varError) Error() string {
+	return fmt.Sprintf("can not determine %s, err:%v", e.name, e.err)
 }
 
 func NewTelemetry(sdk irsdk.SDK, service vcrstandings.VcrStandingsAPI, redact bool) *Telemetry {
@@ -27,106 +75,874 @@ func NewTelemetry(sdk irsdk.SDK, service vcrstandings.VcrStandingsAPI, redact bo
 		sdk:     sdk,
 		service: service,
 		redact:  redact,
+		now:     time.Now,
 	}
 }
 
-func (t *Telemetry) Run(ctx context.Context, waitMilliseconds int, refreshSeconds int) error {
-	var (
-		irSession iryaml.IRSession
-		session   model.Session
-		weekend   model.Weekend
-		drivers   model.Drivers
-	)
+// WithRaceEvents also posts overtakes, lead changes, pit stops, off tracks and retirements as events
+func (t *Telemetry) WithRaceEvents() *Telemetry {
+	t.detector = NewDetector()
 
-	latestTick := -1
+	return t
+}
 
-	for {
-		t.sdk.WaitForData(time.Duration(waitMilliseconds) * time.Millisecond)
+// WithRaceControl also posts the flags shown to the field and to each car as events
+func (t *Telemetry) WithRaceControl() *Telemetry {
+	t.raceControl = NewRaceControl()
 
-		tick := t.sdk.GetLastVersion()
-		if tick != latestTick {
-			latestTick = tick
-			irSession = t.sdk.GetSession()
+	return t
+}
 
-			sessionNum, err := t.sdk.GetVarValue("SessionNum")
-			if err != nil {
-				session.SetState(model.Invalid)
-				session.ErrorText = fmt.Sprintf("Can not determine SessionNum, err:%v, bailing...", err)
+// WithChampionship adds the championship table to the positions posted during races
+func (t *Telemetry) WithChampionship(championship *standings.Championship) *Telemetry {
+	t.championship = championship
 
-				break
-			}
+	return t
+}
 
-			weekend = model.NewWeekend(&irSession.WeekendInfo)
-			session = model.NewSession(sessionNum.(int), irSession.SessionInfo.Sessions)
-			drivers = model.NewDrivers(irSession.DriverInfo.Drivers, t.redact)
-		}
+// WithRatingEstimates adds the projected iRating change of each driver to the positions posted during races
+func (t *Telemetry) WithRatingEstimates() *Telemetry {
+	t.ratings = true
+
+	return t
+}
+
+// WithFuel measures the fuel used by the player's car with calculator
+func (t *Telemetry) WithFuel(calculator *fuel.Calculator) *Telemetry {
+	t.fuel = calculator
 
-		state, err := t.sdk.GetVarValue("SessionState")
+	return t
+}
+
+// WithTyres keeps the history of the player's tyres with tracker
+func (t *Telemetry) WithTyres(tracker *tyres.Tracker) *Telemetry {
+	t.tyres = tracker
+
+	return t
+}
+
+// WithWeather adds the weather now and a timeline of its changes to the positions
+func (t *Telemetry) WithWeather() *Telemetry {
+	t.weather = NewWeatherTimeline()
+
+	return t
+}
+
+// WithTrackMap places every car on the map of the track with mapper each sample
+func (t *Telemetry) WithTrackMap(mapper *trackmap.Mapper) *Telemetry {
+	t.trackMap = mapper
+
+	return t
+}
+
+// WithRelative keeps where every car is on track for the relative timing board each sample
+func (t *Telemetry) WithRelative(board *relative.Board) *Telemetry {
+	t.relative = board
+
+	return t
+}
+
+// Run samples the simulator every waitMilliseconds and posts the live positions as soon as something significant
+// changes, but no more often than every minIntervalMilliseconds, and at least every refreshSeconds.
+//
+// Run returns when the session cools down, the telemetry can not be read or ctx is cancelled. In every case a final
+// payload is posted, with a session_state of Stopped when cancelled.
+func (t *Telemetry) Run(ctx context.Context, waitMilliseconds int, minIntervalMilliseconds int, refreshSeconds int) error {
+	var (
+		pending *model.LivePositions
+		final   *model.Championship
+	)
+
+	wait := time.Duration(waitMilliseconds) * time.Millisecond
+	st := state{latestTick: -1}
+	scheduler := NewScheduler(time.Duration(minIntervalMilliseconds)*time.Millisecond, time.Duration(refreshSeconds)*time.Second)
+
+	for ctx.Err() == nil {
+		t.waitForData(ctx, wait, st.latestTick)
+
+		livePositions, sessionState, err := t.sample(&st)
 		if err != nil {
-			session.SetState(model.Invalid)
-			session.ErrorText = fmt.Sprintf("Can not determine SessionState, err:%v, bailing...", err)
+			st.session.SetState(model.Invalid)
+			st.session.ErrorText = bailing(err)
 
 			break
 		}
 
-		session.SetState(state.(int))
-
-		if state.(int) == model.Invalid {
-			log.Printf("State invalid at tick:%d, ignored", tick)
+		if sessionState == model.Invalid {
+			logger.Debug("State invalid, ignored", "tick", st.latestTick)
 			continue
 		}
 
-		if state.(int) == model.CoolDown {
+		if sessionState == model.CoolDown {
+			final = t.finalTable(&st)
 			break
 		}
 
-		positions, err := t.sdk.GetVarValues("CarIdxClassPosition")
+		cars := t.readCars(&st)
+		t.detect(ctx, &st, cars)
+		t.measureFuel(&st)
+		t.measureTyres(&st)
+		t.mapTrack(&st, cars)
+		t.measureRelative(&st, cars)
+		pending = t.schedule(ctx, scheduler, &st, livePositions)
+	}
+
+	// POSTs either CoolDown, Stopped or error
+	return t.finish(ctx, scheduler, pending, &st, final)
+}
+
+// Supervise is like Run but keeps going across practice, qualifying, race and the next subsession until ctx is
+// cancelled. A session_start event is posted when a new session is seen and a session_end event when it cools down,
+// is replaced by the next session or the simulator goes away.
+//
+//nolint:gocognit // lifecycle
+func (t *Telemetry) Supervise(ctx context.Context, waitMilliseconds int, minIntervalMilliseconds int, refreshSeconds int) error {
+	var (
+		pending *model.LivePositions
+		active  *state // the session started but not yet ended
+	)
+
+	wait := time.Duration(waitMilliseconds) * time.Millisecond
+	st := state{latestTick: -1}
+	scheduler := NewScheduler(time.Duration(minIntervalMilliseconds)*time.Millisecond, time.Duration(refreshSeconds)*time.Second)
+
+	for ctx.Err() == nil {
+		t.waitForData(ctx, wait, st.latestTick)
+
+		livePositions, sessionState, err := t.sample(&st)
 		if err != nil {
-			session.SetState(model.Invalid)
-			session.ErrorText = fmt.Sprintf("Can not determine CarIdxClassPosition, err:%v, bailing...", err)
+			if active != nil {
+				logger.Warn("Lost telemetry", "err", err, "session_num", active.session.SessionNum)
+				t.postEvent(ctx, model.EventSessionEnd, model.EndDisconnected, active)
 
-			break
+				active = nil
+			}
+
+			// Simulator not running, try again shortly
+			st.latestTick = -1
+			sleep(ctx, wait)
+
+			continue
 		}
 
-		drivers.SetPositions(positions.([]int))
+		if active != nil && !sameSession(active, &st) {
+			t.postEvent(ctx, model.EventSessionEnd, model.EndNextSession, active)
 
-		laps, err := t.sdk.GetVarValues("CarIdxLapCompleted")
-		if err != nil {
-			session.SetState(model.Invalid)
-			session.ErrorText = fmt.Sprintf("Can not determine CarIdxLapCompleted, err:%v, bailing...", err)
+			active = nil
+		}
 
-			break
+		if sessionState == model.Invalid {
+			continue
 		}
 
-		drivers.SetLaps(laps.([]int))
+		if sessionState == model.CoolDown {
+			if active != nil {
+				t.post(ctx, &model.LivePositions{SchemaVersion: model.SchemaVersion, Session: st.session, Championship: t.finalTable(&st)})
+				t.postEvent(ctx, model.EventSessionEnd, model.EndCoolDown, &st)
+
+				active = nil
+			}
+
+			continue
+		}
 
-		sortedDrivers := slices.Collect(maps.Values(drivers))
-		sort.Slice(sortedDrivers, func(i, j int) bool { return sortedDrivers[i].CarIdx < sortedDrivers[j].CarIdx })
+		if active == nil {
+			t.postEvent(ctx, model.EventSessionStart, "", &st)
 
-		livePositions := model.LivePositions{
-			Weekend: weekend,
-			Session: session,
-			Drivers: sortedDrivers,
+			active = &state{weekend: st.weekend, session: st.session}
+			pending = nil
+			scheduler = NewScheduler(time.Duration(minIntervalMilliseconds)*time.Millisecond, time.Duration(refreshSeconds)*time.Second)
 		}
 
-		err = t.service.Post(ctx, &livePositions)
+		cars := t.readCars(&st)
+		t.detect(ctx, &st, cars)
+		t.measureFuel(&st)
+		t.measureTyres(&st)
+		t.mapTrack(&st, cars)
+		t.measureRelative(&st, cars)
+		pending = t.schedule(ctx, scheduler, &st, livePositions)
+	}
+
+	if active == nil {
+		return nil
+	}
+
+	err := t.finish(ctx, scheduler, pending, &st, nil)
+	t.postEvent(context.WithoutCancel(ctx), model.EventSessionEnd, model.EndStopped, &st)
+
+	return err
+}
+
+// waitForData waits up to wait for the tick after latestTick. If there is none it sleeps out the rest of wait, so the
+// loop does not spin when the SDK returns at once, as it does on Linux and for a snapshot.
+func (t *Telemetry) waitForData(ctx context.Context, wait time.Duration, latestTick int) {
+	start := time.Now()
+
+	if t.sdk.WaitForData(wait) && t.sdk.GetLastVersion() != latestTick {
+		return
+	}
+
+	sleep(ctx, wait-time.Since(start))
+}
+
+// sample reads the latest telemetry into st, returning the positions to post and the iRacing session state
+func (t *Telemetry) sample(st *state) (*model.LivePositions, int, error) {
+	tick := t.sdk.GetLastVersion()
+	if tick != st.latestTick {
+		st.latestTick = tick
+		st.irSession = t.sdk.GetSession()
+
+		sessionNum, err := t.sdk.GetVarValue("SessionNum")
 		if err != nil {
-			session.SetState(model.Invalid)
-			session.ErrorText = fmt.Sprintf("Can not POST to endpoint, err:%v, bailing...", err)
+			return nil, model.Invalid, &varError{"SessionNum", err}
+		}
+
+		st.weekend = model.NewWeekend(&st.irSession.WeekendInfo)
+		st.session = model.NewSession(sessionNum.(int), st.irSession.SessionInfo.Sessions)
+		st.drivers = model.NewDrivers(st.irSession.DriverInfo.Drivers, t.redact)
+	}
+
+	sessionState, err := t.sdk.GetVarValue("SessionState")
+	if err != nil {
+		return nil, model.Invalid, &varError{"SessionState", err}
+	}
+
+	st.session.SetState(sessionState.(int))
+
+	if sessionState.(int) == model.Invalid || sessionState.(int) == model.CoolDown {
+		return nil, sessionState.(int), nil
+	}
+
+	positions, err := t.sdk.GetVarValues("CarIdxClassPosition")
+	if err != nil {
+		return nil, model.Invalid, &varError{"CarIdxClassPosition", err}
+	}
+
+	st.drivers.SetPositions(positions.([]int))
+
+	laps, err := t.sdk.GetVarValues("CarIdxLapCompleted")
+	if err != nil {
+		return nil, model.Invalid, &varError{"CarIdxLapCompleted", err}
+	}
+
+	st.drivers.SetLaps(laps.([]int))
+
+	st.drivers.SetOverallPositions(t.optionalPositions())
+	st.drivers.SetTiming(t.optionalTimes("CarIdxF2Time"), t.optionalTimes("CarIdxBestLapTime"))
+
+	sortedDrivers := slices.Collect(maps.Values(st.drivers))
+	sort.Slice(sortedDrivers, func(i, j int) bool { return sortedDrivers[i].CarIdx < sortedDrivers[j].CarIdx })
+
+	var safety *model.Safety
+
+	// The running order only counts once the race has started
+	if t.ratings && st.session.SessionType == "Race" && (sessionState.(int) == model.Racing || sessionState.(int) == model.Checkered) {
+		rating.Project(sortedDrivers)
+		safety = t.readSafety(st)
+	}
+
+	return &model.LivePositions{
+		SchemaVersion: model.SchemaVersion,
+		Weekend:       st.weekend,
+		Session:       st.session,
+		CarClasses:    model.NewCarClasses(st.irSession.DriverInfo.Drivers, st.drivers),
+		Drivers:       sortedDrivers,
+		Weather:       t.readWeather(st),
+		Tyres:         t.tyreReport(),
+		Safety:        safety,
+	}, sessionState.(int), nil
+}
+
+// schedule posts livePositions with the projected championship if the scheduler says it is due, otherwise or if the
+// post fails returns it as pending
+func (t *Telemetry) schedule(ctx context.Context, scheduler *Scheduler, st *state, livePositions *model.LivePositions) *model.LivePositions {
+	now := t.now()
+	if !scheduler.Due(now, livePositions) {
+		return livePositions
+	}
+
+	livePositions.Championship = t.projectedTable(st)
+
+	err := t.service.Post(ctx, livePositions)
+	if err != nil {
+		st.session.SetState(model.Invalid)
+		st.session.ErrorText = fmt.Sprintf("Can not POST to endpoint, err:%v, bailing...", err)
+
+		return livePositions
+	}
+
+	scheduler.Posted(now)
+
+	return nil
+}
+
+// finish posts the final payload for a session, with the final championship table if any, flushing anything pending
+// first if ctx was cancelled
+func (t *Telemetry) finish(ctx context.Context, scheduler *Scheduler, pending *model.LivePositions, st *state,
+	final *model.Championship,
+) error {
+	session := st.session
+
+	// The context may already be cancelled, but the server still needs to hear how the feed ended
+	finalCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalPostTimeout)
+	defer cancel()
+
+	if ctx.Err() != nil {
+		// Deliver any change that was waiting for the minimum interval before saying goodbye
+		if pending != nil && scheduler.Pending() {
+			pending.Championship = t.projectedTable(st)
+
+			err := t.service.Post(finalCtx, pending)
+			if err != nil {
+				logger.Error("Can not flush pending positions", "err", err)
+			}
 		}
 
-		time.Sleep(time.Duration(refreshSeconds) * time.Second)
+		session.SetState(model.Stopped)
 	}
 
 	livePositions := model.LivePositions{
-		Session: session,
+		SchemaVersion: model.SchemaVersion,
+		Session:       session,
+		Championship:  final,
 	}
 
-	// POSTs either CoolDown or error
-	err := t.service.Post(ctx, &livePositions)
+	err := t.service.Post(finalCtx, &livePositions)
 	if err != nil {
 		return fmt.Errorf("can not final post, err:%s", err)
 	}
 
 	return err
 }
+
+func (t *Telemetry) post(ctx context.Context, livePositions *model.LivePositions) {
+	err := t.service.Post(ctx, livePositions)
+	if err != nil {
+		logger.Error("Can not POST to endpoint", "err", err)
+	}
+}
+
+func (t *Telemetry) postEvent(ctx context.Context, eventType string, reason string, st *state) {
+	event := model.NewEvent(eventType, t.now(), st.weekend.SubSessionID, st.session)
+	event.Reason = reason
+
+	err := t.service.PostEvent(ctx, &event)
+	if err != nil {
+		logger.Error("Can not POST event", "type", eventType, "err", err)
+	}
+}
+
+// detect posts the race events and race control flags since the previous sample, if enabled. Telemetry without the
+// cars, such as an old recording, has no race events.
+func (t *Telemetry) detect(ctx context.Context, st *state, cars map[int]reading) {
+	if (t.detector == nil && t.raceControl == nil) || cars == nil {
+		return
+	}
+
+	now := t.now()
+
+	var events []detected
+
+	if t.detector != nil {
+		events = append(events, t.detector.Detect(now, st, cars)...)
+	}
+
+	if t.raceControl != nil {
+		events = append(events, t.checkFlags(st, cars)...)
+	}
+
+	for _, d := range events {
+		event := model.NewEvent(d.eventType, now, st.weekend.SubSessionID, st.session)
+		event.Data = d.data
+
+		err := t.service.PostEvent(ctx, &event)
+		if err != nil {
+			logger.Error("Can not POST event", "type", d.eventType, "err", err)
+		}
+	}
+}
+
+// checkFlags reads the session flags for race control
+func (t *Telemetry) checkFlags(st *state, cars map[int]reading) []detected {
+	sessionFlags, err := t.sdk.GetVarValue("SessionFlags")
+	if err != nil {
+		logger.Debug("No race control", "err", &varError{"SessionFlags", err})
+		return nil
+	}
+
+	sessionTime, err := t.sdk.GetVarValue("SessionTime")
+	if err != nil {
+		logger.Debug("No race control", "err", &varError{"SessionTime", err})
+		return nil
+	}
+
+	flags, _ := sessionFlags.(int)
+	seconds, _ := sessionTime.(float64)
+
+	return t.raceControl.Check(st, flags, seconds, cars)
+}
+
+// measureFuel passes what the player's car is doing to the fuel calculator, if enabled. A spectator or a recording
+// without the variables has no fuel measurements.
+func (t *Telemetry) measureFuel(st *state) {
+	if t.fuel == nil {
+		return
+	}
+
+	values, err := t.readVars(
+		"FuelLevel", "FuelLevelPct", "FuelUsePerHour", "LapCompleted", "LapDistPct", "IsOnTrack", "OnPitRoad",
+		"SessionFlags", "SessionTime", "SessionLapsRemainEx", "SessionTimeRemain",
+	)
+	if err != nil {
+		logger.Debug("No fuel measurements", "err", err)
+		return
+	}
+
+	level, _ := values["FuelLevel"].(float32)
+	levelPct, _ := values["FuelLevelPct"].(float32)
+	kgPerHour, _ := values["FuelUsePerHour"].(float32)
+	lapDistPct, _ := values["LapDistPct"].(float32)
+	flags, _ := values["SessionFlags"].(int)
+
+	r := fuel.Reading{
+		SubSessionID: st.weekend.SubSessionID,
+		SessionNum:   st.session.SessionNum,
+		Level:        float64(level),
+		LevelPct:     float64(levelPct),
+		LapDistPct:   float64(lapDistPct),
+		Caution:      flags&cautionFlags != 0,
+	}
+
+	r.SessionTime, _ = values["SessionTime"].(float64)
+	r.TimeRemaining, _ = values["SessionTimeRemain"].(float64)
+	r.LapsCompleted, _ = values["LapCompleted"].(int)
+	r.LapsRemaining, _ = values["SessionLapsRemainEx"].(int)
+	r.OnTrack, _ = values["IsOnTrack"].(bool)
+	r.OnPitRoad, _ = values["OnPitRoad"].(bool)
+
+	// iRacing reports use by weight and the tank may be restricted by the series
+	driverInfo := &st.irSession.DriverInfo
+	if driverInfo.DriverCarFuelKgPerLtr > 0 {
+		r.UsePerHour = float64(kgPerHour) / driverInfo.DriverCarFuelKgPerLtr
+	}
+
+	r.Capacity = driverInfo.DriverCarFuelMaxLtr
+	if driverInfo.DriverCarMaxFuelPct > 0 {
+		r.Capacity *= driverInfo.DriverCarMaxFuelPct
+	}
+
+	t.fuel.Update(r)
+}
+
+// measureTyres passes the player's tyres to the tyre tracker, if enabled. A spectator or a recording without the
+// variables has no tyre history.
+func (t *Telemetry) measureTyres(st *state) {
+	if t.tyres == nil {
+		return
+	}
+
+	lapsCompleted, err := t.sdk.GetVarValue("LapCompleted")
+	if err != nil {
+		logger.Debug("No tyre history", "err", &varError{"LapCompleted", err})
+		return
+	}
+
+	r := tyres.Reading{
+		SubSessionID: st.weekend.SubSessionID,
+		SessionNum:   st.session.SessionNum,
+		Setup:        st.irSession.CarSetup,
+	}
+
+	r.LapsCompleted, _ = lapsCompleted.(int)
+
+	// iRacing names the corners in capitals, LFtempCL etc
+	for c, corner := range tyres.Corners {
+		prefix := strings.ToUpper(corner)
+
+		var names []string
+		for _, name := range []string{"TiresUsed", "tempCL", "tempCM", "tempCR", "wearL", "wearM", "wearR", "coldPressure"} {
+			names = append(names, prefix+name)
+		}
+
+		values, err := t.readVars(names...)
+		if err != nil {
+			logger.Debug("No tyre history", "err", err)
+			return
+		}
+
+		r.Used[c], _ = values[prefix+"TiresUsed"].(int)
+		r.Temps[c] = float32s(values[prefix+"tempCL"], values[prefix+"tempCM"], values[prefix+"tempCR"])
+		r.Wear[c] = float32s(values[prefix+"wearL"], values[prefix+"wearM"], values[prefix+"wearR"])
+		coldPressure, _ := values[prefix+"coldPressure"].(float32)
+		r.ColdPressure[c] = float64(coldPressure)
+	}
+
+	err = t.tyres.Update(&r)
+	if err != nil {
+		logger.Debug("Tyres not measured", "err", err)
+	}
+}
+
+// tyreReport is the player's tyres for the positions, if enabled
+func (t *Telemetry) tyreReport() *model.Tyres {
+	if t.tyres == nil {
+		return nil
+	}
+
+	return t.tyres.Report()
+}
+
+// mapTrack places every car on the track map, if enabled, learning the outline from the player's car. A spectator or
+// a recording without the variables sees the cars around a circle.
+func (t *Telemetry) mapTrack(st *state, cars map[int]reading) {
+	if t.trackMap == nil || cars == nil {
+		return
+	}
+
+	sessionTime, err := t.sdk.GetVarValue("SessionTime")
+	if err != nil {
+		logger.Debug("No track map", "err", &varError{"SessionTime", err})
+		return
+	}
+
+	r := trackmap.Reading{TrackID: st.weekend.TrackID, TrackConfig: st.weekend.TrackConfigName, Player: t.readPlayer()}
+	r.SessionTime, _ = sessionTime.(float64)
+
+	for _, carIdx := range slices.Sorted(maps.Keys(cars)) {
+		r.Cars = append(r.Cars, trackmap.Car{CarIdx: carIdx, LapDistPct: cars[carIdx].lapDistPct, OnPitRoad: cars[carIdx].onPitRoad})
+	}
+
+	err = t.trackMap.Update(&r)
+	if err != nil {
+		logger.Warn("Track map", "err", err)
+	}
+}
+
+// readPlayer reads where the player's car is going to learn the track outline, nil for a spectator. iRacing only
+// gives the latitude and longitude in disk telemetry.
+func (t *Telemetry) readPlayer() *trackmap.Player {
+	values, err := t.readVars("LapDistPct", "IsOnTrack", "OnPitRoad", "VelocityX", "VelocityY", "Yaw")
+	if err != nil {
+		logger.Debug("No track outline", "err", err)
+		return nil
+	}
+
+	p := trackmap.Player{}
+	p.OnTrack, _ = values["IsOnTrack"].(bool)
+	p.OnPitRoad, _ = values["OnPitRoad"].(bool)
+
+	for name, v := range map[string]*float64{
+		"LapDistPct": &p.LapDistPct, "VelocityX": &p.VelocityX, "VelocityY": &p.VelocityY, "Yaw": &p.Yaw,
+	} {
+		f, _ := values[name].(float32)
+		*v = float64(f)
+	}
+
+	if lat, err := t.sdk.GetVarValue("Lat"); err == nil {
+		p.Lat, _ = lat.(float64)
+	}
+
+	if lon, err := t.sdk.GetVarValue("Lon"); err == nil {
+		p.Lon, _ = lon.(float64)
+	}
+
+	return &p
+}
+
+// measureRelative passes where every car is on track to the relative timing board, if enabled. A recording without
+// the variables has an empty board.
+func (t *Telemetry) measureRelative(st *state, cars map[int]reading) {
+	if t.relative == nil || cars == nil {
+		return
+	}
+
+	values, err := t.readCarVars("CarIdxLap", "CarIdxEstTime")
+	if err != nil {
+		logger.Debug("No relative", "err", err)
+		return
+	}
+
+	laps, _ := values["CarIdxLap"].([]int)
+	estTimes, _ := values["CarIdxEstTime"].([]float32)
+
+	r := relative.Reading{
+		PlayerCarIdx: st.irSession.DriverInfo.DriverCarIdx,
+		CameraCarIdx: -1,
+		Race:         st.session.SessionType == "Race",
+	}
+
+	if v, err := t.sdk.GetVarValue("SessionTime"); err == nil {
+		r.SessionTime, _ = v.(float64)
+	}
+
+	if v, err := t.sdk.GetVarValue("CamCarIdx"); err == nil {
+		r.CameraCarIdx, _ = v.(int)
+	}
+
+	classes := map[int]model.CarClass{}
+	for _, class := range model.NewCarClasses(st.irSession.DriverInfo.Drivers, st.drivers) {
+		classes[class.CarClassID] = class
+	}
+
+	numbers := map[int]string{}
+	for _, d := range st.irSession.DriverInfo.Drivers {
+		numbers[d.CarIdx] = d.CarNumber
+	}
+
+	for _, carIdx := range slices.Sorted(maps.Keys(cars)) {
+		if carIdx >= len(laps) || carIdx >= len(estTimes) {
+			continue
+		}
+
+		driver, c := st.drivers[carIdx], cars[carIdx]
+		class := classes[driver.CarClassID]
+
+		r.Cars = append(r.Cars, relative.Car{
+			CarIdx:        carIdx,
+			UserName:      driver.UserName,
+			CarNumber:     numbers[carIdx],
+			CarClassID:    driver.CarClassID,
+			ClassColor:    class.Color,
+			EstLapTime:    class.EstLapTime,
+			Position:      c.position,
+			ClassPosition: c.classPosition,
+			IRating:       driver.IRating,
+			Lap:           laps[carIdx],
+			LapDistPct:    c.lapDistPct,
+			EstTime:       float64(estTimes[carIdx]),
+			OnPitRoad:     c.onPitRoad,
+		})
+	}
+
+	t.relative.Update(r)
+}
+
+// readWeather reads the live weather into the timeline, if enabled. Recordings made before iRacing had dynamic
+// weather have no weather.
+func (t *Telemetry) readWeather(st *state) *model.Weather {
+	if t.weather == nil {
+		return nil
+	}
+
+	values, err := t.readVars(
+		"SessionTime", "AirTemp", "TrackTempCrew", "WindVel", "WindDir", "Precipitation", "TrackWetness", "Skies",
+		"WeatherDeclaredWet",
+	)
+	if err != nil {
+		logger.Debug("No weather", "err", err)
+		return nil
+	}
+
+	r := model.WeatherReading{}
+	r.SessionTime, _ = values["SessionTime"].(float64)
+	r.TrackWetness, _ = values["TrackWetness"].(int)
+	r.Skies, _ = values["Skies"].(int)
+	r.DeclaredWet, _ = values["WeatherDeclaredWet"].(bool)
+
+	for name, v := range map[string]*float64{
+		"AirTemp": &r.AirTemp, "TrackTempCrew": &r.TrackTemp, "WindVel": &r.WindVel, "WindDir": &r.WindDir,
+		"Precipitation": &r.Precipitation,
+	} {
+		f, _ := values[name].(float32)
+		*v = float64(f)
+	}
+
+	conditions := model.NewConditions(&r)
+
+	return t.weather.Record(st, conditions)
+}
+
+// readCars reads where every driver is once a sample for the detector, race control, track map and relative. It is
+// nil if none of them are enabled or the telemetry does not have the variables.
+func (t *Telemetry) readCars(st *state) map[int]reading {
+	if t.detector == nil && t.raceControl == nil && t.trackMap == nil && t.relative == nil {
+		return nil
+	}
+
+	values, err := t.readCarVars("CarIdxPosition", "CarIdxLapDistPct", "CarIdxOnPitRoad", "CarIdxTrackSurface")
+	if err != nil {
+		logger.Debug("No race events, track map or relative", "err", err)
+		return nil
+	}
+
+	positions, _ := values["CarIdxPosition"].([]int)
+	pcts, _ := values["CarIdxLapDistPct"].([]float32)
+	onPitRoad, _ := values["CarIdxOnPitRoad"].([]bool)
+	surfaces, _ := values["CarIdxTrackSurface"].([]int)
+
+	// Only race control needs the flags, so recordings without them still have race events
+	var flags []int
+
+	v, err := t.sdk.GetVarValues("CarIdxSessionFlags")
+	if err == nil {
+		flags, _ = v.([]int)
+	}
+
+	cars := make(map[int]reading, len(st.drivers))
+
+	for carIdx, driver := range st.drivers {
+		if carIdx >= len(positions) || carIdx >= len(pcts) || carIdx >= len(onPitRoad) || carIdx >= len(surfaces) {
+			continue
+		}
+
+		r := reading{
+			position:      positions[carIdx],
+			classPosition: driver.ClassPosition,
+			lapsCompleted: driver.LapsCompleted,
+			lapDistPct:    math.Round(float64(pcts[carIdx])*lapDistPrecision) / lapDistPrecision,
+			onPitRoad:     onPitRoad[carIdx],
+			surface:       surfaces[carIdx],
+		}
+
+		if carIdx < len(flags) {
+			r.flags = flags[carIdx]
+		}
+
+		cars[carIdx] = r
+	}
+
+	return cars
+}
+
+// readSafety reads the player's incidents, nil for a spectator or telemetry without them
+func (t *Telemetry) readSafety(st *state) *model.Safety {
+	if _, ok := st.drivers[st.irSession.DriverInfo.DriverCarIdx]; !ok {
+		return nil
+	}
+
+	values, err := t.readVars("PlayerCarMyIncidentCount", "LapCompleted")
+	if err != nil {
+		logger.Debug("No safety", "err", err)
+		return nil
+	}
+
+	incidents, _ := values["PlayerCarMyIncidentCount"].(int)
+	lapsCompleted, _ := values["LapCompleted"].(int)
+
+	return rating.Safety(incidents, lapsCompleted, st.irSession.WeekendInfo.TrackNumTurns)
+}
+
+// projectedTable is the championship as if the race ended now, for races when a championship is configured
+func (t *Telemetry) projectedTable(st *state) *model.Championship {
+	round, ok := t.round(st)
+	if !ok {
+		return nil
+	}
+
+	return t.championship.Table(round)
+}
+
+// finalTable records the race that has just ended in the championship and returns the final table
+func (t *Telemetry) finalTable(st *state) *model.Championship {
+	round, ok := t.round(st)
+	if !ok {
+		return nil
+	}
+
+	round.Final = true
+
+	err := t.championship.Record(round)
+	if err != nil {
+		logger.Error("Can not record the race in the championship", "err", err)
+	}
+
+	return t.championship.Table(round)
+}
+
+// round returns the results of the race so far, if it is a race and a championship is configured
+func (t *Telemetry) round(st *state) (model.Round, bool) {
+	if t.championship == nil || st.session.SessionType != "Race" {
+		return model.Round{}, false
+	}
+
+	return model.NewRound(st.weekend, &st.irSession, st.session.SessionNum, st.drivers, t.optionalTimes("CarIdxBestLapTime")), true
+}
+
+// optionalTimes reads a per car time that older recordings may not have, nil without it
+func (t *Telemetry) optionalTimes(name string) []float32 {
+	v, err := t.sdk.GetVarValues(name)
+	if err != nil {
+		logger.Debug("No times", "name", name, "err", err)
+
+		return nil
+	}
+
+	times, _ := v.([]float32)
+
+	return times
+}
+
+// optionalPositions reads the overall position of each car, nil for telemetry without them such as an old recording
+func (t *Telemetry) optionalPositions() []int {
+	v, err := t.sdk.GetVarValues("CarIdxPosition")
+	if err != nil {
+		logger.Debug("No overall positions", "err", &varError{"CarIdxPosition", err})
+
+		return nil
+	}
+
+	positions, _ := v.([]int)
+
+	return positions
+}
+
+// readVars reads every one of the named variables, or none if any is missing such as from an old recording
+func (t *Telemetry) readVars(names ...string) (map[string]any, error) {
+	return readAll(t.sdk.GetVarValue, names)
+}
+
+// readCarVars is readVars for variables with a value for each car, indexed by car_idx
+func (t *Telemetry) readCarVars(names ...string) (map[string]any, error) {
+	return readAll(t.sdk.GetVarValues, names)
+}
+
+func readAll(get func(name string) (any, error), names []string) (map[string]any, error) {
+	values := make(map[string]any, len(names))
+
+	for _, name := range names {
+		v, err := get(name)
+		if err != nil {
+			return nil, &varError{name, err}
+		}
+
+		values[name] = v
+	}
+
+	return values, nil
+}
+
+// float32s converts three float32 telemetry values, 0 for any that are not
+func float32s(a, b, c any) [3]float64 {
+	var values [3]float64
+
+	for i, v := range []any{a, b, c} {
+		f, _ := v.(float32)
+		values[i] = float64(f)
+	}
+
+	return values
+}
+
+// sameSession compares the subsession and session number, i.e. practice, qualifying or race
+func sameSession(a, b *state) bool {
+	return a.weekend.SubSessionID == b.weekend.SubSessionID && a.session.SessionNum == b.session.SessionNum
+}
+
+func bailing(err error) string {
+	var ve *varError
+	if errors.As(err, &ve) {
+		return fmt.Sprintf("Can not determine %s, err:%v, bailing...", ve.name, ve.err)
+	}
+
+	return fmt.Sprintf("%v, bailing...", err)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -2,14 +2,21 @@ package telemetry
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/ianhaycox/vcrlive/connectors/vcrstandings"
+	"github.com/ianhaycox/vcrlive/fuel"
 	"github.com/ianhaycox/vcrlive/irsdk"
 	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/ianhaycox/vcrlive/irsdk/scenario"
 	"github.com/ianhaycox/vcrlive/model"
+	"github.com/ianhaycox/vcrlive/relative"
+	"github.com/ianhaycox/vcrlive/trackmap"
+	"github.com/ianhaycox/vcrlive/tyres"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -36,6 +43,9 @@ func TestTelemetry(t *testing.T) {
 			sdk.EXPECT().GetVarValue("SessionState").Return(4, nil),
 			sdk.EXPECT().GetVarValues("CarIdxClassPosition").Return([]int{0, 12, 13}, nil),
 			sdk.EXPECT().GetVarValues("CarIdxLapCompleted").Return([]int{0, 67, 68}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxPosition").Return([]int{0, 12, 13}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxF2Time").Return([]float32{0, 10.5, 12.25}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxBestLapTime").Return([]float32{-1, 80.5, 81.305}, nil),
 
 			// Second loop
 			sdk.EXPECT().WaitForData(time.Duration(10000000)),
@@ -45,21 +55,524 @@ func TestTelemetry(t *testing.T) {
 
 		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
 		vcr.EXPECT().Post(ctx, &model.LivePositions{
-			Weekend: model.Weekend{TrackID: 1},
-			Session: model.Session{SessionNum: 1, SessionState: "Racing"},
+			SchemaVersion: model.SchemaVersion,
+			Weekend:       model.Weekend{TrackID: 1},
+			Session:       model.Session{SessionNum: 1, SessionState: "Racing"},
+			CarClasses:    []model.CarClass{{Cars: 2, LeaderCarIdx: -1, BestLapTime: 80.5}},
 			Drivers: []model.Driver{
-				{CarIdx: 1, UserName: "1", UserID: 1, ClassPosition: 12, LapsCompleted: 67},
-				{CarIdx: 2, UserName: "2", UserID: 2, ClassPosition: 13, LapsCompleted: 68},
+				{CarIdx: 1, UserName: "1", UserID: 1, ClassPosition: 12, LapsCompleted: 67, Position: 12, BestLapTime: 80.5, ClassPace: 100},
+				{CarIdx: 2, UserName: "2", UserID: 2, ClassPosition: 13, LapsCompleted: 68, Position: 13, ClassInterval: 1.75, BestLapTime: 81.305,
+					ClassPace: 101},
 			},
 		})
 
-		vcr.EXPECT().Post(ctx, &model.LivePositions{
-			Session: model.Session{SessionNum: 1, SessionState: "Cool Down"},
+		vcr.EXPECT().Post(gomock.Any(), &model.LivePositions{
+			SchemaVersion: model.SchemaVersion,
+			Session:       model.Session{SessionNum: 1, SessionState: "Cool Down"},
 		})
 
 		tm := NewTelemetry(sdk, vcr, false)
 
-		err := tm.Run(ctx, 10, 1)
+		err := tm.Run(ctx, 10, 1000, 1)
+		assert.NoError(t, err)
+	})
+	t.Run("Telemetry without overall positions should still be posted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.TODO()
+
+		sdk := irsdk.NewMockSDK(ctrl)
+		gomock.InOrder(
+			sdk.EXPECT().WaitForData(time.Duration(10000000)),
+			sdk.EXPECT().GetLastVersion().Return(1),
+			sdk.EXPECT().GetSession().Return(iryaml.IRSession{
+				SessionInfo: iryaml.SessionInfo{Sessions: []iryaml.Session{{SessionNum: 1}}},
+				DriverInfo:  iryaml.DriverInfo{Drivers: []iryaml.Driver{{CarIdx: 1, UserName: "1", UserID: 1}}},
+			}),
+			sdk.EXPECT().GetVarValue("SessionNum").Return(1, nil),
+			sdk.EXPECT().GetVarValue("SessionState").Return(4, nil),
+			sdk.EXPECT().GetVarValues("CarIdxClassPosition").Return([]int{0, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxLapCompleted").Return([]int{0, 3}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxPosition").Return(nil, fmt.Errorf("not recorded")),
+			sdk.EXPECT().GetVarValues("CarIdxF2Time").Return(nil, fmt.Errorf("not recorded")),
+			sdk.EXPECT().GetVarValues("CarIdxBestLapTime").Return(nil, fmt.Errorf("not recorded")),
+
+			sdk.EXPECT().WaitForData(time.Duration(10000000)),
+			sdk.EXPECT().GetLastVersion().Return(1),
+			sdk.EXPECT().GetVarValue("SessionState").Return(6, nil),
+		)
+
+		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
+		gomock.InOrder(
+			vcr.EXPECT().Post(ctx, &model.LivePositions{
+				SchemaVersion: model.SchemaVersion,
+				Session:       model.Session{SessionNum: 1, SessionState: "Racing"},
+				CarClasses:    []model.CarClass{{Cars: 1, LeaderCarIdx: 1}},
+				Drivers:       []model.Driver{{CarIdx: 1, UserName: "1", UserID: 1, ClassPosition: 1, LapsCompleted: 3}},
+			}),
+			vcr.EXPECT().Post(gomock.Any(), gomock.Any()),
+		)
+
+		err := NewTelemetry(sdk, vcr, false).Run(ctx, 10, 1000, 1)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Cancelling the context should flush pending positions then post Stopped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		sdk := irsdk.NewMockSDK(ctrl)
+		gomock.InOrder(
+			sdk.EXPECT().WaitForData(time.Duration(10000000)),
+			sdk.EXPECT().GetLastVersion().Return(1),
+			sdk.EXPECT().GetSession().Return(iryaml.IRSession{
+				SessionInfo: iryaml.SessionInfo{Sessions: []iryaml.Session{{SessionNum: 1}}},
+				DriverInfo: iryaml.DriverInfo{Drivers: []iryaml.Driver{
+					{CarIdx: 1, UserName: "1", UserID: 1},
+					{CarIdx: 2, UserName: "2", UserID: 2},
+				}},
+			}),
+			sdk.EXPECT().GetVarValue("SessionNum").Return(1, nil),
+			sdk.EXPECT().GetVarValue("SessionState").Return(4, nil),
+			sdk.EXPECT().GetVarValues("CarIdxClassPosition").Return([]int{0, 1, 2}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxLapCompleted").Return([]int{0, 3, 3}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxPosition").Return([]int{0, 1, 2}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxF2Time").Return(nil, fmt.Errorf("not recorded")),
+			sdk.EXPECT().GetVarValues("CarIdxBestLapTime").Return(nil, fmt.Errorf("not recorded")),
+
+			// Second loop, lead change is held back by the minimum interval then Ctrl-C
+			sdk.EXPECT().WaitForData(time.Duration(10000000)).Do(func(time.Duration) { cancel() }),
+			sdk.EXPECT().GetLastVersion().Return(1),
+			sdk.EXPECT().GetVarValue("SessionState").Return(4, nil),
+			sdk.EXPECT().GetVarValues("CarIdxClassPosition").Return([]int{0, 2, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxLapCompleted").Return([]int{0, 3, 4}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxPosition").Return([]int{0, 2, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxF2Time").Return(nil, fmt.Errorf("not recorded")),
+			sdk.EXPECT().GetVarValues("CarIdxBestLapTime").Return(nil, fmt.Errorf("not recorded")),
+		)
+
+		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
+		gomock.InOrder(
+			vcr.EXPECT().Post(ctx, gomock.Any()),
+			vcr.EXPECT().Post(gomock.Any(), &model.LivePositions{
+				SchemaVersion: model.SchemaVersion,
+				Session:       model.Session{SessionNum: 1, SessionState: "Racing"},
+				CarClasses:    []model.CarClass{{Cars: 2, LeaderCarIdx: 2}},
+				Drivers: []model.Driver{
+					{CarIdx: 1, UserName: "1", UserID: 1, ClassPosition: 2, LapsCompleted: 3, Position: 2},
+					{CarIdx: 2, UserName: "2", UserID: 2, ClassPosition: 1, LapsCompleted: 4, Position: 1},
+				},
+			}),
+			vcr.EXPECT().Post(gomock.Any(), &model.LivePositions{
+				SchemaVersion: model.SchemaVersion,
+				Session:       model.Session{SessionNum: 1, SessionState: "Stopped"},
+			}),
+		)
+
+		now := time.Now()
+		tm := NewTelemetry(sdk, vcr, false)
+		tm.now = func() time.Time { return now }
+
+		err := tm.Run(ctx, 10, 1000, 10)
 		assert.NoError(t, err)
 	})
+
+	t.Run("An SDK that returns at once should be sampled once a wait, not in a busy loop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		waits := 0
+
+		sdk := irsdk.NewMockSDK(ctrl)
+		sdk.EXPECT().WaitForData(gomock.Any()).DoAndReturn(func(time.Duration) bool { waits++; return true }).AnyTimes()
+		sdk.EXPECT().GetLastVersion().Return(1).AnyTimes() // a snapshot never ticks over
+		sdk.EXPECT().GetSession().Return(iryaml.IRSession{}).AnyTimes()
+		sdk.EXPECT().GetVarValue("SessionNum").Return(1, nil).AnyTimes()
+		sdk.EXPECT().GetVarValue("SessionState").Return(model.Invalid, nil).AnyTimes()
+
+		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
+		vcr.EXPECT().Post(gomock.Any(), gomock.Any()).Return(nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		assert.NoError(t, NewTelemetry(sdk, vcr, false).Run(ctx, 20, 1000, 1))
+		assert.LessOrEqual(t, waits, 11, "one sample a 20ms wait over 200ms")
+		assert.GreaterOrEqual(t, waits, 5)
+	})
+
+	t.Run("A failed post should stay pending", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
+		gomock.InOrder(
+			vcr.EXPECT().Post(gomock.Any(), gomock.Any()).Return(fmt.Errorf("unavailable")),
+			vcr.EXPECT().Post(gomock.Any(), gomock.Any()).Return(nil),
+		)
+
+		tm := NewTelemetry(nil, vcr, false)
+		scheduler := NewScheduler(time.Minute, time.Hour)
+		st := state{session: model.Session{SessionNum: 1}}
+		livePositions := &model.LivePositions{Session: st.session}
+
+		assert.Equal(t, livePositions, tm.schedule(context.TODO(), scheduler, &st, livePositions))
+		assert.True(t, scheduler.Pending())
+
+		assert.Nil(t, tm.schedule(context.TODO(), scheduler, &st, livePositions), "not held back by the minimum interval")
+		assert.False(t, scheduler.Pending())
+	})
+}
+
+func TestSupervise(t *testing.T) {
+	t.Run("Should post session start and end events across sessions until cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		irSession := iryaml.IRSession{
+			WeekendInfo: iryaml.WeekendInfo{SubSessionID: 100},
+			SessionInfo: iryaml.SessionInfo{Sessions: []iryaml.Session{
+				{SessionNum: 1, SessionType: "Qualify"},
+				{SessionNum: 2, SessionType: "Race"},
+			}},
+			DriverInfo: iryaml.DriverInfo{Drivers: []iryaml.Driver{{CarIdx: 1, UserName: "1", UserID: 1}}},
+		}
+
+		sdk := irsdk.NewMockSDK(ctrl)
+		gomock.InOrder(
+			// Qualifying
+			sdk.EXPECT().WaitForData(time.Duration(10000000)),
+			sdk.EXPECT().GetLastVersion().Return(1),
+			sdk.EXPECT().GetSession().Return(irSession),
+			sdk.EXPECT().GetVarValue("SessionNum").Return(1, nil),
+			sdk.EXPECT().GetVarValue("SessionState").Return(4, nil),
+			sdk.EXPECT().GetVarValues("CarIdxClassPosition").Return([]int{0, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxLapCompleted").Return([]int{0, 3}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxPosition").Return([]int{0, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxF2Time").Return(nil, fmt.Errorf("not recorded")),
+			sdk.EXPECT().GetVarValues("CarIdxBestLapTime").Return(nil, fmt.Errorf("not recorded")),
+
+			// Race
+			sdk.EXPECT().WaitForData(time.Duration(10000000)),
+			sdk.EXPECT().GetLastVersion().Return(2),
+			sdk.EXPECT().GetSession().Return(irSession),
+			sdk.EXPECT().GetVarValue("SessionNum").Return(2, nil),
+			sdk.EXPECT().GetVarValue("SessionState").Return(4, nil),
+			sdk.EXPECT().GetVarValues("CarIdxClassPosition").Return([]int{0, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxLapCompleted").Return([]int{0, 0}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxPosition").Return([]int{0, 1}, nil),
+			sdk.EXPECT().GetVarValues("CarIdxF2Time").Return(nil, fmt.Errorf("not recorded")),
+			sdk.EXPECT().GetVarValues("CarIdxBestLapTime").Return(nil, fmt.Errorf("not recorded")),
+
+			// Race over
+			sdk.EXPECT().WaitForData(time.Duration(10000000)),
+			sdk.EXPECT().GetLastVersion().Return(2),
+			sdk.EXPECT().GetVarValue("SessionState").Return(6, nil),
+
+			// iRacing closed, then Ctrl-C
+			sdk.EXPECT().WaitForData(time.Duration(10000000)).Do(func(time.Duration) { cancel() }),
+			sdk.EXPECT().GetLastVersion().Return(-1),
+			sdk.EXPECT().GetSession().Return(iryaml.IRSession{}),
+			sdk.EXPECT().GetVarValue("SessionNum").Return(nil, fmt.Errorf("session is not active")),
+		)
+
+		var (
+			events []string
+			posts  []string
+		)
+
+		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
+		vcr.EXPECT().PostEvent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event *model.Event) error {
+			events = append(events, fmt.Sprintf("%s %d %s %s", event.Type, event.Session.SessionNum, event.Session.SessionType, event.Reason))
+			return nil
+		}).Times(4)
+		vcr.EXPECT().Post(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, livePositions *model.LivePositions) error {
+			posts = append(posts, fmt.Sprintf("%d %s %d", livePositions.Session.SessionNum, livePositions.Session.SessionState, len(livePositions.Drivers)))
+			return nil
+		}).Times(3)
+
+		tm := NewTelemetry(sdk, vcr, false)
+
+		err := tm.Supervise(ctx, 10, 1000, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"session_start 1 Qualify ",
+			"session_end 1 Qualify next_session",
+			"session_start 2 Race ",
+			"session_end 2 Race cool_down",
+		}, events)
+		assert.Equal(t, []string{"1 Racing 1", "2 Racing 1", "2 Cool Down 0"}, posts)
+	})
+
+	t.Run("Should follow a generated weekend through the real SDK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		gen, err := scenario.New(1).
+			Class(scenario.Class{ID: 1, Name: "GT3", LapTime: 10 * time.Second, Variance: 100 * time.Millisecond}).
+			Field(1, 5).
+			Qualify(20 * time.Second).
+			Race(3).
+			Start()
+		require.NoError(t, err)
+
+		go func() {
+			<-gen.Done()
+			cancel()
+		}()
+
+		var (
+			events  []string
+			drivers int
+		)
+
+		vcr := vcrstandings.NewMockVcrStandingsAPI(ctrl)
+		vcr.EXPECT().PostEvent(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event *model.Event) error {
+			events = append(events, fmt.Sprintf("%s %d %s %s", event.Type, event.Session.SessionNum, event.Session.SessionType, event.Reason))
+			return nil
+		}).AnyTimes()
+		vcr.EXPECT().Post(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, livePositions *model.LivePositions) error {
+			drivers = max(drivers, len(livePositions.Drivers))
+			return nil
+		}).AnyTimes()
+
+		tm := NewTelemetry(irsdk.NewIrSDK(gen), vcr, false)
+
+		err = tm.Supervise(ctx, 1, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"session_start 0 Lone Qualify ",
+			"session_end 0 Lone Qualify cool_down",
+			"session_start 1 Race ",
+			"session_end 1 Race cool_down",
+		}, events)
+		assert.Equal(t, 5, drivers, "the field without the pace car")
+	})
+}
+
+func TestMeasureFuel(t *testing.T) {
+	sdk := recorded(t, map[string]any{
+		"FuelLevel": float32(40), "FuelLevelPct": float32(0.5), "FuelUsePerHour": float32(45), "LapCompleted": 3,
+		"LapDistPct": float32(0.25), "IsOnTrack": true, "OnPitRoad": false, "SessionFlags": irsdk.FlagCaution,
+		"SessionTime": 600.0, "SessionLapsRemainEx": 20, "SessionTimeRemain": 1800.0,
+	})
+
+	st := state{
+		irSession: iryaml.IRSession{DriverInfo: iryaml.DriverInfo{
+			DriverCarFuelKgPerLtr: 0.75, DriverCarFuelMaxLtr: 100, DriverCarMaxFuelPct: 0.8,
+		}},
+		weekend: model.Weekend{SubSessionID: 7},
+		session: model.Session{SessionNum: 2},
+	}
+
+	calculator := fuel.NewCalculator()
+	tm := NewTelemetry(sdk, nil, false).WithFuel(calculator)
+
+	t.Run("Readings should be converted to litres and the capacity allowed", func(t *testing.T) {
+		tm.measureFuel(&st)
+
+		assert.Equal(t, fuel.Estimate{Level: 40, LevelPct: 0.5, Capacity: 80, UsePerHour: 60}, calculator.Estimate())
+	})
+}
+
+func TestMeasureTyres(t *testing.T) {
+	values := map[string]any{"LapCompleted": 12}
+
+	for _, corner := range []string{"LF", "RF", "LR", "RR"} {
+		values[corner+"TiresUsed"] = 1
+		values[corner+"tempCL"], values[corner+"tempCM"], values[corner+"tempCR"] = float32(70), float32(75), float32(80)
+		values[corner+"wearL"], values[corner+"wearM"], values[corner+"wearR"] = float32(0.9), float32(0.95), float32(1)
+		values[corner+"coldPressure"] = float32(150)
+	}
+
+	sdk := recorded(t, values)
+	st := state{weekend: model.Weekend{SubSessionID: 7}, session: model.Session{SessionNum: 2}}
+
+	tracker := tyres.NewTracker()
+	tm := NewTelemetry(sdk, nil, false).WithTyres(tracker)
+
+	t.Run("Each corner should be read", func(t *testing.T) {
+		tm.measureTyres(&st)
+
+		report := tracker.Report()
+		assert.Equal(t, model.TyreCorner{Corner: "lf", Used: 1, Temps: [3]float64{80, 75, 70}, Wear: [3]float64{100, 95, 90}, ColdPressure: 150},
+			report.Live[0])
+		assert.Equal(t, model.TyreCorner{Corner: "rr", Used: 1, Temps: [3]float64{70, 75, 80}, Wear: [3]float64{90, 95, 100}, ColdPressure: 150},
+			report.Live[3])
+		assert.Equal(t, 12, report.Stints[0].FromLap)
+	})
+
+	t.Run("The positions should carry the tyres", func(t *testing.T) {
+		assert.Nil(t, NewTelemetry(sdk, nil, false).tyreReport())
+		assert.Equal(t, tracker.Report(), tm.tyreReport())
+	})
+}
+
+func TestMapTrack(t *testing.T) {
+	sdk := recorded(t, map[string]any{
+		"SessionTime":        600.0,
+		"CarIdxPosition":     []int{0, 1, 2, 0},
+		"CarIdxLapDistPct":   []float32{-1, 0.25, 0.5, -1},
+		"CarIdxOnPitRoad":    []bool{false, false, true, false},
+		"CarIdxTrackSurface": []int{-1, irsdk.TrackOnTrack, irsdk.TrackInPitStall, -1},
+		"CarIdxSessionFlags": []int{0, 0, 0, 0},
+		"LapDistPct":         float32(0.25),
+		"IsOnTrack":          true,
+		"OnPitRoad":          false,
+		"VelocityX":          float32(50),
+		"VelocityY":          float32(0),
+		"Yaw":                float32(0),
+	})
+
+	st := state{
+		weekend: model.Weekend{TrackID: 168, TrackConfigName: "Grand Prix"},
+		drivers: model.Drivers{1: {CarIdx: 1}, 2: {CarIdx: 2}, 3: {CarIdx: 3}},
+	}
+
+	mapper := trackmap.NewMapper("")
+	tm := NewTelemetry(sdk, nil, false).WithTrackMap(mapper)
+
+	t.Run("Cars in the world should be placed on the map", func(t *testing.T) {
+		tm.mapTrack(&st, tm.readCars(&st))
+
+		expected := trackmap.Positions{TrackID: 168, TrackConfig: "Grand Prix", SessionTime: 600, Cars: []trackmap.Position{
+			{CarIdx: 1, LapDistPct: 0.25, X: 1, Y: 0.5},
+			{CarIdx: 2, LapDistPct: 0.5, X: 0.5, Y: 1, OnPitRoad: true},
+		}}
+
+		assert.Equal(t, expected, mapper.Positions())
+	})
+}
+
+func TestMeasureRelative(t *testing.T) {
+	sdk := recorded(t, map[string]any{
+		"SessionTime":        600.0,
+		"CamCarIdx":          2,
+		"CarIdxPosition":     []int{0, 1, 2},
+		"CarIdxLapDistPct":   []float32{-1, 0.5, 0.45},
+		"CarIdxOnPitRoad":    []bool{false, false, false},
+		"CarIdxTrackSurface": []int{-1, irsdk.TrackOnTrack, irsdk.TrackOnTrack},
+		"CarIdxLap":          []int{0, 6, 5},
+		"CarIdxEstTime":      []float32{0, 50, 45},
+	})
+
+	st := state{
+		irSession: iryaml.IRSession{DriverInfo: iryaml.DriverInfo{DriverCarIdx: 1, Drivers: []iryaml.Driver{
+			{CarIdx: 1, UserName: "A", CarNumber: "7", CarClassID: 84, CarClassColor: "0xffda59", CarClassEstLapTime: 100},
+			{CarIdx: 2, UserName: "B", CarNumber: "12", CarClassID: 84, CarClassColor: "0xffda59", CarClassEstLapTime: 100},
+		}}},
+		session: model.Session{SessionType: "Race"},
+		drivers: model.Drivers{1: {CarIdx: 1, UserName: "A", CarClassID: 84}, 2: {CarIdx: 2, UserName: "B", CarClassID: 84}},
+	}
+
+	board := relative.NewBoard()
+	tm := NewTelemetry(sdk, nil, false).WithRelative(board)
+
+	t.Run("Cars should be timed from the player", func(t *testing.T) {
+		tm.measureRelative(&st, tm.readCars(&st))
+
+		r, err := board.Around(relative.FocusPlayer, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, relative.Relative{FocusCarIdx: 1, SessionTime: 600, Cars: []relative.Entry{
+			{CarIdx: 1, UserName: "A", CarNumber: "7", CarClassID: 84, ClassColor: "#ffda59", Position: 1, Status: relative.StatusFocus},
+			{CarIdx: 2, UserName: "B", CarNumber: "12", CarClassID: 84, ClassColor: "#ffda59", Position: 2, Delta: -5, Laps: -1,
+				Status: relative.StatusLapBehind},
+		}}, r)
+
+		r, err = board.Around(relative.FocusCamera, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, r.FocusCarIdx)
+	})
+}
+
+func TestReadSafety(t *testing.T) {
+	sdk := recorded(t, map[string]any{"PlayerCarMyIncidentCount": 4, "LapCompleted": 10})
+	st := state{
+		irSession: iryaml.IRSession{WeekendInfo: iryaml.WeekendInfo{TrackNumTurns: 18}, DriverInfo: iryaml.DriverInfo{DriverCarIdx: 1}},
+		drivers:   model.Drivers{1: {CarIdx: 1}},
+	}
+
+	tm := NewTelemetry(sdk, nil, false).WithRatingEstimates()
+
+	t.Run("The player's incidents should be counted against the corners driven", func(t *testing.T) {
+		assert.Equal(t, &model.Safety{Incidents: 4, Corners: 180, CornersPerIncident: 45}, tm.readSafety(&st))
+	})
+
+	t.Run("A spectator should have no safety", func(t *testing.T) {
+		spectator := st
+		spectator.drivers = model.Drivers{2: {CarIdx: 2}}
+
+		assert.Nil(t, tm.readSafety(&spectator))
+	})
+}
+
+func TestMissingVariables(t *testing.T) {
+	t.Run("A variable that is not recorded should read none, naming it", func(t *testing.T) {
+		tm := NewTelemetry(recorded(t, map[string]any{"SessionTime": 600.0}), nil, false)
+
+		values, err := tm.readVars("SessionTime", "AirTemp")
+		assert.Nil(t, values)
+		assert.ErrorContains(t, err, "can not determine AirTemp")
+	})
+
+	t.Run("A recording without the variables should leave every feature as it was", func(t *testing.T) {
+		calculator, tracker, mapper, board := fuel.NewCalculator(), tyres.NewTracker(), trackmap.NewMapper(""), relative.NewBoard()
+		tm := NewTelemetry(recorded(t, nil), nil, false).WithFuel(calculator).WithTyres(tracker).WithTrackMap(mapper).
+			WithRelative(board).WithWeather().WithRatingEstimates()
+
+		st := state{
+			irSession: iryaml.IRSession{DriverInfo: iryaml.DriverInfo{DriverCarIdx: 1}},
+			drivers:   model.Drivers{1: {CarIdx: 1}},
+		}
+
+		cars := tm.readCars(&st)
+		tm.measureFuel(&st)
+		tm.measureTyres(&st)
+		tm.mapTrack(&st, cars)
+		tm.measureRelative(&st, cars)
+
+		assert.Nil(t, cars)
+		assert.Equal(t, fuel.NewCalculator().Estimate(), calculator.Estimate())
+		assert.Nil(t, tracker.Report())
+		assert.Equal(t, trackmap.NewMapper("").Positions(), mapper.Positions())
+
+		r, err := board.Around(relative.FocusPlayer, 1)
+		want, wantErr := relative.NewBoard().Around(relative.FocusPlayer, 1)
+		assert.Equal(t, want, r)
+		assert.Equal(t, wantErr, err)
+
+		assert.Nil(t, tm.readWeather(&st))
+		assert.Nil(t, tm.readSafety(&st))
+	})
+}
+
+// recorded is a fake SDK that reads values as if from a recording, any variable not in values was not recorded
+func recorded(t *testing.T, values map[string]any) irsdk.SDK {
+	t.Helper()
+
+	lookup := func(name string) (any, error) {
+		v, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("not recorded")
+		}
+
+		return v, nil
+	}
+
+	sdk := irsdk.NewMockSDK(gomock.NewController(t))
+	sdk.EXPECT().GetVarValue(gomock.Any()).DoAndReturn(lookup).AnyTimes()
+	sdk.EXPECT().GetVarValues(gomock.Any()).DoAndReturn(lookup).AnyTimes()
+
+	return sdk
 }
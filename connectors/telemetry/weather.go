@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"math"
+	"slices"
+
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+const (
+	// tempStep is how far a temperature moves, °C, to be worth a place in the timeline
+	tempStep = 1
+
+	// windStep is how far the wind speed moves, m/s, to be worth a place in the timeline
+	windStep = 2
+
+	// timelineLength keeps payloads small in long races, the oldest changes after the start of the session are dropped
+	timelineLength = 50
+)
+
+// WeatherTimeline follows the live weather, keeping the conditions each time something changes
+type WeatherTimeline struct {
+	subSessionID int
+	sessionNum   int
+	started      bool
+	timeline     []model.WeatherChange
+}
+
+func NewWeatherTimeline() *WeatherTimeline {
+	return &WeatherTimeline{}
+}
+
+// Record adds the conditions to the timeline when they have changed since the last entry and returns the weather for
+// the payload. The first sample of a session starts the timeline.
+func (w *WeatherTimeline) Record(st *state, c model.Conditions) *model.Weather {
+	if !w.started || w.subSessionID != st.weekend.SubSessionID || w.sessionNum != st.session.SessionNum {
+		w.subSessionID, w.sessionNum, w.started = st.weekend.SubSessionID, st.session.SessionNum, true
+		w.timeline = []model.WeatherChange{{Conditions: c}}
+	} else if changes := weatherChanges(&w.timeline[len(w.timeline)-1].Conditions, &c); len(changes) > 0 {
+		w.timeline = append(w.timeline, model.WeatherChange{Conditions: c, Changes: changes})
+
+		if len(w.timeline) > timelineLength {
+			w.timeline = slices.Delete(w.timeline, 1, len(w.timeline)-timelineLength+1)
+		}
+	}
+
+	return &model.Weather{Current: c, Timeline: slices.Clone(w.timeline)}
+}
+
+// weatherChanges lists what is different now from before
+func weatherChanges(before, now *model.Conditions) []string {
+	var changes []string
+
+	changed := func(ok bool, change string) {
+		if ok {
+			changes = append(changes, change)
+		}
+	}
+
+	changed(before.Precipitation == 0 && now.Precipitation > 0, model.ChangeRainStarted)
+	changed(before.Precipitation > 0 && now.Precipitation == 0, model.ChangeRainStopped)
+	changed(now.Wetter(before), model.ChangeWetter)
+	changed(before.Wetter(now), model.ChangeDrying)
+	changed(before.Skies != now.Skies, model.ChangeSkies)
+	changed(!before.DeclaredWet && now.DeclaredWet, model.ChangeDeclaredWet)
+	changed(before.DeclaredWet && !now.DeclaredWet, model.ChangeDeclaredDry)
+	changed(math.Abs(now.AirTemp-before.AirTemp) >= tempStep, model.ChangeAirTemp)
+	changed(math.Abs(now.TrackTemp-before.TrackTemp) >= tempStep, model.ChangeTrackTemp)
+	changed(math.Abs(now.WindSpeed-before.WindSpeed) >= windStep, model.ChangeWind)
+
+	return changes
+}
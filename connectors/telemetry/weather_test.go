@@ -0,0 +1,117 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeatherTimeline(t *testing.T) {
+	session := func(num int) *state {
+		return &state{weekend: model.Weekend{SubSessionID: 1}, session: model.Session{SessionNum: num}}
+	}
+
+	dry := model.Conditions{AirTemp: 20, TrackTemp: 30, WindSpeed: 2, Wetness: "Dry", Skies: "Partly cloudy"}
+
+	changes := func(w *model.Weather) [][]string {
+		var changes [][]string
+		for _, c := range w.Timeline {
+			changes = append(changes, c.Changes)
+		}
+
+		return changes
+	}
+
+	t.Run("The first conditions should start the timeline", func(t *testing.T) {
+		w := NewWeatherTimeline().Record(session(1), dry)
+
+		assert.Equal(t, &model.Weather{Current: dry, Timeline: []model.WeatherChange{{Conditions: dry}}}, w)
+	})
+
+	t.Run("Small changes should not be in the timeline", func(t *testing.T) {
+		wt := NewWeatherTimeline()
+		wt.Record(session(1), dry)
+
+		now := dry
+		now.SessionTime, now.AirTemp, now.TrackTemp, now.WindSpeed = 10, 20.9, 29.1, 3.9
+
+		w := wt.Record(session(1), now)
+		assert.Equal(t, now, w.Current)
+		assert.Len(t, w.Timeline, 1)
+	})
+
+	t.Run("Rain should be followed by drying", func(t *testing.T) {
+		wt := NewWeatherTimeline()
+		wt.Record(session(1), dry)
+
+		raining := dry
+		raining.SessionTime, raining.Precipitation, raining.Skies = 10, 5, "Overcast"
+		wt.Record(session(1), raining)
+
+		wet := raining
+		wet.SessionTime, wet.Wetness, wet.DeclaredWet, wet.TrackTemp = 20, "Lightly wet", true, 27
+		wt.Record(session(1), wet)
+
+		drying := wet
+		drying.SessionTime, drying.Precipitation, drying.Wetness, drying.WindSpeed = 30, 0, "Mostly dry", 5
+		wt.Record(session(1), drying)
+
+		declaredDry := drying
+		declaredDry.SessionTime, declaredDry.DeclaredWet, declaredDry.AirTemp = 40, false, 22
+		w := wt.Record(session(1), declaredDry)
+
+		expected := [][]string{
+			nil,
+			{model.ChangeRainStarted, model.ChangeSkies},
+			{model.ChangeWetter, model.ChangeDeclaredWet, model.ChangeTrackTemp},
+			{model.ChangeRainStopped, model.ChangeDrying, model.ChangeWind},
+			{model.ChangeDeclaredDry, model.ChangeAirTemp},
+		}
+
+		assert.Equal(t, expected, changes(w))
+	})
+
+	t.Run("A new session should start again", func(t *testing.T) {
+		wt := NewWeatherTimeline()
+		wt.Record(session(1), dry)
+
+		wet := dry
+		wet.Wetness = "Very wet"
+		wt.Record(session(1), wet)
+
+		w := wt.Record(session(2), wet)
+		assert.Equal(t, []model.WeatherChange{{Conditions: wet}}, w.Timeline)
+	})
+
+	t.Run("The oldest changes after the first should be dropped", func(t *testing.T) {
+		wt := NewWeatherTimeline()
+		wt.Record(session(1), dry)
+
+		now := dry
+
+		var w *model.Weather
+
+		for i := range timelineLength + 5 {
+			now.SessionTime = float64(i + 1)
+			now.AirTemp += tempStep
+			w = wt.Record(session(1), now)
+		}
+
+		assert.Len(t, w.Timeline, timelineLength)
+		assert.Equal(t, dry, w.Timeline[0].Conditions)
+		assert.InDelta(t, 7.0, w.Timeline[1].Conditions.SessionTime, 0)
+		assert.Equal(t, now, w.Timeline[timelineLength-1].Conditions)
+	})
+
+	t.Run("The timeline should not change after it is returned", func(t *testing.T) {
+		wt := NewWeatherTimeline()
+		w := wt.Record(session(1), dry)
+
+		wet := dry
+		wet.Wetness = "Very wet"
+		wt.Record(session(1), wet)
+
+		assert.Len(t, w.Timeline, 1)
+	})
+}
@@ -7,16 +7,35 @@ import (
 	"net/http"
 
 	"github.com/ianhaycox/vcrlive/connectors/api"
+	"github.com/ianhaycox/vcrlive/logging"
 	"github.com/ianhaycox/vcrlive/model"
 )
 
+var logger = logging.Component("vcrstandings")
+
+const eventsPath = "/events"
+
 func (v *VcrStandingsService) Post(ctx context.Context, livePositions *model.LivePositions) error {
 	if v.client == nil {
 		fmt.Println(livePositions)
 		return nil
 	}
 
-	r, err := v.client.PrepareRequest(ctx, "", http.MethodPost, nil, livePositions)
+	return v.post(ctx, "", livePositions)
+}
+
+// PostEvent sends a single event to the /events path of the endpoint
+func (v *VcrStandingsService) PostEvent(ctx context.Context, event *model.Event) error {
+	if v.client == nil {
+		fmt.Println(event)
+		return nil
+	}
+
+	return v.post(ctx, eventsPath, event)
+}
+
+func (v *VcrStandingsService) post(ctx context.Context, path string, postBody any) error {
+	r, err := v.client.PrepareRequest(ctx, path, http.MethodPost, nil, postBody)
 	if err != nil {
 		return err
 	}
@@ -34,8 +53,11 @@ func (v *VcrStandingsService) Post(ctx context.Context, livePositions *model.Liv
 	}
 
 	if response.StatusCode != http.StatusOK {
+		logger.Debug("Post rejected", "path", path, "status", response.StatusCode, "body", string(body))
 		return v.client.ReportError(response, body)
 	}
 
+	logger.Debug("Posted", "path", path, "status", response.StatusCode)
+
 	return nil
 }
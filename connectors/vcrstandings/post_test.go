@@ -3,6 +3,7 @@ package vcrstandings
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"testing"
@@ -30,4 +31,40 @@ func TestPost(t *testing.T) {
 		err := v.Post(ctx, &model.LivePositions{})
 		assert.NoError(t, err)
 	})
+	t.Run("Events should be posted to the events path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.TODO()
+		event := &model.Event{Type: model.EventSessionStart}
+		client := api.NewMockAPIClientInterface(ctrl)
+		client.EXPECT().PrepareRequest(ctx, "/events", "POST", nil, event)
+		client.EXPECT().CallAPI(gomock.Any()).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString("OK"))}, nil)
+
+		v := NewVcrStandingsService(client, nil)
+
+		err := v.PostEvent(ctx, event)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Non-200 should return the server error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.TODO()
+		response := &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       io.NopCloser(bytes.NewBufferString("broken"))}
+		client := api.NewMockAPIClientInterface(ctrl)
+		client.EXPECT().PrepareRequest(ctx, "", "POST", nil, &model.LivePositions{})
+		client.EXPECT().CallAPI(gomock.Any()).Return(response, nil)
+		client.EXPECT().ReportError(response, []byte("broken")).Return(fmt.Errorf("server returned non-200"))
+
+		v := NewVcrStandingsService(client, nil)
+
+		err := v.Post(ctx, &model.LivePositions{})
+		assert.ErrorContains(t, err, "non-200")
+	})
 }
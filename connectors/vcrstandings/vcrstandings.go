@@ -23,4 +23,5 @@ func NewVcrStandingsService(client api.APIClientInterface, auth api.Authenticato
 //go:generate mockgen -package vcrstandings -destination vcrstandings_mock.go -source vcrstandings.go
 type VcrStandingsAPI interface {
 	Post(ctx context.Context, livePositions *model.LivePositions) error
+	PostEvent(ctx context.Context, event *model.Event) error
 }
@@ -0,0 +1,243 @@
+// Package fuel measures the fuel the player's car uses each lap and estimates what is needed to finish the session
+package fuel
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// window is how many recent laps the average use is taken over
+	window = 5
+
+	// margin is the laps of fuel to carry beyond the finish
+	margin = 1
+
+	// refuelled is a rise in the fuel level, in litres, that can only be a pit stop
+	refuelled = 0.1
+
+	// unlimitedLaps is SessionLapsRemainEx in a timed session
+	unlimitedLaps = 32767
+
+	// unlimitedTime is SessionTimeRemain in a session without a time limit, a week or more
+	unlimitedTime = 604800
+
+	// precision rounds litres, laps and seconds to hundredths
+	precision = 100
+)
+
+// Reading is what the player's car is doing at a point in time
+type Reading struct {
+	SubSessionID  int
+	SessionNum    int
+	SessionTime   float64 // seconds
+	LapsCompleted int
+	LapDistPct    float64 // 0 to 1 around the current lap
+	OnTrack       bool    // in the car and not in the garage
+	OnPitRoad     bool
+	Caution       bool    // a full course caution is out
+	Level         float64 // litres
+	LevelPct      float64 // 0 to 1 of a full tank
+	UsePerHour    float64 // litres an hour right now
+	Capacity      float64 // litres allowed in the tank
+	LapsRemaining int     // in the session, unlimitedLaps when timed
+	TimeRemaining float64 // seconds in the session
+}
+
+// Estimate is the fuel use so far and what is needed to finish, litres unless stated
+type Estimate struct {
+	Level         float64 `json:"level"`
+	LevelPct      float64 `json:"level_pct"` // 0 to 1 of a full tank
+	Capacity      float64 `json:"capacity"`
+	UsePerHour    float64 `json:"use_per_hour"`
+	GreenLaps     int     `json:"green_laps"`   // laps measured under green
+	CautionLaps   int     `json:"caution_laps"` // laps measured under caution
+	PerLap        float64 `json:"per_lap"`      // average of the recent green laps
+	MinPerLap     float64 `json:"min_per_lap"`
+	MaxPerLap     float64 `json:"max_per_lap"`
+	CautionPerLap float64 `json:"caution_per_lap"` // average of the recent caution laps
+	LapTime       float64 `json:"lap_time"`        // seconds, average of the recent green laps
+	LapsOfFuel    float64 `json:"laps_of_fuel"`    // laps the fuel in the tank lasts at the green average
+	LapsToFinish  float64 `json:"laps_to_finish"`  // 0 if the session has no end
+	ToFinish      float64 `json:"to_finish"`       // needed to finish from here
+	Stops         int     `json:"stops"`           // pit stops needed to finish, allowing a lap spare
+	NextStop      float64 `json:"next_stop"`       // to add at the next stop, a full tank if more than one is needed
+}
+
+// lap is the fuel used and time taken for one lap
+type lap struct {
+	used    float64
+	seconds float64
+}
+
+// Calculator keeps the laps measured in the current session. It is safe to read the estimate while it is updated.
+type Calculator struct {
+	mu sync.RWMutex
+	measurements
+}
+
+// measurements are what is known of the current session
+type measurements struct {
+	subSession int
+	sessionNum int
+	latest     Reading
+	lapsDone   int     // laps completed at the start of the current lap
+	startLevel float64 // litres at the start of the current lap
+	startTime  float64
+	lastLevel  float64
+	measuring  bool // the current lap started on track and has not been refuelled or driven through the pits
+	caution    bool // the current lap was run under caution
+	green      []lap
+	cautions   []lap
+	min, max   float64
+}
+
+func NewCalculator() *Calculator {
+	return &Calculator{measurements: measurements{subSession: -1}}
+}
+
+// Update measures a new reading, starting again when the session changes
+func (c *Calculator) Update(r Reading) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.SubSessionID != c.subSession || r.SessionNum != c.sessionNum {
+		c.measurements = measurements{subSession: r.SubSessionID, sessionNum: r.SessionNum, lapsDone: r.LapsCompleted}
+	}
+
+	switch {
+	case r.LapsCompleted == c.lapsDone+1:
+		if c.measuring && r.OnTrack {
+			c.record(lap{used: c.startLevel - r.Level, seconds: r.SessionTime - c.startTime})
+		}
+
+		c.startLap(r)
+	case r.LapsCompleted != c.lapsDone:
+		// Joined, reset or towed, start again from the next lap
+		c.lapsDone = r.LapsCompleted
+		c.measuring = false
+	case !r.OnTrack || r.OnPitRoad || r.Level > c.lastLevel+refuelled:
+		c.measuring = false
+	}
+
+	c.caution = c.caution || r.Caution
+	c.lastLevel = r.Level
+	c.latest = r
+}
+
+// Estimate returns the fuel use so far and what is needed to finish
+func (c *Calculator) Estimate() Estimate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	r := c.latest
+	e := Estimate{
+		Level:       round(r.Level),
+		LevelPct:    round(r.LevelPct),
+		Capacity:    round(r.Capacity),
+		UsePerHour:  round(r.UsePerHour),
+		GreenLaps:   len(c.green),
+		CautionLaps: len(c.cautions),
+		MinPerLap:   round(c.min),
+		MaxPerLap:   round(c.max),
+	}
+
+	perLap, lapTime := average(c.green)
+	cautionPerLap, _ := average(c.cautions)
+
+	e.PerLap, e.LapTime, e.CautionPerLap = round(perLap), round(lapTime), round(cautionPerLap)
+
+	if perLap <= 0 {
+		return e
+	}
+
+	e.LapsOfFuel = round(r.Level / perLap)
+
+	laps := lapsToFinish(r, lapTime)
+	if laps <= 0 {
+		return e
+	}
+
+	e.LapsToFinish = round(laps)
+	e.ToFinish = round(laps * perLap)
+
+	need := (laps+margin)*perLap - r.Level
+	if need <= 0 || r.Capacity <= 0 {
+		return e
+	}
+
+	e.Stops = int(math.Ceil(need / r.Capacity))
+	e.NextStop = round(min(need, r.Capacity))
+
+	return e
+}
+
+func (c *measurements) startLap(r Reading) {
+	c.lapsDone = r.LapsCompleted
+	c.startLevel = r.Level
+	c.startTime = r.SessionTime
+	c.measuring = r.OnTrack && !r.OnPitRoad
+	c.caution = false
+}
+
+// record keeps a measured lap, ignoring one that used no fuel such as a replay of a stationary car
+func (c *measurements) record(l lap) {
+	if l.used <= 0 || l.seconds <= 0 {
+		return
+	}
+
+	if c.caution {
+		c.cautions = recent(c.cautions, l)
+		return
+	}
+
+	c.green = recent(c.green, l)
+
+	if c.min == 0 || l.used < c.min {
+		c.min = l.used
+	}
+
+	c.max = max(c.max, l.used)
+}
+
+// lapsToFinish is how many laps, including the rest of the current one, the player has left to drive, 0 if the
+// session has no end. When timed the lap being driven as the time runs out is finished.
+func lapsToFinish(r Reading, lapTime float64) float64 {
+	if r.LapsRemaining >= 0 && r.LapsRemaining < unlimitedLaps {
+		return max(float64(r.LapsRemaining)-r.LapDistPct, 0)
+	}
+
+	if r.TimeRemaining <= 0 || r.TimeRemaining >= unlimitedTime || lapTime <= 0 {
+		return 0
+	}
+
+	return math.Ceil(r.TimeRemaining/lapTime+r.LapDistPct) - r.LapDistPct
+}
+
+func recent(laps []lap, l lap) []lap {
+	laps = append(laps, l)
+	if len(laps) > window {
+		laps = laps[len(laps)-window:]
+	}
+
+	return laps
+}
+
+func average(laps []lap) (float64, float64) {
+	if len(laps) == 0 {
+		return 0, 0
+	}
+
+	used, seconds := 0.0, 0.0
+
+	for _, l := range laps {
+		used += l.used
+		seconds += l.seconds
+	}
+
+	return used / float64(len(laps)), seconds / float64(len(laps))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*precision) / precision
+}
@@ -0,0 +1,157 @@
+package fuel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drive runs laps of the given use from the reading r, a few readings a lap, returning the reading at the end
+func drive(c *Calculator, r Reading, uses ...float64) Reading {
+	for _, use := range uses {
+		for _, pct := range []float64{0.25, 0.5, 0.75} {
+			r.LapDistPct = pct
+			r.Level -= use / 4
+			r.SessionTime += 25
+			c.Update(r)
+		}
+
+		r.LapsCompleted++
+		r.LapDistPct = 0
+		r.Level -= use / 4
+		r.SessionTime += 25
+		c.Update(r)
+	}
+
+	return r
+}
+
+func TestCalculator(t *testing.T) {
+	start := Reading{SubSessionID: 1, SessionNum: 2, LapDistPct: 0.9, OnTrack: true, Level: 50, Capacity: 60, LapsRemaining: 20}
+
+	t.Run("The partial first lap should not be measured", func(t *testing.T) {
+		c := NewCalculator()
+		c.Update(start)
+
+		drive(c, start, 2.5)
+
+		assert.Equal(t, Estimate{Level: 47.5, Capacity: 60}, c.Estimate())
+	})
+
+	t.Run("Green laps should give the average, minimum and maximum use", func(t *testing.T) {
+		c := NewCalculator()
+		r := drive(c, start, 2, 2.5, 3, 2.5)
+
+		e := c.Estimate()
+		assert.Equal(t, 3, e.GreenLaps)
+		assert.InDelta(t, 2.67, e.PerLap, 0.01)
+		assert.InDelta(t, 2.5, e.MinPerLap, 0.001)
+		assert.InDelta(t, 3, e.MaxPerLap, 0.001)
+		assert.InDelta(t, 100, e.LapTime, 0.001)
+		assert.InDelta(t, r.Level/(8.0/3), e.LapsOfFuel, 0.01)
+	})
+
+	t.Run("Only the recent laps should count towards the average", func(t *testing.T) {
+		c := NewCalculator()
+		drive(c, start, 1, 5, 5, 2, 2, 2, 2, 2)
+
+		assert.InDelta(t, 2, c.Estimate().PerLap, 0.001)
+	})
+
+	t.Run("Laps under caution should be kept apart", func(t *testing.T) {
+		c := NewCalculator()
+		r := drive(c, start, 2, 2, 2)
+
+		r.Caution = true
+		r = drive(c, r, 1, 1)
+
+		r.Caution = false
+		drive(c, r, 1.2, 2)
+
+		// The lap started under caution is a caution lap
+		e := c.Estimate()
+		assert.Equal(t, 3, e.GreenLaps)
+		assert.Equal(t, 3, e.CautionLaps)
+		assert.InDelta(t, 2, e.PerLap, 0.001)
+		assert.InDelta(t, 1.07, e.CautionPerLap, 0.001)
+	})
+
+	t.Run("A lap with a pit stop should not be measured", func(t *testing.T) {
+		c := NewCalculator()
+		r := drive(c, start, 2, 2)
+
+		r.OnPitRoad = true
+		r.Level += 20
+		c.Update(r)
+
+		r.OnPitRoad = false
+		drive(c, r, 3, 2)
+
+		e := c.Estimate()
+		assert.Equal(t, 2, e.GreenLaps)
+		assert.InDelta(t, 2, e.MaxPerLap, 0.001)
+	})
+
+	t.Run("A new session should start again", func(t *testing.T) {
+		c := NewCalculator()
+		r := drive(c, start, 2, 2, 2)
+
+		r.SessionNum++
+		c.Update(r)
+
+		assert.Equal(t, 0, c.Estimate().GreenLaps)
+	})
+
+	t.Run("Enough fuel should need no stop", func(t *testing.T) {
+		c := NewCalculator()
+		r := start
+		r.LapsRemaining = 10
+		drive(c, r, 2, 2)
+
+		e := c.Estimate()
+		assert.InDelta(t, 10, e.LapsToFinish, 0.001)
+		assert.InDelta(t, 20, e.ToFinish, 0.001)
+		assert.Equal(t, 0, e.Stops)
+		assert.InDelta(t, 0, e.NextStop, 0.001)
+	})
+
+	t.Run("A long race should need a full tank then a top up", func(t *testing.T) {
+		c := NewCalculator()
+		r := start
+		r.LapsRemaining = 60
+		r = drive(c, r, 2, 2)
+		c.Update(r)
+
+		// 60 laps and a spare at 2 litres a lap is 122, less the 46 in the tank
+		e := c.Estimate()
+		assert.InDelta(t, 120, e.ToFinish, 0.001)
+		assert.Equal(t, 2, e.Stops)
+		assert.InDelta(t, 60, e.NextStop, 0.001)
+	})
+
+	t.Run("A timed race should finish the lap being driven when the time runs out", func(t *testing.T) {
+		c := NewCalculator()
+		r := start
+		r.LapsRemaining = unlimitedLaps
+		r = drive(c, r, 2, 2)
+
+		r.LapDistPct = 0.5
+		r.TimeRemaining = 420
+		c.Update(r)
+
+		assert.InDelta(t, 4.5, c.Estimate().LapsToFinish, 0.001)
+	})
+
+	t.Run("Practice without a time limit should not estimate the finish", func(t *testing.T) {
+		c := NewCalculator()
+		r := start
+		r.LapsRemaining = unlimitedLaps
+		r.TimeRemaining = unlimitedTime
+		drive(c, r, 2, 2)
+
+		e := c.Estimate()
+		assert.InDelta(t, 2, e.PerLap, 0.001)
+		assert.InDelta(t, 0, e.LapsToFinish, 0.001)
+		assert.Equal(t, 0, e.Stops)
+	})
+}
@@ -12,12 +12,17 @@ require golang.org/x/sys v0.34.0
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
 
 require (
+	github.com/fxamacker/cbor/v2 v2.9.4
 	github.com/google/go-querystring v1.1.0
 	github.com/hidez8891/shm v0.0.0-20200313135933-0ec4df5f28c7
+	github.com/klauspost/compress v1.18.0
 	github.com/stretchr/testify v1.10.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	go.uber.org/mock v0.4.0
 	golang.org/x/oauth2 v0.30.0
 )
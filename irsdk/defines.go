@@ -0,0 +1,56 @@
+package irsdk
+
+// Enumerations and bit fields from irsdk_defines.h
+
+// Session flags from SessionFlags and CarIdxSessionFlags, irsdk_Flags
+const (
+	FlagCheckered     = 0x00000001
+	FlagWhite         = 0x00000002
+	FlagGreen         = 0x00000004
+	FlagYellow        = 0x00000008
+	FlagRed           = 0x00000010
+	FlagBlue          = 0x00000020
+	FlagDebris        = 0x00000040
+	FlagCrossed       = 0x00000080
+	FlagYellowWaving  = 0x00000100
+	FlagOneLapToGreen = 0x00000200
+	FlagGreenHeld     = 0x00000400
+	FlagTenToGo       = 0x00000800
+	FlagFiveToGo      = 0x00001000
+	FlagRandomWaving  = 0x00002000
+	FlagCaution       = 0x00004000
+	FlagCautionWaving = 0x00008000
+
+	// Driver black flags
+	FlagBlack      = 0x00010000
+	FlagDisqualify = 0x00020000
+	FlagServicible = 0x00040000 // car is allowed service (not a flag)
+	FlagFurled     = 0x00080000
+	FlagRepair     = 0x00100000
+
+	// Start lights
+	FlagStartHidden = 0x10000000
+	FlagStartReady  = 0x20000000
+	FlagStartSet    = 0x40000000
+	FlagStartGo     = 0x80000000
+)
+
+// Track locations from CarIdxTrackSurface, irsdk_TrkLoc
+const (
+	TrackNotInWorld     = -1
+	TrackOffTrack       = 0
+	TrackInPitStall     = 1
+	TrackApproachingPit = 2 // the pit entry and exit roads
+	TrackOnTrack        = 3
+)
+
+// Session states from SessionState, irsdk_SessionState
+const (
+	StateInvalid = iota
+	StateGetInCar
+	StateWarmup
+	StateParadeLaps
+	StateRacing
+	StateCheckered
+	StateCoolDown
+)
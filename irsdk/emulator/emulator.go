@@ -0,0 +1,467 @@
+// Package emulator writes the iRacing shared memory layout so the real irsdk decode path can be driven without the
+// simulator, e.g. for integration tests on Linux.
+//
+//	emu, _ := emulator.New(nil)
+//	sdk := irsdk.NewIrSDK(emu)
+//
+//	emu.SetSession(yaml)
+//	emu.Set("SessionState", 4)
+//	emu.Tick()
+//	sdk.WaitForData(time.Millisecond)
+package emulator
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Memory layout, see irsdk_defines.h
+const (
+	headerLen    = 48
+	maxBufs      = 4 // buffer headers reserved, iRacing uses 3 of them
+	bufHeaderLen = 16
+	varHeaderLen = 144
+	nameLen      = 32
+	descLen      = 64
+	unitLen      = 32
+	version      = 2
+	connected    = 1
+
+	// DefaultTickRate is iRacing's usual telemetry rate
+	DefaultTickRate = 60
+
+	// DefaultBuffers is the number of rotating variable buffers iRacing uses
+	DefaultBuffers = 3
+
+	// DefaultSessionSize is room for a full field of drivers in the session YAML
+	DefaultSessionSize = 512 * 1024
+)
+
+// Var describes a telemetry variable
+type Var struct {
+	Name  string        `json:"name"`
+	Type  irsdk.VarType `json:"type"`
+	Count int           `json:"count"`
+	Unit  string        `json:"unit"`
+	Desc  string        `json:"desc"`
+}
+
+//go:embed vars.json
+var standardVars []byte
+
+// StandardVars returns the variables iRacing provides in a race, as dumped from the simulator
+func StandardVars() []Var {
+	var vars []Var
+
+	err := json.Unmarshal(standardVars, &vars)
+	if err != nil {
+		panic(err)
+	}
+
+	return vars
+}
+
+// Config for an emulator, zero values take the defaults
+type Config struct {
+	Vars        []Var // StandardVars if empty
+	TickRate    int
+	Buffers     int // 1 to 4
+	SessionSize int // bytes reserved for the session YAML
+}
+
+// backing is where the memory image is kept
+type backing interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Emulator maintains an iRacing memory image, readable with irsdk.NewIrSDK
+type Emulator struct {
+	mu            sync.Mutex
+	mem           backing
+	file          *os.File
+	vars          map[string]placed
+	row           []byte // values for the next tick
+	tick          int
+	buffers       int
+	bufLen        int
+	bufOffset     int
+	sessionOffset int
+	sessionSize   int
+	sessionUpdate int
+}
+
+// placed is a variable and its offset in a buffer row
+type placed struct {
+	Var
+	offset int
+}
+
+// New creates an emulator held in memory. The simulator is connected with an empty session and no ticks.
+func New(cfg *Config) (*Emulator, error) {
+	return newEmulator(cfg, &memory{})
+}
+
+// NewFile creates an emulator that writes through to a file so another process can read it
+func NewFile(path string, cfg *Config) (*Emulator, error) {
+	f, err := os.Create(path) //nolint:gosec // test data
+	if err != nil {
+		return nil, err
+	}
+
+	emu, err := newEmulator(cfg, f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	emu.file = f
+
+	return emu, nil
+}
+
+//nolint:mnd // layout
+func newEmulator(cfg *Config, mem backing) (*Emulator, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	vars := cfg.Vars
+	if len(vars) == 0 {
+		vars = StandardVars()
+	}
+
+	emu := &Emulator{
+		mem:         mem,
+		vars:        make(map[string]placed, len(vars)),
+		buffers:     orDefault(cfg.Buffers, DefaultBuffers),
+		sessionSize: orDefault(cfg.SessionSize, DefaultSessionSize),
+	}
+
+	if emu.buffers > maxBufs {
+		return nil, fmt.Errorf("at most %d buffers", maxBufs)
+	}
+
+	// Variables are aligned to their size within a row
+	for _, v := range vars {
+		if _, ok := emu.vars[v.Name]; ok {
+			return nil, fmt.Errorf("duplicate variable %s", v.Name)
+		}
+
+		if v.Count < 1 {
+			v.Count = 1
+		}
+
+		size := typeSize(v.Type)
+		if size == 0 {
+			return nil, fmt.Errorf("variable %s has unknown type %d", v.Name, v.Type)
+		}
+
+		emu.bufLen = align(emu.bufLen, size)
+		emu.vars[v.Name] = placed{Var: v, offset: emu.bufLen}
+		emu.bufLen += size * v.Count
+	}
+
+	emu.bufLen = align(emu.bufLen, 16)
+	emu.row = make([]byte, emu.bufLen)
+
+	varHeaderOffset := headerLen + maxBufs*bufHeaderLen
+	emu.sessionOffset = align(varHeaderOffset+len(vars)*varHeaderLen, 16)
+	emu.bufOffset = align(emu.sessionOffset+emu.sessionSize, 16)
+
+	header := []int{
+		version, connected, orDefault(cfg.TickRate, DefaultTickRate), 0, emu.sessionSize, emu.sessionOffset,
+		len(vars), varHeaderOffset, emu.buffers, emu.bufLen,
+	}
+
+	for i, value := range header {
+		emu.putInt(i*4, value)
+	}
+
+	for i := range emu.buffers {
+		emu.putInt(headerLen+i*bufHeaderLen+4, emu.bufOffset+i*emu.bufLen)
+	}
+
+	for i, v := range vars {
+		p := emu.vars[v.Name]
+		h := make([]byte, varHeaderLen)
+		binary.LittleEndian.PutUint32(h[0:], uint32(v.Type))   //nolint:gosec // small
+		binary.LittleEndian.PutUint32(h[4:], uint32(p.offset)) //nolint:gosec // small
+		binary.LittleEndian.PutUint32(h[8:], uint32(p.Count))  //nolint:gosec // small
+		copy(h[16:16+nameLen-1], v.Name)
+		copy(h[16+nameLen:16+nameLen+descLen-1], v.Desc)
+		copy(h[16+nameLen+descLen:16+nameLen+descLen+unitLen-1], v.Unit)
+		emu.write(h, varHeaderOffset+i*varHeaderLen)
+	}
+
+	// Size the image
+	emu.write(make([]byte, emu.buffers*emu.bufLen), emu.bufOffset)
+
+	return emu, nil
+}
+
+// ReadAt reads the memory image, for irsdk.NewIrSDK
+func (emu *Emulator) ReadAt(b []byte, off int64) (int, error) {
+	emu.mu.Lock()
+	defer emu.mu.Unlock()
+
+	return emu.mem.ReadAt(b, off)
+}
+
+// Close closes the file of a file backed emulator
+func (emu *Emulator) Close() error {
+	if emu.file != nil {
+		return emu.file.Close()
+	}
+
+	return nil
+}
+
+// Connect sets the connected status as when the simulator is running
+func (emu *Emulator) Connect() {
+	emu.mu.Lock()
+	defer emu.mu.Unlock()
+
+	emu.putInt(4, connected) //nolint:mnd // status
+}
+
+// Disconnect clears the connected status as when the simulator exits
+func (emu *Emulator) Disconnect() {
+	emu.mu.Lock()
+	defer emu.mu.Unlock()
+
+	emu.putInt(4, 0) //nolint:mnd // status
+}
+
+// SetSession replaces the session YAML and increments the session info update count
+func (emu *Emulator) SetSession(yaml string) error {
+	encoded, err := charmap.Windows1252.NewEncoder().String(yaml)
+	if err != nil {
+		return err
+	}
+
+	if len(encoded) >= emu.sessionSize {
+		return fmt.Errorf("session of %d bytes does not fit in %d", len(encoded), emu.sessionSize)
+	}
+
+	emu.mu.Lock()
+	defer emu.mu.Unlock()
+
+	area := make([]byte, emu.sessionSize)
+	copy(area, encoded)
+	emu.write(area, emu.sessionOffset)
+
+	emu.sessionUpdate++
+	emu.putInt(12, emu.sessionUpdate) //nolint:mnd // sessionInfoUpdate
+
+	return nil
+}
+
+// Set stages a value for the next Tick. Values carry over to later ticks until set again.
+//
+// Scalars are int, float32, float64, bool or a string for char variables. Arrays take a slice of the same or one
+// scalar for every element.
+//
+//nolint:gocyclo,cyclop // one case per type
+func (emu *Emulator) Set(name string, value any) error {
+	emu.mu.Lock()
+	defer emu.mu.Unlock()
+
+	v, ok := emu.vars[name]
+	if !ok {
+		return fmt.Errorf("unknown variable %s", name)
+	}
+
+	var values []any
+
+	switch value := value.(type) {
+	case []int:
+		values = each(value)
+	case []float32:
+		values = each(value)
+	case []float64:
+		values = each(value)
+	case []bool:
+		values = each(value)
+	default:
+		values = make([]any, v.Count)
+		for i := range values {
+			values[i] = value
+		}
+	}
+
+	if len(values) != v.Count {
+		return fmt.Errorf("%s has %d values, not %d", name, v.Count, len(values))
+	}
+
+	size := typeSize(v.Type)
+
+	for i, value := range values {
+		err := encode(emu.row[v.offset+i*size:v.offset+(i+1)*size], v.Type, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Tick publishes the staged values in the next buffer with an incremented tick count and returns the count
+func (emu *Emulator) Tick() int {
+	emu.mu.Lock()
+	defer emu.mu.Unlock()
+
+	emu.tick++
+	buffer := emu.tick % emu.buffers
+
+	emu.write(emu.row, emu.bufOffset+buffer*emu.bufLen)
+	emu.putInt(headerLen+buffer*bufHeaderLen, emu.tick)
+
+	return emu.tick
+}
+
+func (emu *Emulator) putInt(off int, value int) {
+	b := make([]byte, 4)                            //nolint:mnd // int32
+	binary.LittleEndian.PutUint32(b, uint32(value)) //nolint:gosec // layout values are small
+	emu.write(b, off)
+}
+
+func (emu *Emulator) write(b []byte, off int) {
+	_, err := emu.mem.WriteAt(b, int64(off))
+	if err != nil {
+		// Only a failing file, nothing useful can be emulated after that
+		panic(err)
+	}
+}
+
+//nolint:mnd // little endian encodings
+func encode(b []byte, varType irsdk.VarType, value any) error {
+	switch varType {
+	case irsdk.VarTypeChar:
+		s, ok := value.(string)
+		if !ok || len(s) != 1 {
+			return fmt.Errorf("char needs a one character string, not %v", value)
+		}
+
+		b[0] = s[0]
+	case irsdk.VarTypeBool:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("bool needs a bool, not %T", value)
+		}
+
+		b[0] = 0
+		if v {
+			b[0] = 1
+		}
+	case irsdk.VarTypeInt, irsdk.VarTypeBitField:
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("int needs an int, not %T", value)
+		}
+
+		binary.LittleEndian.PutUint32(b, uint32(v)) //nolint:gosec // wraps like the C int it is
+	case irsdk.VarTypeFloat:
+		v, ok := float(value)
+		if !ok {
+			return fmt.Errorf("float needs a number, not %T", value)
+		}
+
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+	case irsdk.VarTypeDouble:
+		v, ok := float(value)
+		if !ok {
+			return fmt.Errorf("double needs a number, not %T", value)
+		}
+
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	default:
+		return errors.New("unknown type")
+	}
+
+	return nil
+}
+
+func float(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+func each[T any](values []T) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+
+	return result
+}
+
+//nolint:mnd // bytes
+func typeSize(varType irsdk.VarType) int {
+	switch varType {
+	case irsdk.VarTypeChar, irsdk.VarTypeBool:
+		return 1
+	case irsdk.VarTypeInt, irsdk.VarTypeBitField, irsdk.VarTypeFloat:
+		return 4
+	case irsdk.VarTypeDouble:
+		return 8
+	}
+
+	return 0
+}
+
+func align(n int, size int) int {
+	return (n + size - 1) / size * size
+}
+
+func orDefault(value int, def int) int {
+	if value > 0 {
+		return value
+	}
+
+	return def
+}
+
+// memory is a growable in memory image
+type memory struct {
+	b []byte
+}
+
+func (m *memory) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, m.b[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (m *memory) WriteAt(b []byte, off int64) (int, error) {
+	if end := int(off) + len(b); end > len(m.b) {
+		m.b = append(m.b, make([]byte, end-len(m.b))...)
+	}
+
+	return copy(m.b[off:], b), nil
+}
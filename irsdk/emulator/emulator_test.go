@@ -0,0 +1,148 @@
+package emulator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const session = `WeekendInfo:
+ TrackName: suzuka grandprix
+ TrackDisplayName: Suzuka International Racing Course
+DriverInfo:
+ DriverCarIdx: 1
+ Drivers:
+ - CarIdx: 0
+   UserName: Pace Car
+ - CarIdx: 1
+   UserName: Jürgen Müller
+`
+
+func TestEmulator(t *testing.T) {
+	t.Run("Standard variables should decode through the SDK", func(t *testing.T) {
+		emu, err := New(nil)
+		require.NoError(t, err)
+
+		require.NoError(t, emu.SetSession(session))
+		require.NoError(t, emu.Set("SessionNum", 2))
+		require.NoError(t, emu.Set("SessionTime", 123.5))
+		require.NoError(t, emu.Set("CarIdxLapCompleted", 7))
+		assert.Error(t, emu.Set("CarIdxOnPitRoad", []bool{true, false}), "wrong length")
+		assert.Equal(t, 1, emu.Tick())
+
+		sdk := irsdk.NewIrSDK(emu)
+		defer sdk.Close()
+
+		vars, err := sdk.GetVars()
+		require.NoError(t, err)
+		assert.Len(t, vars, len(StandardVars()))
+
+		assert.Equal(t, 1, sdk.GetLastVersion())
+		assert.Equal(t, "Jürgen Müller", sdk.GetSession().DriverInfo.Drivers[1].UserName)
+
+		sessionNum, _ := sdk.GetVarValue("SessionNum")
+		assert.Equal(t, 2, sessionNum)
+
+		sessionTime, _ := sdk.GetVarValue("SessionTime")
+		assert.InDelta(t, 123.5, sessionTime, 0.001)
+
+		laps, _ := sdk.GetVarValues("CarIdxLapCompleted")
+		assert.Len(t, laps, 64)
+		assert.Equal(t, 7, laps.([]int)[63])
+	})
+
+	t.Run("Ticks should rotate through the buffers", func(t *testing.T) {
+		emu, err := New(&Config{Vars: []Var{{Name: "Speed", Type: irsdk.VarTypeFloat, Count: 1}}})
+		require.NoError(t, err)
+
+		sdk := irsdk.NewIrSDK(emu)
+		assert.False(t, sdk.WaitForData(time.Millisecond), "no ticks yet")
+
+		for tick := 1; tick <= 5; tick++ {
+			require.NoError(t, emu.Set("Speed", float32(tick)))
+			emu.Tick()
+
+			assert.True(t, sdk.WaitForData(time.Millisecond))
+			assert.False(t, sdk.WaitForData(time.Millisecond), "same tick")
+
+			speed, _ := sdk.GetVarValue("Speed")
+			assert.InDelta(t, tick, speed, 0.001)
+		}
+
+		info := irsdk.Inspect(emu)
+		assert.Equal(t, DefaultBuffers, info.NumBuf)
+		assert.Equal(t, 5, info.Buffers[5%DefaultBuffers].TickCount)
+		assert.Equal(t, 4, info.Buffers[4%DefaultBuffers].TickCount)
+	})
+
+	t.Run("Session changes and disconnects should be seen by the SDK", func(t *testing.T) {
+		emu, err := New(&Config{Vars: []Var{{Name: "SessionState", Type: irsdk.VarTypeInt}}})
+		require.NoError(t, err)
+
+		require.NoError(t, emu.SetSession(session))
+		emu.Tick()
+
+		sdk := irsdk.NewIrSDK(emu)
+		assert.Equal(t, "suzuka grandprix", sdk.GetSession().WeekendInfo.TrackName)
+
+		require.NoError(t, emu.SetSession("WeekendInfo:\n TrackName: spa\n"))
+		emu.Tick()
+		assert.True(t, sdk.WaitForData(time.Millisecond))
+		assert.Equal(t, "spa", sdk.GetSession().WeekendInfo.TrackName)
+
+		emu.Disconnect()
+		sdk.WaitForData(time.Millisecond)
+		assert.Equal(t, -1, sdk.GetLastVersion())
+
+		emu.Connect()
+		emu.Tick()
+		assert.True(t, sdk.WaitForData(time.Millisecond))
+		assert.Equal(t, 3, sdk.GetLastVersion())
+	})
+
+	t.Run("File backed emulator should be readable as a snapshot", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "live.bin")
+
+		emu, err := NewFile(path, nil)
+		require.NoError(t, err)
+
+		defer emu.Close()
+
+		require.NoError(t, emu.SetSession(session))
+		require.NoError(t, emu.Set("SessionState", 4))
+		emu.Tick()
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+
+		sdk := irsdk.NewIrSDK(f)
+		defer sdk.Close()
+
+		state, _ := sdk.GetVarValue("SessionState")
+		assert.Equal(t, 4, state)
+	})
+
+	t.Run("Invalid values should be rejected", func(t *testing.T) {
+		emu, err := New(nil)
+		require.NoError(t, err)
+
+		assert.Error(t, emu.Set("NoSuchVar", 1))
+		assert.Error(t, emu.Set("SessionNum", 1.5))
+		assert.Error(t, emu.Set("CarIdxLapCompleted", []int{1, 2}))
+		assert.Error(t, emu.Set("OnPitRoad", 1))
+
+		_, err = New(&Config{Buffers: 5})
+		assert.Error(t, err)
+
+		_, err = New(&Config{Vars: []Var{{Name: "A", Type: irsdk.VarTypeInt}, {Name: "A", Type: irsdk.VarTypeInt}}})
+		assert.Error(t, err)
+
+		small, _ := New(&Config{SessionSize: 16})
+		assert.Error(t, small.SetSession(session))
+	})
+}
@@ -46,3 +46,37 @@ func readHeader(r reader) header {
 	// fmt.Printf("%v\n%+v\n", rbuf, h)
 	return h
 }
+
+// Info describes the shared memory layout, for diagnosing a simulator or file
+type Info struct {
+	Version           int
+	Connected         bool
+	TickRate          int
+	SessionInfoUpdate int
+	SessionInfoLen    int
+	SessionInfoOffset int
+	NumVars           int
+	VarHeaderOffset   int
+	NumBuf            int
+	BufLen            int
+	Buffers           []VarBuffer
+}
+
+// Inspect reads the header and variable buffer headers from a snapshot, recording or the live shared memory
+func Inspect(r Source) Info {
+	h := readHeader(r)
+
+	return Info{
+		Version:           h.version,
+		Connected:         sessionStatusOK(h.status),
+		TickRate:          h.tickRate,
+		SessionInfoUpdate: h.sessionInfoUpdate,
+		SessionInfoLen:    h.sessionInfoLen,
+		SessionInfoOffset: h.sessionInfoOffset,
+		NumVars:           h.numVars,
+		VarHeaderOffset:   h.headerOffset,
+		NumBuf:            h.numBuf,
+		BufLen:            h.bufLen,
+		Buffers:           readBuffers(r, &h),
+	}
+}
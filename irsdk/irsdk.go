@@ -37,6 +37,7 @@ type SDK interface {
 	GetVarValue(name string) (interface{}, error)
 	GetVarValues(name string) (interface{}, error)
 	GetSession() iryaml.IRSession
+	GetSessionData(path string) (string, error)
 	GetLastVersion() int
 	IsConnected() bool
 	GetYaml() string
@@ -67,9 +68,12 @@ func NewIrSDK(r reader) *IRSDK {
 
 	sdk := &IRSDK{r: r, lastValidData: 0}
 
-	err := events.OpenEvent(dataValidEventName)
-	if err != nil {
-		log.Fatal("Open event", err)
+	// Recordings are paced by the player rather than the simulator
+	if _, ok := r.(ticker); !ok {
+		err := events.OpenEvent(dataValidEventName)
+		if err != nil {
+			log.Fatal("Open event", err)
+		}
 	}
 
 	initIRSDK(sdk)
@@ -79,25 +83,58 @@ func NewIrSDK(r reader) *IRSDK {
 
 func (sdk *IRSDK) RefreshSession() {
 	if sessionStatusOK(sdk.h.status) {
-		sRaw := readSessionData(sdk.r, sdk.h)
+		sdk.parseSession(readSessionData(sdk.r, sdk.h))
+	}
+}
 
-		err := yaml.Unmarshal([]byte(sRaw), &sdk.session)
-		if err != nil {
-			log.Println(err)
+// parseSession decodes the session info YAML, keeping the previous session if it is invalid
+func (sdk *IRSDK) parseSession(sRaw string) {
+	err := yaml.Unmarshal([]byte(sRaw), &sdk.session)
+	if err != nil {
+		sessionParses.Inc("error")
+		logger.Error("Can not parse session info", "err", err)
+	} else {
+		sessionParses.Inc("ok")
+	}
+
+	sdk.s = strings.Split(sRaw, "\n")
+}
+
+func (sdk *IRSDK) WaitForData(timeout time.Duration) bool {
+	// Anything read when connecting is new to the caller
+	connecting := !sdk.IsConnected()
+	if connecting {
+		initIRSDK(sdk)
+	}
+
+	if t, ok := sdk.r.(ticker); ok {
+		if !t.Next(timeout) {
+			return false
 		}
+	} else if !events.WaitForSingleObject(timeout) {
+		return false
+	}
 
-		sdk.s = strings.Split(sRaw, "\n")
+	if sdk.refreshHeader() {
+		return sessionStatusOK(sdk.h.status)
 	}
+
+	return readVariableValues(sdk) || (connecting && sdk.GetLastVersion() > 0)
 }
 
-func (sdk *IRSDK) WaitForData(timeout time.Duration) bool {
-	if !sdk.IsConnected() {
+// refreshHeader picks up a new session, returning true if the simulator connected, disconnected or changed the
+// variables so everything was read again
+func (sdk *IRSDK) refreshHeader() bool {
+	h := readHeader(sdk.r)
+
+	if h.status != sdk.h.status || layoutChanged(sdk.h, &h) {
 		initIRSDK(sdk)
+		return true
 	}
 
-	if events.WaitForSingleObject(timeout) {
+	if h.sessionInfoUpdate != sdk.h.sessionInfoUpdate {
+		sdk.h = &h
 		sdk.RefreshSession()
-		return readVariableValues(sdk)
 	}
 
 	return false
@@ -213,19 +250,15 @@ func initIRSDK(sdk *IRSDK) {
 	sdk.h = &h
 	sdk.s = nil
 
+	connected.Set(0)
+
 	if sdk.tVars != nil {
 		sdk.tVars.vars = nil
 	}
 
 	if sessionStatusOK(h.status) {
-		sRaw := readSessionData(sdk.r, &h)
-
-		err := yaml.Unmarshal([]byte(sRaw), &sdk.session)
-		if err != nil {
-			log.Println(err)
-		}
-
-		sdk.s = strings.Split(sRaw, "\n")
+		connected.Set(1)
+		sdk.parseSession(readSessionData(sdk.r, &h))
 		sdk.tVars = readVariableHeaders(sdk.r, &h)
 		readVariableValues(sdk)
 	}
@@ -234,3 +267,8 @@ func initIRSDK(sdk *IRSDK) {
 func sessionStatusOK(status int) bool {
 	return (status & stConnected) > 0
 }
+
+// Reader returns the memory the SDK reads, e.g. for Inspect
+func (sdk *IRSDK) Reader() Source {
+	return sdk.r
+}
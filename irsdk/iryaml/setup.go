@@ -7,21 +7,23 @@ type CarSetup struct {
 }
 
 type SetupTiresAero struct {
-	LeftFront       SetupAeroFront       `yaml:"LeftFront"`
-	RightFront      SetupAeroFront       `yaml:"RightFront"`
-	LeftRear        SetupAeroRear        `yaml:"LeftRear"`
-	RightRear       SetupAeroRear        `yaml:"RightRear"`
+	LeftFront       SetupTireLeft        `yaml:"LeftFront"`
+	RightFront      SetupTireRight       `yaml:"RightFront"`
+	LeftRear        SetupTireLeft        `yaml:"LeftRear"`
+	RightRear       SetupTireRight       `yaml:"RightRear"`
 	AeroBalanceCalc SetupAeroBalanceCalc `yaml:"AeroBalanceCalc"`
 }
 
-type SetupAeroFront struct {
+// SetupTireLeft is a left tyre, values across the tread are outer, middle and inner
+type SetupTireLeft struct {
 	StartingPressure string `yaml:"StartingPressure"`
 	LastHotPressure  string `yaml:"LastHotPressure"`
 	LastTempsOMI     string `yaml:"LastTempsOMI"`
 	TreadRemaining   string `yaml:"TreadRemaining"`
 }
 
-type SetupAeroRear struct {
+// SetupTireRight is a right tyre, values across the tread are inner, middle and outer
+type SetupTireRight struct {
 	StartingPressure string `yaml:"StartingPressure"`
 	LastHotPressure  string `yaml:"LastHotPressure"`
 	LastTempsIMO     string `yaml:"LastTempsIMO"`
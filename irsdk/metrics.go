@@ -0,0 +1,15 @@
+package irsdk
+
+import (
+	"github.com/ianhaycox/vcrlive/logging"
+	"github.com/ianhaycox/vcrlive/metrics"
+)
+
+var logger = logging.Component("irsdk")
+
+var (
+	ticksRead     = metrics.NewCounter("vcrlive_irsdk_ticks_read_total", "Telemetry ticks read from the simulator")
+	decodeSeconds = metrics.NewHistogram("vcrlive_irsdk_decode_seconds", "Time to decode the telemetry variables of a tick", metrics.DefaultBuckets)
+	sessionParses = metrics.NewCounter("vcrlive_irsdk_session_yaml_parses_total", "Session info YAML parses by result, ok or error", "result")
+	connected     = metrics.NewGauge("vcrlive_irsdk_connected", "1 while the simulator is connected, 0 when it is not")
+)
@@ -0,0 +1,469 @@
+package irsdk
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A recording is a gzip stream starting with recordingMagic followed by frames of
+//
+//	kind      1 byte, one of frameImage, frameSession or frameVars
+//	elapsed   4 bytes, milliseconds since the recording started
+//	length    4 bytes
+//	payload   length bytes
+//
+// The first frame is a complete copy of the shared memory. After that only the session YAML is recorded when it
+// changes and the latest variable buffer on each new tick, which keeps an evening's racing to a manageable size.
+const (
+	recordingMagic = "VCRREC1\n"
+
+	frameImage   byte = 'I' // whole memory image, also written if the variable layout changes
+	frameSession byte = 'S' // sessionInfoUpdate then the session YAML
+	frameVars    byte = 'V' // tick count then one variable buffer row
+
+	frameHeaderLen = 9
+	bufHeaderLen   = 16
+	mainHeaderLen  = 48
+	varHeaderLen   = 144
+)
+
+// Source is anything NewIrSDK can read the shared memory layout from, e.g. an *os.File or a *Player
+type Source interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// ticker is a Source that moves on to the next tick itself rather than being updated by the simulator
+type ticker interface {
+	Next(timeout time.Duration) bool
+}
+
+// IsRecording reports whether r holds a recording rather than a single memory snapshot
+func IsRecording(r io.ReaderAt) bool {
+	magic := make([]byte, 2)
+
+	_, err := r.ReadAt(magic, 0)
+
+	// gzip, a snapshot starts with the SDK version
+	return err == nil && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// Recorder writes the simulator's shared memory to a recording
+type Recorder struct {
+	r       reader
+	gz      *gzip.Writer
+	now     func() time.Time
+	start   time.Time
+	h       header
+	tick    int
+	started bool
+	Stats   RecordingStats
+}
+
+// RecordingStats counts what a recording holds
+type RecordingStats struct {
+	Images         int
+	SessionUpdates int
+	Ticks          int
+	Elapsed        time.Duration
+}
+
+// NewRecorder records the memory sdk reads from to w. Call Capture after each WaitForData and Close when done.
+func NewRecorder(sdk *IRSDK, w io.Writer) (*Recorder, error) {
+	gz := gzip.NewWriter(w)
+
+	_, err := io.WriteString(gz, recordingMagic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{r: sdk.r, gz: gz, now: time.Now, tick: -1}, nil
+}
+
+// Capture writes whatever changed since the last call. Nothing is written while the simulator is disconnected.
+func (rec *Recorder) Capture() error {
+	h := readHeader(rec.r)
+	if !sessionStatusOK(h.status) {
+		return nil
+	}
+
+	if !rec.started {
+		rec.start = rec.now()
+		rec.started = true
+	}
+
+	buffers := readBuffers(rec.r, &h)
+
+	if rec.Stats.Images == 0 || layoutChanged(&rec.h, &h) {
+		image := make([]byte, imageSize(&h, buffers))
+
+		err := rec.read(image, 0)
+		if err != nil {
+			return err
+		}
+
+		rec.h = h
+		rec.tick = latest(buffers).TickCount
+		rec.Stats.Images++
+
+		return rec.write(frameImage, image)
+	}
+
+	if h.sessionInfoUpdate != rec.h.sessionInfoUpdate {
+		payload := make([]byte, 4+h.sessionInfoLen) //nolint:mnd // update count
+
+		binary.LittleEndian.PutUint32(payload, uint32(h.sessionInfoUpdate)) //nolint:gosec // from a uint32
+
+		err := rec.read(payload[4:], int64(h.sessionInfoOffset))
+		if err != nil {
+			return err
+		}
+
+		rec.Stats.SessionUpdates++
+
+		err = rec.write(frameSession, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	rec.h = h
+
+	vb := latest(buffers)
+	if vb.TickCount == rec.tick {
+		return nil
+	}
+
+	payload := make([]byte, 4+h.bufLen) //nolint:mnd // tick count
+
+	binary.LittleEndian.PutUint32(payload, uint32(vb.TickCount)) //nolint:gosec // from a uint32
+
+	err := rec.read(payload[4:], int64(vb.bufOffset))
+	if err != nil {
+		return err
+	}
+
+	rec.tick = vb.TickCount
+	rec.Stats.Ticks++
+
+	return rec.write(frameVars, payload)
+}
+
+// Close flushes the recording, the underlying writer is left open
+func (rec *Recorder) Close() error {
+	return rec.gz.Close()
+}
+
+func (rec *Recorder) read(b []byte, off int64) error {
+	_, err := rec.r.ReadAt(b, off)
+	if errors.Is(err, io.EOF) {
+		// Snapshot files end at the last byte used
+		return nil
+	}
+
+	return err
+}
+
+func (rec *Recorder) write(kind byte, payload []byte) error {
+	rec.Stats.Elapsed = rec.now().Sub(rec.start)
+
+	frame := make([]byte, frameHeaderLen)
+	frame[0] = kind
+	binary.LittleEndian.PutUint32(frame[1:5], uint32(rec.Stats.Elapsed.Milliseconds())) //nolint:gosec,mnd // 49 days
+	binary.LittleEndian.PutUint32(frame[5:9], uint32(len(payload)))                     //nolint:gosec,mnd // < 2GB
+
+	_, err := rec.gz.Write(frame)
+	if err != nil {
+		return err
+	}
+
+	_, err = rec.gz.Write(payload)
+
+	return err
+}
+
+// Player replays a recording as a Source for NewIrSDK, moving on a tick each time the SDK waits for data
+type Player struct {
+	src     io.Closer
+	r       *bufio.Reader
+	image   []byte
+	speed   float64
+	now     func() time.Time
+	start   time.Time
+	pending *frame
+	done    chan struct{}
+	err     error
+	Stats   RecordingStats
+}
+
+type frame struct {
+	kind    byte
+	elapsed time.Duration
+	payload []byte
+}
+
+// NewPlayer opens a recording. Playback runs at speed times real time, or as fast as it is read with a speed of 0.
+func NewPlayer(r io.ReadCloser, speed float64) (*Player, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a recording, err:%w", err)
+	}
+
+	p := &Player{src: r, r: bufio.NewReader(gz), speed: speed, now: time.Now, done: make(chan struct{})}
+
+	magic := make([]byte, len(recordingMagic))
+
+	_, err = io.ReadFull(p.r, magic)
+	if err != nil || string(magic) != recordingMagic {
+		return nil, errors.New("not a recording")
+	}
+
+	first, err := p.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	if first.kind != frameImage {
+		return nil, errors.New("recording does not start with a memory image")
+	}
+
+	err = p.apply(first)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ReadAt reads the current memory image
+func (p *Player) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(p.image)) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, p.image[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Next applies frames up to and including the next tick, waiting until it is due but no longer than timeout.
+// It returns false if there was no new tick in time or the recording has ended.
+func (p *Player) Next(timeout time.Duration) bool {
+	if p.start.IsZero() {
+		p.start = p.now()
+	}
+
+	for {
+		if p.pending == nil {
+			f, err := p.readFrame()
+			if errors.Is(err, io.EOF) {
+				p.end()
+				return false
+			}
+
+			if err != nil {
+				p.fail(err)
+				return false
+			}
+
+			p.pending = f
+		}
+
+		if p.speed > 0 {
+			due := p.start.Add(time.Duration(float64(p.pending.elapsed) / p.speed))
+
+			wait := due.Sub(p.now())
+			if wait > timeout {
+				time.Sleep(timeout)
+				return false
+			}
+
+			if wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		f := p.pending
+		p.pending = nil
+
+		err := p.apply(f)
+		if err != nil {
+			p.fail(err)
+			return false
+		}
+
+		if f.kind == frameVars {
+			return true
+		}
+	}
+}
+
+// Done is closed when the recording has been played to the end
+func (p *Player) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns why the recording stopped before its end, nil if it was played to the end or is still playing
+func (p *Player) Err() error {
+	return p.err
+}
+
+// Close closes the recording
+func (p *Player) Close() error {
+	p.end()
+
+	return p.src.Close()
+}
+
+// fail stops playing a truncated or corrupt recording
+func (p *Player) fail(err error) {
+	if p.err == nil {
+		logger.Error("Recording stopped", "err", err)
+		p.err = err
+	}
+
+	p.end()
+}
+
+func (p *Player) end() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+}
+
+func (p *Player) readFrame() (*frame, error) {
+	head := make([]byte, frameHeaderLen)
+
+	_, err := io.ReadFull(p.r, head)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("truncated recording, err:%w", err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := &frame{
+		kind:    head[0],
+		elapsed: time.Duration(binary.LittleEndian.Uint32(head[1:5])) * time.Millisecond, //nolint:mnd // layout above
+	}
+
+	// A frame is never larger than the shared memory, or for an update the image it updates
+	length := int64(binary.LittleEndian.Uint32(head[5:9])) //nolint:mnd // layout above
+	limit := int64(fileMapSize)
+
+	if f.kind != frameImage {
+		limit = int64(len(p.image)) + 4 //nolint:mnd // update or tick count
+	}
+
+	if length > limit {
+		return nil, fmt.Errorf("corrupt recording, a frame of %d bytes is larger than the %d bytes of memory", length, limit)
+	}
+
+	// Read as the data arrives so a corrupt length can not allocate more than the file holds
+	f.payload, err = io.ReadAll(io.LimitReader(p.r, length))
+	if err == nil && int64(len(f.payload)) < length {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("truncated recording, err:%w", err)
+	}
+
+	return f, nil
+}
+
+// apply updates the memory image with a frame, checking it fits the image
+//
+//nolint:mnd // layout above
+func (p *Player) apply(f *frame) error {
+	p.Stats.Elapsed = f.elapsed
+
+	if f.kind == frameImage {
+		if len(f.payload) < mainHeaderLen {
+			return fmt.Errorf("corrupt recording, a memory image of %d bytes", len(f.payload))
+		}
+
+		p.image = f.payload
+		p.Stats.Images++
+
+		return nil
+	}
+
+	if f.kind != frameSession && f.kind != frameVars {
+		return nil
+	}
+
+	if p.image == nil || len(f.payload) < 4 {
+		return fmt.Errorf("corrupt recording, a %q frame of %d bytes", f.kind, len(f.payload))
+	}
+
+	h := readHeader(p)
+
+	switch f.kind {
+	case frameSession:
+		// The session area is fixed size, clear anything left from a longer document
+		if !fits(h.sessionInfoOffset, h.sessionInfoLen, len(p.image)) || len(f.payload)-4 > h.sessionInfoLen {
+			return fmt.Errorf("corrupt recording, a session of %d bytes does not fit %d bytes at %d",
+				len(f.payload)-4, h.sessionInfoLen, h.sessionInfoOffset)
+		}
+
+		area := p.image[h.sessionInfoOffset : h.sessionInfoOffset+h.sessionInfoLen]
+		clear(area)
+		copy(area, f.payload[4:])
+		copy(p.image[12:16], f.payload[0:4])
+		p.Stats.SessionUpdates++
+	case frameVars:
+		if h.numBuf < 1 || !fits(mainHeaderLen, h.numBuf*bufHeaderLen, len(p.image)) {
+			return fmt.Errorf("corrupt recording, %d variable buffers", h.numBuf)
+		}
+
+		buffers := readBuffers(p, &h)
+		if !fits(buffers[0].bufOffset, len(f.payload)-4, len(p.image)) {
+			return fmt.Errorf("corrupt recording, a variable buffer of %d bytes does not fit at %d", len(f.payload)-4, buffers[0].bufOffset)
+		}
+
+		// Everything goes in the first buffer which is then the only one with a tick count
+		for i := range h.numBuf {
+			clear(p.image[mainHeaderLen+i*bufHeaderLen : mainHeaderLen+i*bufHeaderLen+4])
+		}
+
+		copy(p.image[buffers[0].bufOffset:], f.payload[4:])
+		copy(p.image[mainHeaderLen:mainHeaderLen+4], f.payload[0:4])
+		p.Stats.Ticks++
+	}
+
+	return nil
+}
+
+// fits reports whether length bytes at offset are within size
+func fits(offset, length, size int) bool {
+	return offset >= 0 && length >= 0 && offset <= size && length <= size-offset
+}
+
+// layoutChanged reports whether the variables have moved, e.g. the simulator was restarted
+func layoutChanged(a, b *header) bool {
+	return a.headerOffset != b.headerOffset || a.numVars != b.numVars || a.numBuf != b.numBuf ||
+		a.bufLen != b.bufLen || a.sessionInfoOffset != b.sessionInfoOffset || a.sessionInfoLen != b.sessionInfoLen
+}
+
+// imageSize is the extent of the memory in use
+func imageSize(h *header, buffers []VarBuffer) int {
+	size := max(mainHeaderLen+h.numBuf*bufHeaderLen, h.headerOffset+h.numVars*varHeaderLen, h.sessionInfoOffset+h.sessionInfoLen)
+
+	for _, vb := range buffers {
+		size = max(size, vb.bufOffset+h.bufLen)
+	}
+
+	return size
+}
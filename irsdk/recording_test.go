@@ -0,0 +1,259 @@
+package irsdk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memory is a minimal shared memory layout with Speed and Gear variables in three buffers
+type memory struct {
+	b []byte
+}
+
+const (
+	testVarHeaders  = 112
+	testSession     = testVarHeaders + 2*varHeaderLen
+	testSessionLen  = 256
+	testFirstBuffer = testSession + testSessionLen
+	testBufLen      = 8
+)
+
+func newMemory(session string) *memory {
+	m := &memory{b: make([]byte, testFirstBuffer+3*testBufLen)}
+
+	for i, v := range []int{2, stConnected, 60, 1, testSessionLen, testSession, 2, testVarHeaders, 3, testBufLen} {
+		m.put(i*4, v)
+	}
+
+	for i := range 3 {
+		m.put(mainHeaderLen+i*bufHeaderLen+4, testFirstBuffer+i*testBufLen)
+	}
+
+	m.variable(0, VarTypeFloat, 0, "Speed", "GPS vehicle speed", "m/s")
+	m.variable(1, VarTypeInt, 4, "Gear", "0=reverse 1=neutral", "")
+	copy(m.b[testSession:], session)
+
+	return m
+}
+
+func (m *memory) put(off int, v int) {
+	binary.LittleEndian.PutUint32(m.b[off:], uint32(v)) //nolint:gosec // test data
+}
+
+func (m *memory) variable(i int, varType VarType, offset int, name, desc, unit string) {
+	base := testVarHeaders + i*varHeaderLen
+	m.put(base, int(varType))
+	m.put(base+4, offset)
+	m.put(base+8, 1)
+	copy(m.b[base+16:], name)
+	copy(m.b[base+48:], desc)
+	copy(m.b[base+112:], unit)
+}
+
+// tick writes a new row to the next buffer
+func (m *memory) tick(tick int, speed float32, gear int) {
+	buffer := tick % 3
+	row := testFirstBuffer + buffer*testBufLen
+
+	binary.LittleEndian.PutUint32(m.b[row:], math.Float32bits(speed))
+	m.put(row+4, gear)
+	m.put(mainHeaderLen+buffer*bufHeaderLen, tick)
+}
+
+func (m *memory) session(update int, session string) {
+	clear(m.b[testSession : testSession+testSessionLen])
+	copy(m.b[testSession:], session)
+	m.put(12, update)
+}
+
+func (m *memory) ReadAt(b []byte, off int64) (int, error) {
+	n := copy(b, m.b[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (m *memory) Close() error {
+	return nil
+}
+
+func TestRecording(t *testing.T) {
+	m := newMemory("WeekendInfo:\n TrackName: suzuka\n")
+	m.tick(1, 10, 3)
+
+	sdk := NewIrSDK(m)
+
+	var recording bytes.Buffer
+
+	rec, err := NewRecorder(sdk, &recording)
+	require.NoError(t, err)
+
+	rec.now = func() time.Time { return time.Time{} }
+
+	assert.NoError(t, rec.Capture())
+
+	m.tick(2, 20, 4)
+	assert.NoError(t, rec.Capture())
+	assert.NoError(t, rec.Capture(), "same tick is not recorded again")
+
+	m.session(2, "WeekendInfo:\n TrackName: spa\n")
+	m.tick(3, 30, 5)
+	assert.NoError(t, rec.Capture())
+	assert.NoError(t, rec.Close())
+
+	assert.Equal(t, RecordingStats{Images: 1, SessionUpdates: 1, Ticks: 2}, rec.Stats)
+	assert.True(t, IsRecording(bytes.NewReader(recording.Bytes())))
+	assert.False(t, IsRecording(m))
+
+	t.Run("Replay should step through each recorded tick", func(t *testing.T) {
+		player, err := NewPlayer(io.NopCloser(bytes.NewReader(recording.Bytes())), 0)
+		require.NoError(t, err)
+
+		replay := NewIrSDK(player)
+		defer replay.Close()
+
+		speed, err := replay.GetVarValue("Speed")
+		require.NoError(t, err)
+		assert.InDelta(t, 10, speed, 0.001)
+
+		assert.True(t, replay.WaitForData(0))
+		speed, _ = replay.GetVarValue("Speed")
+		assert.InDelta(t, 20, speed, 0.001)
+		assert.Equal(t, "suzuka", replay.GetSession().WeekendInfo.TrackName)
+
+		assert.True(t, replay.WaitForData(0))
+		gear, _ := replay.GetVarValue("Gear")
+		assert.Equal(t, 5, gear)
+		assert.Equal(t, "spa", replay.GetSession().WeekendInfo.TrackName)
+
+		assert.False(t, replay.WaitForData(0))
+		assert.Equal(t, 2, player.Stats.Ticks)
+
+		select {
+		case <-player.Done():
+		default:
+			assert.Fail(t, "player should be done")
+		}
+	})
+
+	t.Run("Inspect should describe the layout", func(t *testing.T) {
+		info := Inspect(m)
+		assert.True(t, info.Connected)
+		assert.Equal(t, 60, info.TickRate)
+		assert.Equal(t, 2, info.NumVars)
+		assert.Len(t, info.Buffers, 3)
+		assert.Equal(t, 3, info.Buffers[0].TickCount)
+		assert.Equal(t, testFirstBuffer, info.Buffers[0].Offset())
+	})
+
+	t.Run("Not a recording", func(t *testing.T) {
+		_, err := NewPlayer(io.NopCloser(bytes.NewReader(m.b)), 1)
+		assert.Error(t, err)
+	})
+}
+
+// recorded writes a recording of frames as they are laid out in the file
+func recorded(t *testing.T, frames ...[]byte) io.ReadCloser {
+	t.Helper()
+
+	var b bytes.Buffer
+
+	gz := gzip.NewWriter(&b)
+	_, err := io.WriteString(gz, recordingMagic)
+	require.NoError(t, err)
+
+	for _, f := range frames {
+		_, err = gz.Write(f)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, gz.Close())
+
+	return io.NopCloser(&b)
+}
+
+// frameOf lays out a frame claiming length bytes of payload
+func frameOf(kind byte, length int, payload []byte) []byte {
+	f := make([]byte, frameHeaderLen, frameHeaderLen+len(payload))
+	f[0] = kind
+	binary.LittleEndian.PutUint32(f[5:9], uint32(length)) //nolint:gosec // test data
+
+	return append(f, payload...)
+}
+
+func TestCorruptRecording(t *testing.T) {
+	m := newMemory("WeekendInfo:\n TrackName: suzuka\n")
+	image := frameOf(frameImage, len(m.b), m.b)
+	vars := frameOf(frameVars, 4+testBufLen, make([]byte, 4+testBufLen))
+
+	t.Run("A complete recording should play to the end", func(t *testing.T) {
+		player, err := NewPlayer(recorded(t, image, vars), 0)
+		require.NoError(t, err)
+
+		assert.True(t, player.Next(0))
+		assert.False(t, player.Next(0))
+		assert.NoError(t, player.Err())
+	})
+
+	t.Run("A truncated frame should stop the recording", func(t *testing.T) {
+		player, err := NewPlayer(recorded(t, image, vars[:frameHeaderLen+2]), 0)
+		require.NoError(t, err)
+
+		assert.False(t, player.Next(0))
+		assert.ErrorContains(t, player.Err(), "truncated recording")
+
+		_, err = NewPlayer(recorded(t, image[:100]), 0)
+		assert.ErrorContains(t, err, "truncated recording")
+	})
+
+	t.Run("A frame larger than the memory should not be read", func(t *testing.T) {
+		_, err := NewPlayer(recorded(t, frameOf(frameImage, math.MaxUint32, nil)), 0)
+		assert.ErrorContains(t, err, "larger than")
+
+		player, err := NewPlayer(recorded(t, image, frameOf(frameVars, len(m.b)+5, nil)), 0)
+		require.NoError(t, err)
+
+		assert.False(t, player.Next(0))
+		assert.ErrorContains(t, player.Err(), "larger than")
+	})
+
+	t.Run("A frame that does not fit the memory image should stop the recording", func(t *testing.T) {
+		_, err := NewPlayer(recorded(t, frameOf(frameImage, 10, make([]byte, 10))), 0)
+		assert.ErrorContains(t, err, "a memory image of 10 bytes")
+
+		moved := newMemory("")
+		moved.put(mainHeaderLen+4, len(moved.b)-4)
+
+		player, err := NewPlayer(recorded(t, frameOf(frameImage, len(moved.b), moved.b), vars), 0)
+		require.NoError(t, err)
+
+		assert.False(t, player.Next(0))
+		assert.ErrorContains(t, player.Err(), "does not fit")
+
+		session := frameOf(frameSession, 4+testSessionLen+1, make([]byte, 4+testSessionLen+1))
+
+		player, err = NewPlayer(recorded(t, image, session), 0)
+		require.NoError(t, err)
+
+		assert.False(t, player.Next(0))
+		assert.ErrorContains(t, player.Err(), "does not fit")
+
+		empty := frameOf(frameVars, 2, make([]byte, 2))
+
+		player, err = NewPlayer(recorded(t, image, empty), 0)
+		require.NoError(t, err)
+
+		assert.False(t, player.Next(0))
+		assert.ErrorContains(t, player.Err(), "corrupt recording")
+	})
+}
@@ -0,0 +1,934 @@
+package scenario
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/irsdk/emulator"
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxCars        = 64
+	paceCarClassID = 11
+
+	getInCarTime = 2.0 // seconds in each state before racing
+	warmupTime   = 2.0
+	paradeTime   = 3.0
+	coolDownTime = 5.0
+	greenTime    = 5.0 // green flag shown at the start
+	gridGap      = 0.2 // seconds between each grid slot getting away
+	offTrackPace = 0.1 // fraction of normal speed when off track
+
+	reasonRunning      = 0
+	reasonDisconnected = 32
+)
+
+// published are the variables the generator writes
+var published = []string{
+	"SessionNum", "SessionState", "SessionTime", "SessionTick", "SessionTimeRemain", "SessionLapsRemainEx",
+	"SessionFlags", "PlayerCarIdx",
+	"CarIdxLap", "CarIdxLapCompleted", "CarIdxLapDistPct", "CarIdxPosition", "CarIdxClassPosition", "CarIdxOnPitRoad",
+	"CarIdxTrackSurface", "CarIdxLastLapTime", "CarIdxBestLapTime", "CarIdxF2Time", "CarIdxEstTime", "CarIdxClass",
+	"CarIdxSessionFlags",
+	"AirTemp", "TrackTemp", "TrackTempCrew", "Precipitation", "TrackWetness", "Skies", "WeatherDeclaredWet", "WindVel",
+	"WindDir",
+}
+
+// Car is the state of one car, see Generator.Snapshot
+type Car struct {
+	CarIdx        int
+	ClassID       int
+	Lap           int // lap being driven
+	LapsCompleted int
+	Pct           float64 // distance around the lap
+	Position      int     // 0 until placed
+	ClassPosition int
+	OnPitRoad     bool
+	Surface       int // irsdk.Track...
+	LastLap       time.Duration
+	BestLap       time.Duration
+	Finished      bool
+	Out           bool // disconnected
+	Incidents     int
+}
+
+// Snapshot is the generated state at the current tick
+type Snapshot struct {
+	Tick       int
+	SessionNum int
+	State      int // irsdk.State...
+	Time       time.Duration
+	Connected  bool
+	Cars       []Car // ordered by CarIdx
+}
+
+// car is a Car while the session runs
+type car struct {
+	Car
+	driver     *Driver
+	class      *Class
+	target     float64 // seconds for the current lap
+	lapStart   float64
+	startDelay float64
+	pitUntil   float64
+	offUntil   float64
+	bestLapNum int
+	finish     int // finishing order
+	total      float64
+}
+
+// Generator runs a Scenario, writing each tick to an emulator. It is an irsdk.Source which moves on a tick each time
+// the SDK waits for data.
+type Generator struct {
+	sc        *Scenario
+	emu       *emulator.Emulator
+	rng       *rand.Rand
+	dt        float64
+	tick      int
+	session   int
+	state     int
+	time      float64 // session time
+	since     float64 // when the state was entered
+	greenAt   float64
+	checkered float64
+	finishers int
+	cars      []*car
+	grid      []int
+	connected bool
+	ended     bool
+	done      chan struct{}
+	results   [][]iryaml.ResultsPosition
+}
+
+// Start validates the scenario and publishes the first tick, getting in the cars for the first session
+func (sc *Scenario) Start() (*Generator, error) {
+	err := sc.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var vars []emulator.Var
+
+	for _, v := range emulator.StandardVars() {
+		if slices.Contains(published, v.Name) {
+			vars = append(vars, v)
+		}
+	}
+
+	emu, err := emulator.New(&emulator.Config{Vars: vars, TickRate: sc.tickRate})
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Generator{
+		sc:        sc,
+		emu:       emu,
+		rng:       newRand(sc.seed, 0),
+		dt:        1 / float64(sc.tickRate),
+		connected: true,
+		done:      make(chan struct{}),
+		results:   make([][]iryaml.ResultsPosition, len(sc.sessions)),
+	}
+
+	for i := range sc.drivers {
+		d := &sc.drivers[i]
+		g.cars = append(g.cars, &car{Car: Car{CarIdx: d.CarIdx, ClassID: d.ClassID}, driver: d, class: sc.class(d.ClassID)})
+	}
+
+	slices.SortFunc(g.cars, func(a, b *car) int { return a.CarIdx - b.CarIdx })
+
+	g.enter(0)
+
+	err = g.writeSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, g.publish()
+}
+
+// Step moves the scenario on one tick, returning false once the last session has cooled down
+func (g *Generator) Step() bool {
+	if g.ended {
+		return false
+	}
+
+	g.time += g.dt
+	g.advance()
+
+	if g.ended {
+		close(g.done)
+		return false
+	}
+
+	err := g.publish()
+	if err != nil {
+		panic(err) // only bad scenario values, caught by the tests using it
+	}
+
+	return true
+}
+
+// Next is Step for irsdk.NewIrSDK, the scenario runs as fast as it is read
+func (g *Generator) Next(_ time.Duration) bool {
+	return g.Step()
+}
+
+// ReadAt reads the emulated memory
+func (g *Generator) ReadAt(b []byte, off int64) (int, error) {
+	return g.emu.ReadAt(b, off)
+}
+
+// Close releases the emulator
+func (g *Generator) Close() error {
+	return g.emu.Close()
+}
+
+// Done is closed when the scenario has finished
+func (g *Generator) Done() <-chan struct{} {
+	return g.done
+}
+
+// Snapshot returns the state at the current tick
+func (g *Generator) Snapshot() Snapshot {
+	s := Snapshot{
+		Tick:       g.tick,
+		SessionNum: g.session,
+		State:      g.state,
+		Time:       seconds(g.time),
+		Connected:  g.connected,
+	}
+
+	for _, c := range g.cars {
+		s.Cars = append(s.Cars, c.Car)
+	}
+
+	return s
+}
+
+// Results returns the results of a session once it has finished
+func (g *Generator) Results(sessionNum int) []iryaml.ResultsPosition {
+	return g.results[sessionNum]
+}
+
+// enter starts a session with the cars getting in
+func (g *Generator) enter(sessionNum int) {
+	g.session = sessionNum
+	g.time = 0
+	g.setState(irsdk.StateGetInCar)
+	g.finishers = 0
+	g.grid = g.gridOrder()
+
+	for i, carIdx := range g.grid {
+		c := g.car(carIdx)
+		c.Car = Car{CarIdx: c.CarIdx, ClassID: c.ClassID, Surface: irsdk.TrackInPitStall, OnPitRoad: true}
+		c.pitUntil, c.offUntil, c.finish, c.total, c.bestLapNum = 0, 0, 0, 0, 0
+		c.startDelay = float64(i) * gridGap
+	}
+
+	g.place()
+}
+
+func (g *Generator) setState(state int) {
+	g.state = state
+	g.since = g.time
+}
+
+func (g *Generator) advance() {
+	session := &g.sc.sessions[g.session]
+	inState := g.time - g.since
+
+	switch g.state {
+	case irsdk.StateGetInCar:
+		if inState >= getInCarTime {
+			if session.Type == Race {
+				g.setState(irsdk.StateWarmup)
+			} else {
+				g.green()
+			}
+		}
+	case irsdk.StateWarmup:
+		if inState >= warmupTime {
+			g.setState(irsdk.StateParadeLaps)
+		}
+	case irsdk.StateParadeLaps:
+		if inState >= paradeTime {
+			g.green()
+		}
+	case irsdk.StateRacing, irsdk.StateCheckered:
+		for _, c := range g.cars {
+			g.move(c)
+		}
+
+		if g.state == irsdk.StateRacing && session.Laps == 0 && g.time-g.greenAt >= session.Time.Seconds() {
+			g.checker()
+		}
+
+		if g.state == irsdk.StateCheckered && g.allFinished() {
+			g.finishSession()
+		}
+	case irsdk.StateCoolDown:
+		if inState >= coolDownTime {
+			if g.session+1 == len(g.sc.sessions) {
+				g.ended = true
+				return
+			}
+
+			g.enter(g.session + 1)
+
+			err := g.writeSession()
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	g.place()
+}
+
+// green starts the cars, leaving the pits for practice and qualifying or from the grid for a race
+func (g *Generator) green() {
+	g.setState(irsdk.StateRacing)
+	g.greenAt = g.time
+
+	for _, c := range g.cars {
+		c.Lap = 1
+		c.Surface = irsdk.TrackOnTrack
+		c.OnPitRoad = false
+		c.lapStart = g.time + c.startDelay
+		c.target = g.lapTarget(c)
+	}
+}
+
+func (g *Generator) checker() {
+	g.setState(irsdk.StateCheckered)
+	g.checkered = g.time
+}
+
+// allFinished is true when every car still running has taken the checkered flag, or the stragglers have had time to
+func (g *Generator) allFinished() bool {
+	slowest := 0.0
+
+	for _, c := range g.cars {
+		if !c.Finished && !c.Out {
+			slowest = max(slowest, c.class.LapTime.Seconds())
+		}
+	}
+
+	return slowest == 0 || g.time-g.checkered > 2*slowest
+}
+
+// move drives a car on for one tick
+func (g *Generator) move(c *car) {
+	if c.Out || c.Finished || g.time < g.greenAt+c.startDelay {
+		return
+	}
+
+	if c.pitUntil > g.time {
+		c.Surface, c.OnPitRoad = irsdk.TrackInPitStall, true
+		return
+	}
+
+	c.Surface, c.OnPitRoad = irsdk.TrackOnTrack, false
+	pace := 1.0
+
+	if c.offUntil > g.time {
+		c.Surface, pace = irsdk.TrackOffTrack, offTrackPace
+	}
+
+	from := c.Pct
+	c.Pct += pace * g.dt / c.target
+
+	for _, incident := range g.sc.incidents {
+		if g.happens(incident.Session, incident.CarIdx, incident.Lap, incident.At, c, from) {
+			c.offUntil = g.time + incident.Lost.Seconds()
+			c.Surface = irsdk.TrackOffTrack
+			c.Incidents++
+		}
+	}
+
+	for _, d := range g.sc.disconnects {
+		if g.happens(d.Session, d.CarIdx, d.Lap, d.At, c, from) {
+			c.Out, c.Surface, c.OnPitRoad = true, irsdk.TrackNotInWorld, false
+			return
+		}
+	}
+
+	if c.Pct >= 1 {
+		g.completeLap(c)
+	}
+}
+
+// happens is true if a car passed the place an event happens this tick
+func (g *Generator) happens(session, carIdx, lap int, at float64, c *car, from float64) bool {
+	return session == g.session && carIdx == c.CarIdx && lap == c.Lap && from < at && c.Pct >= at
+}
+
+func (g *Generator) completeLap(c *car) {
+	lapTime := g.time - c.lapStart
+
+	c.Pct--
+	c.LapsCompleted++
+	c.LastLap = seconds(lapTime)
+	c.total += lapTime
+
+	if c.BestLap == 0 || c.LastLap < c.BestLap {
+		c.BestLap = c.LastLap
+		c.bestLapNum = c.LapsCompleted
+	}
+
+	session := &g.sc.sessions[g.session]
+
+	if g.state == irsdk.StateRacing && session.Laps > 0 && c.LapsCompleted >= session.Laps {
+		g.checker()
+	}
+
+	if g.state == irsdk.StateCheckered {
+		g.finishers++
+		c.Finished, c.finish, c.Pct = true, g.finishers, 0
+
+		return
+	}
+
+	c.Lap++
+	c.lapStart = g.time
+	c.target = g.lapTarget(c)
+
+	for _, pit := range g.sc.pits {
+		if pit.Session == g.session && pit.CarIdx == c.CarIdx && pit.Lap == c.LapsCompleted {
+			c.pitUntil = g.time + pit.Stop.Seconds()
+			c.Surface, c.OnPitRoad, c.Pct = irsdk.TrackInPitStall, true, 0
+		}
+	}
+}
+
+func (g *Generator) lapTarget(c *car) float64 {
+	base := c.class.LapTime.Seconds() * c.driver.Pace
+	lap := base + g.rng.NormFloat64()*c.class.Variance.Seconds()
+
+	return max(lap, base/2) //nolint:mnd // keep silly variances sane
+}
+
+// place sets overall and class positions
+func (g *Generator) place() {
+	order := slices.Clone(g.cars)
+
+	if g.sc.sessions[g.session].Type == Race {
+		if g.state < irsdk.StateRacing {
+			order = order[:0]
+			for _, carIdx := range g.grid {
+				order = append(order, g.car(carIdx))
+			}
+		} else {
+			slices.SortStableFunc(order, raceOrder)
+		}
+	} else {
+		order = slices.DeleteFunc(order, func(c *car) bool { return c.BestLap == 0 })
+		slices.SortStableFunc(order, func(a, b *car) int { return cmp.Compare(a.BestLap, b.BestLap) })
+	}
+
+	for _, c := range g.cars {
+		c.Position, c.ClassPosition = 0, 0
+	}
+
+	classPositions := map[int]int{}
+
+	for i, c := range order {
+		classPositions[c.ClassID]++
+		c.Position = i + 1
+		c.ClassPosition = classPositions[c.ClassID]
+	}
+}
+
+// raceOrder is by laps completed, then who finished first, then distance around the lap
+func raceOrder(a, b *car) int {
+	if a.LapsCompleted != b.LapsCompleted {
+		return b.LapsCompleted - a.LapsCompleted
+	}
+
+	if a.Finished && b.Finished {
+		return a.finish - b.finish
+	}
+
+	if a.Finished != b.Finished {
+		if a.Finished {
+			return -1
+		}
+
+		return 1
+	}
+
+	return cmp.Compare(b.Pct, a.Pct)
+}
+
+// gridOrder is the qualifying order for a race, otherwise by car
+func (g *Generator) gridOrder() []int {
+	grid := make([]int, 0, len(g.cars))
+	for _, c := range g.cars {
+		grid = append(grid, c.CarIdx)
+	}
+
+	for s := g.session - 1; s >= 0; s-- {
+		if g.sc.sessions[s].Type != Qualify || len(g.results[s]) == 0 {
+			continue
+		}
+
+		position := map[int]int{}
+		for _, r := range g.results[s] {
+			position[r.CarIdx] = r.Position
+		}
+
+		slices.SortStableFunc(grid, func(a, b int) int {
+			pa, oka := position[a]
+			pb, okb := position[b]
+
+			switch {
+			case oka && okb:
+				return pa - pb
+			case oka:
+				return -1
+			case okb:
+				return 1
+			}
+
+			return 0
+		})
+
+		break
+	}
+
+	return grid
+}
+
+// finishSession records the results in the session YAML and cools down
+func (g *Generator) finishSession() {
+	g.place()
+	g.setState(irsdk.StateCoolDown)
+
+	var results []iryaml.ResultsPosition
+
+	for _, c := range g.cars {
+		if c.Position == 0 {
+			continue
+		}
+
+		reason, reasonStr := reasonRunning, "Running"
+		if c.Out {
+			reason, reasonStr = reasonDisconnected, "Disconnected"
+		}
+
+		results = append(results, iryaml.ResultsPosition{
+			Position:      c.Position,
+			ClassPosition: c.ClassPosition - 1, // zero based in iRacing's YAML
+			CarIdx:        c.CarIdx,
+			Lap:           c.bestLapNum,
+			Time:          c.total,
+			FastestLap:    c.bestLapNum,
+			FastestTime:   c.BestLap.Seconds(),
+			LastTime:      c.LastLap.Seconds(),
+			LapsComplete:  c.LapsCompleted,
+			LapsDriven:    float64(c.LapsCompleted) + c.Pct,
+			Incidents:     c.Incidents,
+			ReasonOutID:   reason,
+			ReasonOutStr:  reasonStr,
+		})
+	}
+
+	slices.SortFunc(results, func(a, b iryaml.ResultsPosition) int { return a.Position - b.Position })
+	g.results[g.session] = results
+
+	err := g.writeSession()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// publish writes the current state as the next tick, unless the simulator is in an outage
+//
+//nolint:funlen // one line per variable
+func (g *Generator) publish() error {
+	if g.outage() {
+		if g.connected {
+			g.emu.Disconnect()
+			g.connected = false
+		}
+
+		return nil
+	}
+
+	if !g.connected {
+		g.emu.Connect()
+		g.connected = true
+	}
+
+	session := &g.sc.sessions[g.session]
+	n := maxCars
+	lap, completed, position, classPosition, surface, class, flags := ints(n, -1), ints(n, -1), ints(n, 0), ints(n, 0), ints(n, irsdk.TrackNotInWorld), ints(n, 0), ints(n, 0)
+	pct, last, best, f2, est := floats(n, -1), floats(n, -1), floats(n, -1), floats(n, 0), floats(n, 0)
+	onPit := make([]bool, n)
+
+	leader, fastest := g.leader(), g.fastest()
+	offTrack := false
+
+	for _, c := range g.cars {
+		i := c.CarIdx
+		lap[i], completed[i], position[i], classPosition[i] = c.Lap, c.LapsCompleted, c.Position, c.ClassPosition
+		surface[i], class[i], onPit[i] = c.Surface, c.ClassID, c.OnPitRoad
+		flags[i] = irsdk.FlagServicible
+
+		if c.Out {
+			pct[i] = -1
+			continue
+		}
+
+		pct[i], est[i] = c.Pct, c.Pct*c.target
+
+		if c.LastLap > 0 {
+			last[i], best[i] = c.LastLap.Seconds(), c.BestLap.Seconds()
+		}
+
+		switch {
+		case session.Type == Race && leader != nil:
+			f2[i] = (distance(leader) - distance(c)) * c.class.LapTime.Seconds()
+		case session.Type != Race && c.BestLap > 0:
+			f2[i] = (c.BestLap - fastest).Seconds()
+		}
+
+		if c.Finished {
+			flags[i] |= irsdk.FlagCheckered
+		}
+
+		offTrack = offTrack || c.Surface == irsdk.TrackOffTrack
+	}
+
+	lapsRemain, timeRemain := 32767, 0.0 //nolint:mnd // iRacing's unlimited laps
+	if session.Laps > 0 {
+		lapsRemain = session.Laps
+		if leader != nil {
+			lapsRemain = max(0, session.Laps-leader.LapsCompleted)
+		}
+	} else if g.state >= irsdk.StateRacing {
+		timeRemain = max(0, session.Time.Seconds()-(g.time-g.greenAt))
+	}
+
+	weather := g.weather()
+
+	values := []struct {
+		name  string
+		value any
+	}{
+		{"SessionNum", g.session},
+		{"SessionState", g.state},
+		{"SessionTime", g.time},
+		{"SessionTick", g.tick + 1},
+		{"SessionTimeRemain", timeRemain},
+		{"SessionLapsRemainEx", lapsRemain},
+		{"SessionFlags", g.sessionFlags(offTrack, lapsRemain)},
+		{"PlayerCarIdx", g.sc.player},
+		{"CarIdxLap", lap},
+		{"CarIdxLapCompleted", completed},
+		{"CarIdxLapDistPct", pct},
+		{"CarIdxPosition", position},
+		{"CarIdxClassPosition", classPosition},
+		{"CarIdxOnPitRoad", onPit},
+		{"CarIdxTrackSurface", surface},
+		{"CarIdxLastLapTime", last},
+		{"CarIdxBestLapTime", best},
+		{"CarIdxF2Time", f2},
+		{"CarIdxEstTime", est},
+		{"CarIdxClass", class},
+		{"CarIdxSessionFlags", flags},
+		{"AirTemp", weather.AirTemp},
+		{"TrackTemp", weather.TrackTemp},
+		{"TrackTempCrew", weather.TrackTemp},
+		{"Precipitation", weather.Precipitation},
+		{"TrackWetness", weather.Wetness},
+		{"Skies", weather.Skies},
+		{"WeatherDeclaredWet", weather.DeclaredWet},
+		{"WindVel", weather.WindVel},
+		{"WindDir", weather.WindDir},
+	}
+
+	for _, v := range values {
+		err := g.emu.Set(v.name, v.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	g.tick = g.emu.Tick()
+
+	return nil
+}
+
+func (g *Generator) sessionFlags(offTrack bool, lapsRemain int) int {
+	switch g.state {
+	case irsdk.StateGetInCar, irsdk.StateWarmup:
+		return irsdk.FlagStartHidden
+	case irsdk.StateParadeLaps:
+		return irsdk.FlagStartReady
+	case irsdk.StateCheckered, irsdk.StateCoolDown:
+		return irsdk.FlagCheckered
+	}
+
+	flags := 0
+
+	if g.time-g.greenAt < greenTime {
+		flags |= irsdk.FlagGreen | irsdk.FlagStartGo
+	}
+
+	if lapsRemain == 1 {
+		flags |= irsdk.FlagWhite
+	}
+
+	if offTrack {
+		flags |= irsdk.FlagYellow
+	}
+
+	return flags
+}
+
+func (g *Generator) outage() bool {
+	for _, o := range g.sc.outages {
+		if o.Session == g.session && g.time >= o.At.Seconds() && g.time < (o.At+o.For).Seconds() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// weather interpolates the weather points of the current session, carrying on from the last session without any
+func (g *Generator) weather() Weather {
+	current := Weather{AirTemp: 25, TrackTemp: 35, WindVel: 2, Wetness: 1} //nolint:mnd // a pleasant dry day
+
+	var next *Weather
+
+	for i := range g.sc.weather {
+		w := &g.sc.weather[i]
+
+		switch {
+		case w.Session < g.session || (w.Session == g.session && w.At.Seconds() <= g.time):
+			current = *w
+		case w.Session == g.session && next == nil:
+			next = w
+		}
+	}
+
+	if next == nil || current.Session != g.session {
+		return current
+	}
+
+	span := next.At.Seconds() - current.At.Seconds()
+	f := (g.time - current.At.Seconds()) / span
+
+	current.AirTemp += (next.AirTemp - current.AirTemp) * f
+	current.TrackTemp += (next.TrackTemp - current.TrackTemp) * f
+	current.Precipitation += (next.Precipitation - current.Precipitation) * f
+	current.WindVel += (next.WindVel - current.WindVel) * f
+
+	return current
+}
+
+// leader is the car in first place
+func (g *Generator) leader() *car {
+	for _, c := range g.cars {
+		if c.Position == 1 {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// fastest is the best lap of the session
+func (g *Generator) fastest() time.Duration {
+	var fastest time.Duration
+
+	for _, c := range g.cars {
+		if c.BestLap > 0 && (fastest == 0 || c.BestLap < fastest) {
+			fastest = c.BestLap
+		}
+	}
+
+	return fastest
+}
+
+func (g *Generator) car(carIdx int) *car {
+	for _, c := range g.cars {
+		if c.CarIdx == carIdx {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// writeSession publishes the session YAML
+func (g *Generator) writeSession() error {
+	b, err := yaml.Marshal(g.irSession())
+	if err != nil {
+		return err
+	}
+
+	return g.emu.SetSession(string(b))
+}
+
+//nolint:mnd // plausible values
+func (g *Generator) irSession() *iryaml.IRSession {
+	sc := g.sc
+
+	carIDs := map[int]bool{}
+	for _, class := range sc.classes {
+		carIDs[class.CarID] = true
+	}
+
+	weather, weatherType := g.weather(), "Static"
+	if len(sc.weather) > 1 {
+		weatherType = "Dynamic"
+	}
+
+	s := &iryaml.IRSession{
+		WeekendInfo: iryaml.WeekendInfo{
+			TrackName:        strings.ToLower(strings.ReplaceAll(sc.track.DisplayName, " ", "")),
+			TrackID:          sc.track.ID,
+			TrackLength:      fmt.Sprintf("%.2f km", sc.track.LengthKm),
+			TrackDisplayName: sc.track.DisplayName,
+			TrackConfigName:  sc.track.ConfigName,
+			SeriesID:         sc.seriesID,
+			SeasonID:         sc.seasonID,
+			SessionID:        sc.subSessionID / 2,
+			SubSessionID:     sc.subSessionID,
+			Official:         1,
+			EventType:        Race,
+			Category:         "Road",
+			SimMode:          "full",
+			NumCarClasses:    len(sc.classes),
+			NumCarTypes:      len(carIDs),
+			WeekendOptions:   iryaml.WeekendOptions{NumStarters: len(sc.drivers)},
+
+			TrackWeatherType:      weatherType,
+			TrackSkies:            []string{"Clear", "Partly Cloudy", "Mostly Cloudy", "Overcast"}[min(max(weather.Skies, 0), 3)],
+			TrackAirTemp:          fmt.Sprintf("%.2f C", weather.AirTemp),
+			TrackSurfaceTemp:      fmt.Sprintf("%.2f C", weather.TrackTemp),
+			TrackAirPressure:      "29.92 Hg",
+			TrackWindVel:          fmt.Sprintf("%.2f m/s", weather.WindVel),
+			TrackWindDir:          fmt.Sprintf("%.2f rad", weather.WindDir),
+			TrackRelativeHumidity: "55 %",
+			TrackFogLevel:         "0 %",
+		},
+		DriverInfo: iryaml.DriverInfo{
+			DriverCarIdx: sc.player,
+			PaceCarIdx:   0,
+			Drivers: []iryaml.Driver{{
+				CarIdx: 0, UserName: "Pace Car", UserID: -1, CarIsPaceCar: 1, CarClassID: paceCarClassID,
+				CarNumber: "0", CarScreenName: "Safety Car", CarClassShortName: "",
+			}},
+		},
+	}
+
+	for i := range sc.sessions {
+		session := &sc.sessions[i]
+		laps, length := "unlimited", "unlimited"
+
+		if session.Laps > 0 {
+			laps = fmt.Sprint(session.Laps)
+		} else {
+			length = fmt.Sprintf("%.4f sec", session.Time.Seconds())
+		}
+
+		ir := iryaml.Session{
+			SessionNum:       i,
+			SessionLaps:      laps,
+			SessionTime:      length,
+			SessionType:      session.Type,
+			SessionName:      session.Name,
+			ResultsPositions: g.results[i],
+		}
+
+		if len(g.results[i]) > 0 {
+			ir.ResultsLapsComplete = g.results[i][0].LapsComplete
+			ir.ResultsOfficial = 1
+		}
+
+		s.SessionInfo.Sessions = append(s.SessionInfo.Sessions, ir)
+	}
+
+	for _, c := range g.cars {
+		d := c.driver
+
+		s.DriverInfo.Drivers = append(s.DriverInfo.Drivers, iryaml.Driver{
+			CarIdx:             d.CarIdx,
+			UserName:           d.Name,
+			AbbrevName:         d.Name,
+			Initials:           initials(d.Name),
+			UserID:             d.UserID,
+			CarNumber:          fmt.Sprint(d.CarNumber),
+			CarNumberRaw:       d.CarNumber,
+			CarClassID:         d.ClassID,
+			CarID:              c.class.CarID,
+			CarScreenName:      c.class.CarName,
+			CarScreenNameShort: c.class.CarName,
+			CarClassShortName:  c.class.Name,
+			CarClassColor:      c.class.Color,
+			CarClassEstLapTime: float32(c.class.LapTime.Seconds()),
+			IRating:            d.IRating,
+			LicString:          "A 4.99",
+		})
+
+		if d.CarIdx == sc.player {
+			s.DriverInfo.DriverUserID = d.UserID
+		}
+	}
+
+	return s
+}
+
+// distance is laps covered, for gaps
+func distance(c *car) float64 {
+	return float64(c.LapsCompleted) + c.Pct
+}
+
+func initials(name string) string {
+	var result []rune
+
+	for _, word := range strings.Fields(name) {
+		result = append(result, []rune(word)[0])
+	}
+
+	return string(result)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s*1000)) * time.Millisecond //nolint:mnd // millisecond timing like iRacing
+}
+
+func ints(n int, value int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = value
+	}
+
+	return s
+}
+
+func floats(n int, value float64) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = value
+	}
+
+	return s
+}
+
+func newRand(seed int64, stream int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), uint64(stream))) //nolint:gosec // repeatable, not secure
+}
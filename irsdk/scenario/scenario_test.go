@@ -0,0 +1,173 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func weekend(seed int64) *Scenario {
+	return New(seed).
+		Track(168, "Suzuka International Racing Course", "Grand Prix", 5.807).
+		Class(Class{ID: 84, Name: "GT3", LapTime: 20 * time.Second, Variance: 200 * time.Millisecond, Color: "0xffda59"}).
+		Class(Class{ID: 83, Name: "GT4", LapTime: 22 * time.Second, Variance: 300 * time.Millisecond}).
+		Field(84, 4).
+		Field(83, 3).
+		Qualify(45*time.Second).
+		Race(4).
+		Pit(2, 2, 5*time.Second).
+		OffTrack(3, 2, 0.5, 3*time.Second).
+		Disconnect(7, 2, 0.25).
+		Outage(30*time.Second, 2*time.Second).
+		Weather(Weather{AirTemp: 20, TrackTemp: 30, Wetness: 1}).
+		Weather(Weather{At: 60 * time.Second, AirTemp: 22, TrackTemp: 40, Wetness: 2, Skies: 2})
+}
+
+func TestScenario(t *testing.T) {
+	t.Run("Weekend should run through the SDK", func(t *testing.T) {
+		gen, err := weekend(42).Start()
+		require.NoError(t, err)
+
+		sdk := irsdk.NewIrSDK(gen)
+		defer sdk.Close()
+
+		session := sdk.GetSession()
+		assert.Equal(t, "Suzuka International Racing Course", session.WeekendInfo.TrackDisplayName)
+		assert.Equal(t, "5.81 km", session.WeekendInfo.TrackLength)
+		assert.Len(t, session.SessionInfo.Sessions, 2)
+		assert.Equal(t, "4", session.SessionInfo.Sessions[1].SessionLaps)
+		assert.Len(t, session.DriverInfo.Drivers, 8)
+		assert.Equal(t, 1, session.DriverInfo.Drivers[0].CarIsPaceCar)
+
+		var (
+			states     = map[int]bool{}
+			sessions   = map[int]bool{}
+			pitted     bool
+			offTrack   bool
+			yellow     bool
+			gone       bool
+			outage     bool
+			airTemp    float32
+			lastTick   int
+			ticks      int
+			lastState  int
+			sessionNum int
+		)
+
+	loop:
+		for {
+			select {
+			case <-gen.Done():
+				break loop
+			default:
+			}
+
+			if !sdk.WaitForData(time.Millisecond) {
+				outage = outage || !sdk.IsConnected()
+				continue
+			}
+
+			ticks++
+
+			v, _ := sdk.GetVarValue("SessionNum")
+			sessionNum = v.(int)
+			sessions[sessionNum] = true
+
+			v, _ = sdk.GetVarValue("SessionState")
+			lastState = v.(int)
+			states[lastState] = true
+
+			v, _ = sdk.GetVarValue("SessionTick")
+			assert.Greater(t, v.(int), lastTick)
+			lastTick = v.(int)
+
+			v, _ = sdk.GetVarValue("SessionFlags")
+			yellow = yellow || v.(int)&irsdk.FlagYellow != 0
+
+			surfaces, _ := sdk.GetVarValues("CarIdxTrackSurface")
+			onPit, _ := sdk.GetVarValues("CarIdxOnPitRoad")
+
+			if sessionNum == 1 && lastState == irsdk.StateRacing {
+				pitted = pitted || (onPit.([]bool)[2] && surfaces.([]int)[2] == irsdk.TrackInPitStall)
+				offTrack = offTrack || surfaces.([]int)[3] == irsdk.TrackOffTrack
+				gone = gone || surfaces.([]int)[7] == irsdk.TrackNotInWorld
+			}
+
+			v, _ = sdk.GetVarValue("AirTemp")
+			airTemp = v.(float32)
+		}
+
+		assert.Greater(t, ticks, 1000)
+		assert.Equal(t, map[int]bool{0: true, 1: true}, sessions)
+
+		for _, state := range []int{irsdk.StateGetInCar, irsdk.StateWarmup, irsdk.StateParadeLaps, irsdk.StateRacing,
+			irsdk.StateCheckered, irsdk.StateCoolDown} {
+			assert.True(t, states[state], "state %d", state)
+		}
+
+		assert.True(t, pitted, "pitted")
+		assert.True(t, offTrack, "off track")
+		assert.True(t, yellow, "yellow")
+		assert.True(t, gone, "disconnected")
+		assert.True(t, outage, "outage")
+		assert.InDelta(t, 22, airTemp, 0.01)
+
+		qualifying := gen.Results(0)
+		require.Len(t, qualifying, 7)
+		assert.Positive(t, qualifying[0].FastestTime)
+
+		race := sdk.GetSession().SessionInfo.Sessions[1].ResultsPositions
+		require.Len(t, race, 7)
+		assert.Equal(t, 4, race[0].LapsComplete)
+		assert.Equal(t, "Disconnected", race[6].ReasonOutStr)
+		assert.Equal(t, 7, race[6].CarIdx)
+
+		snapshot := gen.Snapshot()
+		assert.Equal(t, 1, snapshot.SessionNum)
+		assert.Equal(t, irsdk.StateCoolDown, snapshot.State)
+		assert.Zero(t, snapshot.Cars[1].Incidents, "pits are not incidents")
+		assert.Equal(t, 1, snapshot.Cars[2].Incidents)
+	})
+
+	t.Run("Same seed should give the same race", func(t *testing.T) {
+		run := func(seed int64) []Car {
+			gen, err := weekend(seed).Start()
+			require.NoError(t, err)
+
+			for gen.Step() {
+			}
+
+			return gen.Snapshot().Cars
+		}
+
+		assert.Equal(t, run(7), run(7))
+		assert.NotEqual(t, run(7), run(8))
+	})
+
+	t.Run("Invalid scenarios should be rejected", func(t *testing.T) {
+		_, err := New(1).Start()
+		assert.ErrorContains(t, err, "no sessions")
+		assert.ErrorContains(t, err, "no drivers")
+
+		_, err = New(1).
+			TickRate(0).
+			Pit(1, 1, time.Second).
+			Class(Class{ID: 1}).
+			Driver(Driver{CarIdx: 64, ClassID: 2}).
+			Driver(Driver{CarIdx: 64, ClassID: 1}).
+			Race(0).
+			OffTrack(9, 1, 0.5, time.Second).
+			Start()
+		assert.ErrorContains(t, err, "tick rate")
+		assert.ErrorContains(t, err, "before its events")
+		assert.ErrorContains(t, err, "needs a lap time")
+		assert.ErrorContains(t, err, "is not 1 to 63")
+		assert.ErrorContains(t, err, "used twice")
+		assert.ErrorContains(t, err, "unknown class 2")
+		assert.ErrorContains(t, err, "needs laps or a time")
+		assert.ErrorContains(t, err, "off track for unknown car 9")
+	})
+}
@@ -16,6 +16,11 @@ func byte4ToInt(in []byte) int {
 	return int(unsigned)
 }
 
+// byte4ToBits keeps every bit of an irsdk bit field, such as FlagStartGo which does not fit in a C int
+func byte4ToBits(in []byte) int {
+	return int(binary.LittleEndian.Uint32(in))
+}
+
 func byte4ToFloat(in []byte) float32 {
 	bits := binary.LittleEndian.Uint32(in)
 	return math.Float32frombits(bits)
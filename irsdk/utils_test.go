@@ -12,4 +12,9 @@ func TestUtils(t *testing.T) {
 		assert.Equal(t, 4, byte4ToInt([]byte{4, 0, 0, 0}))
 		assert.Equal(t, -1, byte4ToInt([]byte{255, 255, 255, 255}))
 	})
+
+	t.Run("bit field conversions", func(t *testing.T) {
+		assert.Equal(t, FlagGreen, byte4ToBits([]byte{4, 0, 0, 0}))
+		assert.Equal(t, FlagStartGo|FlagGreen, byte4ToBits([]byte{4, 0, 0, 0x80}))
+	})
 }
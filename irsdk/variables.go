@@ -13,6 +13,11 @@ type VarBuffer struct {
 	bufOffset int // offset from header
 }
 
+// Offset of the buffer from the start of the shared memory
+func (vb VarBuffer) Offset() int {
+	return vb.bufOffset
+}
+
 type VarType int
 
 const (
@@ -24,6 +29,25 @@ const (
 	VarTypeDouble   VarType = 5
 )
 
+func (t VarType) String() string {
+	switch t {
+	case VarTypeChar:
+		return "char"
+	case VarTypeBool:
+		return "bool"
+	case VarTypeInt:
+		return "int"
+	case VarTypeBitField:
+		return "bitfield"
+	case VarTypeFloat:
+		return "float"
+	case VarTypeDouble:
+		return "double"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+}
+
 type Variable struct {
 	VarType     VarType // irsdk_VarType
 	offset      int     // offset fron start of buffer row
@@ -48,7 +72,7 @@ func (v Variable) String() string {
 	case VarTypeInt:
 		ret = fmt.Sprintf("%d", v.Value)
 	case VarTypeBitField:
-		ret = fmt.Sprintf("%s", v.Value)
+		ret = fmt.Sprintf("0x%08x", v.Value)
 	case VarTypeFloat:
 		ret = fmt.Sprintf("%f", v.Value)
 	case VarTypeDouble:
@@ -68,9 +92,12 @@ type TelemetryVars struct {
 }
 
 func findLatestBuffer(r reader, h *header) VarBuffer {
-	var vb VarBuffer
+	return latest(readBuffers(r, h))
+}
 
-	foundTickCount := 0
+// readBuffers reads the headers of the rotating variable buffers
+func readBuffers(r reader, h *header) []VarBuffer {
+	buffers := make([]VarBuffer, h.numBuf)
 
 	for i := 0; i < h.numBuf; i++ {
 		rbuf := make([]byte, 16)
@@ -80,19 +107,25 @@ func findLatestBuffer(r reader, h *header) VarBuffer {
 			log.Fatal(err)
 		}
 
-		currentVb := VarBuffer{
+		buffers[i] = VarBuffer{
 			byte4ToInt(rbuf[0:4]),
 			byte4ToInt(rbuf[4:8]),
 		}
+	}
+
+	return buffers
+}
+
+// latest returns the buffer with the highest tick count
+func latest(buffers []VarBuffer) VarBuffer {
+	var vb VarBuffer
 
-		// fmt.Printf("BUFF?: %+v\n", currentVb)
-		if foundTickCount < currentVb.TickCount {
-			foundTickCount = currentVb.TickCount
+	for _, currentVb := range buffers {
+		if vb.TickCount < currentVb.TickCount {
 			vb = currentVb
 		}
 	}
 
-	// fmt.Printf("BUFF: %+v\n", vb)
 	return vb
 }
 
@@ -138,7 +171,8 @@ func readVariableValues(sdk *IRSDK) bool {
 		if sdk.tVars.lastVersion < vb.TickCount {
 			newData = true
 			sdk.tVars.lastVersion = vb.TickCount
-			sdk.lastValidData = time.Now().Unix()
+			start := time.Now()
+			sdk.lastValidData = start.Unix()
 
 			for varName, v := range sdk.tVars.vars {
 				var rbuf []byte
@@ -203,7 +237,7 @@ func readVariableValues(sdk *IRSDK) bool {
 							log.Fatal(err)
 						}
 
-						values[i] = byte4ToInt(rbuf)
+						values[i] = byte4ToBits(rbuf)
 					}
 
 					v.Value = values[0]
@@ -241,12 +275,15 @@ func readVariableValues(sdk *IRSDK) bool {
 					v.Value = values[0]
 					v.Values = values
 				default:
-					log.Printf("unknown var type: %d", v.VarType)
+					logger.Warn("Unknown variable type", "name", varName, "type", v.VarType)
 				}
 
 				v.RawBytes = rbuf
 				sdk.tVars.vars[varName] = v
 			}
+
+			ticksRead.Inc()
+			decodeSeconds.Observe(time.Since(start).Seconds())
 		}
 		sdk.tVars.mux.Unlock()
 	}
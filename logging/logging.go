@@ -0,0 +1,96 @@
+// Package logging sets up structured logging with log/slog. Each package logs through a Component logger so every
+// line says where it came from, e.g. component=telemetry.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Levels lists the accepted log levels
+func Levels() []string {
+	return []string{"debug", "info", "warn", "error"}
+}
+
+// Formats lists the accepted log formats
+func Formats() []string {
+	return []string{FormatText, FormatJSON}
+}
+
+// Setup makes the default logger write to w at level, e.g. info, as text or json. The standard log package
+// is sent through it too.
+func Setup(w io.Writer, level string, format string) error {
+	var l slog.Level
+
+	if level != "" {
+		err := l.UnmarshalText([]byte(level))
+		if err != nil {
+			return fmt.Errorf("unknown log level %q, expected one of %s", level, strings.Join(Levels(), ", "))
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: l}
+
+	switch format {
+	case FormatText, "":
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, options)))
+	case FormatJSON:
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, options)))
+	default:
+		return fmt.Errorf("unknown log format %q, expected one of %s", format, strings.Join(Formats(), ", "))
+	}
+
+	return nil
+}
+
+// Component returns a logger adding component=name to every record. It follows the default logger, so loggers made
+// in package variables before Setup still go where Setup says.
+func Component(name string) *slog.Logger {
+	return slog.New(&componentHandler{attrs: []slog.Attr{slog.String("component", name)}})
+}
+
+// componentHandler passes records to whatever the default handler is at the time
+type componentHandler struct {
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *componentHandler) handler() slog.Handler {
+	handler := slog.Default().Handler().WithAttrs(h.attrs)
+	for _, group := range h.groups {
+		handler = handler.WithGroup(group)
+	}
+
+	return handler
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler().Handle(ctx, record)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(h.groups) > 0 {
+		// Attributes after a group belong to it, which only the real handler can track, so this one stops following
+		// the default
+		return h.handler().WithAttrs(attrs)
+	}
+
+	return &componentHandler{attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return &componentHandler{attrs: h.attrs, groups: append(h.groups[:len(h.groups):len(h.groups)], name)}
+}
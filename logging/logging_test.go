@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogging(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	// Made before Setup, like a package variable
+	logger := Component("telemetry")
+
+	t.Run("Component loggers should follow Setup", func(t *testing.T) {
+		var b bytes.Buffer
+
+		require.NoError(t, Setup(&b, "warn", FormatJSON))
+
+		logger.Info("Hidden")
+		logger.Warn("Lost telemetry", "tick", 42)
+		logger.WithGroup("sink").With("name", "mqtt").Error("Can not send")
+
+		lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+
+		var record map[string]any
+
+		require.NoError(t, json.Unmarshal(lines[0], &record))
+		assert.Equal(t, "telemetry", record["component"])
+		assert.Equal(t, "Lost telemetry", record["msg"])
+		assert.InDelta(t, 42, record["tick"], 0)
+
+		require.NoError(t, json.Unmarshal(lines[1], &record))
+		assert.Equal(t, map[string]any{"name": "mqtt"}, record["sink"])
+	})
+
+	t.Run("Text should be the default format", func(t *testing.T) {
+		var b bytes.Buffer
+
+		require.NoError(t, Setup(&b, "", ""))
+		logger.Info("Serving")
+
+		assert.Contains(t, b.String(), "level=INFO msg=Serving component=telemetry")
+	})
+
+	t.Run("Unknown settings should be rejected", func(t *testing.T) {
+		assert.ErrorContains(t, Setup(&bytes.Buffer{}, "verbose", FormatText), "unknown log level")
+		assert.ErrorContains(t, Setup(&bytes.Buffer{}, "info", "xml"), "unknown log format")
+	})
+}
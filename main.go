@@ -1,79 +1,101 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/ianhaycox/vcrlive/connectors/api"
-	"github.com/ianhaycox/vcrlive/connectors/telemetry"
-	"github.com/ianhaycox/vcrlive/connectors/vcrstandings"
-	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/config"
 )
 
-const (
-	defaultWaitMilliseconds = 100
-	defaultRefreshSeconds   = 10
-)
+// command is a vcrlive subcommand
+type command struct {
+	name  string
+	args  string
+	brief string
+	run   func(args []string) error
+}
 
 var (
-	progName         = filepath.Base(os.Args[0])
-	ibtFile          string
-	waitMilliseconds int
-	refreshSeconds   int
-	redact           bool
+	progName = filepath.Base(os.Args[0])
+	commands []command
 )
 
+func init() {
+	commands = []command{
+		{"run", "[flags] [url]", "Post live positions, the default command", func(args []string) error { return feed("run", args) }},
+		{"serve", "[flags] [url]", "Run across sessions and serve positions locally for overlays", func(args []string) error { return feed("serve", args) }},
+		{"replay", "[flags] FILE [url]", "Post positions from a recording", func(args []string) error { return feed("replay", args) }},
+		{"record", "[flags] FILE", "Record the simulator's telemetry to replay or send for diagnosis", record},
+		{"vars", "[flags]", "List the telemetry variables", vars},
+		{"watch", "[flags] VAR...", "Print telemetry variables as they change", watch},
+		{"session", "[flags] [PATH]", "Print the session YAML, or one value, e.g. WeekendInfo:TrackName:", session},
+		{"inspect", "[FILE]", "Describe the shared memory of the simulator, a snapshot or a recording", inspect},
+		{"config", "validate [flags]", "Check the configuration file", configCommand},
+		{"schema", "[KIND]", "Print the JSON Schema of the payloads, or of one kind, positions or event", schema},
+	}
+}
+
 func main() {
-	flag.StringVar(&ibtFile, "file", "", "Test data, e.g. race.bin")
-	flag.IntVar(&waitMilliseconds, "wait", defaultWaitMilliseconds, "Delay in milliseconds to wait for iRacing data")
-	flag.IntVar(&refreshSeconds, "refresh", defaultRefreshSeconds, "Refresh positions every n seconds")
-	flag.BoolVar(&redact, "redact", false, "Obfuscate driver names for testing")
-	flag.Usage = usage
-	flag.Parse()
-
-	client := vcrstandings.NewVcrStandingsService(nil, nil)
-
-	args := flag.Args()
-	if len(args) > 0 {
-		client = vcrstandings.NewVcrStandingsService(api.NewAPIClient(api.NewConfiguration(args[0])), nil)
+	name, args := "run", os.Args[1:]
+
+	// Without a command vcrlive [flags] [url] behaves as it always has
+	if len(args) > 0 && lookup(args[0]) != nil {
+		name, args = args[0], args[1:]
 	}
 
-	var sdk *irsdk.IRSDK
+	err := lookup(name).run(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
 
-	if ibtFile == "" {
-		sdk = irsdk.NewIrSDK(nil)
-	} else {
-		reader, err := os.Open(ibtFile) //nolint:gosec // for testing
-		if err != nil {
-			log.Fatal(err)
+func lookup(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
 		}
+	}
 
-		log.Println("Init irSDK Linux(other)")
+	return nil
+}
 
-		sdk = irsdk.NewIrSDK(reader)
-	}
+// newFlags creates the flag set for a command with usage listing its flags
+func newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	cmd := lookup(name)
 
-	defer sdk.Close()
+	flags.Usage = func() {
+		w := flags.Output()
 
-	telemetry := telemetry.NewTelemetry(sdk, client, redact)
-	ctx := context.Background()
+		if name == "run" {
+			printCommands()
+		}
 
-	// Keep sending telemetry data until the simulator session ends
-	err := telemetry.Run(ctx, waitMilliseconds, refreshSeconds)
-	if err != nil {
-		log.Println(err)
+		_, _ = fmt.Fprintf(w, "\nUsage: %s %s %s\n  %s\n\n", progName, cmd.name, cmd.args, cmd.brief)
+
+		flags.PrintDefaults()
+
+		if name == "run" || name == "serve" || name == "replay" {
+			_, _ = fmt.Fprintf(w, "\nEnvironment variables override the configuration file: %s\n", strings.Join(config.EnvNames(), ", "))
+		}
+
+		os.Exit(0)
 	}
+
+	return flags
 }
 
-func usage() {
+func printCommands() {
 	w := flag.CommandLine.Output()
-	_, _ = fmt.Fprintf(w, "Usage of %s: [flags] [url]\n", progName)
+	_, _ = fmt.Fprintf(w, "Usage: %s [command] [flags] [args]\n\nCommands:\n", progName)
 
-	flag.PrintDefaults()
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-28s %s\n", cmd.name+" "+cmd.args, cmd.brief)
+	}
 
-	os.Exit(0)
+	_, _ = fmt.Fprintf(w, "\nRun \"%s COMMAND -h\" for the command's flags.\n", progName)
 }
@@ -0,0 +1,265 @@
+// Package metrics counts what the telemetry pipeline is doing and exposes it in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets suit latencies from tens of microseconds to a second
+var DefaultBuckets = []float64{0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.1, 1}
+
+// Default is the registry the New functions add to and Handler serves
+var Default = NewRegistry()
+
+// Registry holds metric families by name
+type Registry struct {
+	mux      sync.Mutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// family is a metric and all its label values
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	mux     sync.Mutex
+	series  map[string]*series
+}
+
+// series is one combination of label values
+type series struct {
+	labelValues []string
+	value       float64  // counter or gauge
+	counts      []uint64 // histogram, per bucket
+	count       uint64
+	sum         float64
+}
+
+// Counter only goes up, e.g. ticks read
+type Counter struct{ f *family }
+
+// Gauge goes up and down, e.g. queue depth
+type Gauge struct{ f *family }
+
+// Histogram counts observations in buckets, e.g. latency in seconds
+type Histogram struct{ f *family }
+
+// NewCounter adds a counter to the Default registry
+func NewCounter(name string, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewGauge adds a gauge to the Default registry
+func NewGauge(name string, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// NewHistogram adds a histogram to the Default registry
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, typeCounter, nil, labels)}
+}
+
+func (r *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, typeGauge, nil, labels)}
+}
+
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.register(name, help, typeHistogram, slices.Sorted(slices.Values(buckets)), labels)}
+}
+
+// register panics on a duplicate name as that is a programming error found at start up
+func (r *Registry) register(name string, help string, typ string, buckets []float64, labels []string) *family {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.families[name]; ok {
+		panic("metric " + name + " registered twice")
+	}
+
+	f := &family{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.families[name] = f
+
+	return f
+}
+
+// Inc adds one for the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Set replaces the value for the label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Add changes the value by v
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.update(labelValues, func(s *series) {
+		for i, upper := range h.f.buckets {
+			if v <= upper {
+				s.counts[i]++
+			}
+		}
+
+		s.count++
+		s.sum += v
+	})
+}
+
+func (f *family) update(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s has labels %v, got values %v", f.name, f.labels, labelValues))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+
+	fn(s)
+}
+
+// Handler serves the Default registry
+func Handler() http.Handler {
+	return Default
+}
+
+// ServeHTTP implements http.Handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Write(w)
+}
+
+// Write writes every metric in the Prometheus text exposition format, sorted by name and labels
+func (r *Registry) Write(w io.Writer) error {
+	r.mux.Lock()
+	families := make([]*family, 0, len(r.families))
+
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mux.Unlock()
+
+	slices.SortFunc(families, func(a, b *family) int { return strings.Compare(a.name, b.name) })
+
+	bw := bufio.NewWriter(w)
+
+	for _, f := range families {
+		f.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escape(f.help, false), f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+
+		if f.typ != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelPairs(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+
+		for i, upper := range f.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s.labelValues, formatFloat(upper)), s.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelPairs(s.labelValues, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelPairs(s.labelValues, ""), s.count)
+	}
+}
+
+// labelPairs formats {name="value",...}, adding le for a histogram bucket
+func (f *family) labelPairs(labelValues []string, le string) string {
+	pairs := make([]string, 0, len(f.labels)+1)
+
+	for i, name := range f.labels {
+		pairs = append(pairs, name+`="`+escape(labelValues[i], true)+`"`)
+	}
+
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+
+	return s
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Should write the Prometheus text format", func(t *testing.T) {
+		r := NewRegistry()
+
+		sent := r.NewCounter("test_posts_sent_total", "Messages sent", "sink")
+		depth := r.NewGauge("test_outbox_depth", "Queued messages")
+		latency := r.NewHistogram("test_decode_seconds", "Decode time", []float64{0.01, 0.001})
+
+		sent.Inc("mqtt:broker")
+		sent.Add(2, `http:"quoted"`)
+		depth.Set(3)
+		depth.Add(-1)
+		latency.Observe(0.0005)
+		latency.Observe(0.005)
+		latency.Observe(2)
+
+		var b bytes.Buffer
+
+		require.NoError(t, r.Write(&b))
+		assert.Equal(t, `# HELP test_decode_seconds Decode time
+# TYPE test_decode_seconds histogram
+test_decode_seconds_bucket{le="0.001"} 1
+test_decode_seconds_bucket{le="0.01"} 2
+test_decode_seconds_bucket{le="+Inf"} 3
+test_decode_seconds_sum 2.0055
+test_decode_seconds_count 3
+# HELP test_outbox_depth Queued messages
+# TYPE test_outbox_depth gauge
+test_outbox_depth 2
+# HELP test_posts_sent_total Messages sent
+# TYPE test_posts_sent_total counter
+test_posts_sent_total{sink="http:\"quoted\""} 2
+test_posts_sent_total{sink="mqtt:broker"} 1
+`, b.String())
+	})
+
+	t.Run("Should serve over HTTP", func(t *testing.T) {
+		r := NewRegistry()
+		r.NewCounter("test_ticks_total", "Ticks").Inc()
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, w.Body.String(), "test_ticks_total 1\n")
+	})
+
+	t.Run("Mistakes should panic", func(t *testing.T) {
+		r := NewRegistry()
+		c := r.NewCounter("test_total", "Test", "sink")
+
+		assert.Panics(t, func() { r.NewGauge("test_total", "Again") })
+		assert.Panics(t, func() { c.Inc() })
+	})
+}
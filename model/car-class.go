@@ -0,0 +1,86 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+)
+
+// CarClass describes a class of the session, so overlays can show multiclass races by class
+type CarClass struct {
+	CarClassID   int     `json:"car_class_id"`
+	Name         string  `json:"name"`
+	ShortName    string  `json:"short_name"`
+	Color        string  `json:"color"`          // #rrggbb, empty if not set
+	RelSpeed     int     `json:"rel_speed"`      // higher is faster
+	EstLapTime   float64 `json:"est_lap_time"`   // seconds
+	Cars         int     `json:"cars"`           // drivers in the class
+	LeaderCarIdx int     `json:"leader_car_idx"` // -1 until placed
+	BestLapTime  float64 `json:"best_lap_time"`  // seconds, 0 without a timed lap
+}
+
+// NewCarClasses returns the classes of drivers, fastest first, described by the first of their cars in the session
+func NewCarClasses(irDrivers []iryaml.Driver, drivers Drivers) []CarClass {
+	classes := map[int]*CarClass{}
+
+	for i := range irDrivers {
+		ir := &irDrivers[i]
+
+		driver, ok := drivers[ir.CarIdx]
+		if !ok || driver.CarClassID != ir.CarClassID {
+			continue
+		}
+
+		class, ok := classes[ir.CarClassID]
+		if !ok {
+			class = &CarClass{
+				CarClassID:   ir.CarClassID,
+				Name:         cmp.Or(ir.CarClassShortName, ir.CarScreenName),
+				ShortName:    cmp.Or(ir.CarClassShortName, ir.CarScreenNameShort),
+				Color:        color(ir.CarClassColor),
+				RelSpeed:     ir.CarClassRelSpeed,
+				EstLapTime:   round(float64(ir.CarClassEstLapTime), lapTimePrecision),
+				LeaderCarIdx: -1,
+			}
+			classes[ir.CarClassID] = class
+		}
+
+		class.Cars++
+
+		if driver.ClassPosition == 1 {
+			class.LeaderCarIdx = driver.CarIdx
+		}
+
+		if driver.BestLapTime > 0 && (class.BestLapTime == 0 || driver.BestLapTime < class.BestLapTime) {
+			class.BestLapTime = driver.BestLapTime
+		}
+	}
+
+	carClasses := make([]CarClass, 0, len(classes))
+	for _, class := range classes {
+		carClasses = append(carClasses, *class)
+	}
+
+	slices.SortFunc(carClasses, func(a, b CarClass) int {
+		if a.RelSpeed != b.RelSpeed {
+			return b.RelSpeed - a.RelSpeed
+		}
+
+		return a.CarClassID - b.CarClassID
+	})
+
+	return carClasses
+}
+
+// color converts iRacing's 0xrrggbb to #rrggbb
+func color(s string) string {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 32)
+	if err != nil || v > 0xffffff {
+		return ""
+	}
+
+	return "#" + strconv.FormatUint(v|0x1000000, 16)[1:]
+}
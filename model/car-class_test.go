@@ -0,0 +1,37 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCarClasses(t *testing.T) {
+	irDrivers := []iryaml.Driver{
+		{CarIdx: 0, CarClassID: 11, CarIsPaceCar: 1},
+		{CarIdx: 1, CarClassID: 84, CarClassShortName: "GT3 Class", CarClassColor: "0xffda59", CarClassRelSpeed: 60, CarClassEstLapTime: 125.4321},
+		{CarIdx: 2, CarClassID: 2523, CarClassShortName: "LMP2", CarClassColor: "0x33ceff", CarClassRelSpeed: 75},
+		{CarIdx: 3, CarClassID: 84, CarClassShortName: "GT3 Class", CarClassColor: "0xffda59", CarClassRelSpeed: 60},
+		{CarIdx: 4, CarClassID: 67, CarScreenName: "Global Mazda MX-5 Cup", CarScreenNameShort: "MX-5 Cup", CarClassColor: "0xg"},
+	}
+
+	drivers := NewDrivers(irDrivers, false)
+	drivers.SetPositions([]int{0, 2, 0, 1, 1})
+	drivers.SetTiming(nil, []float32{0, 126.5, -1, 125.9, 130})
+
+	assert.Equal(t, []CarClass{
+		{CarClassID: 2523, Name: "LMP2", ShortName: "LMP2", Color: "#33ceff", RelSpeed: 75, Cars: 1, LeaderCarIdx: -1},
+		{
+			CarClassID: 84, Name: "GT3 Class", ShortName: "GT3 Class", Color: "#ffda59", RelSpeed: 60, EstLapTime: 125.432, Cars: 2,
+			LeaderCarIdx: 3, BestLapTime: 125.9,
+		},
+		{CarClassID: 67, Name: "Global Mazda MX-5 Cup", ShortName: "MX-5 Cup", Cars: 1, LeaderCarIdx: 4, BestLapTime: 130},
+	}, NewCarClasses(irDrivers, drivers))
+}
+
+func TestColor(t *testing.T) {
+	for in, expected := range map[string]string{"0xffda59": "#ffda59", "0x0000ff": "#0000ff", "0xFF": "#0000ff", "": "", "0x1000000": ""} {
+		assert.Equal(t, expected, color(in), in)
+	}
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+)
+
+// lapTimePrecision rounds a live lap time to the millisecond, as timed by iRacing
+const lapTimePrecision = 1000
+
+// Result is how a driver placed in a race, for scoring the championship
+type Result struct {
+	UserID        int     `json:"user_id"`
+	UserName      string  `json:"user_name"`
+	CarClassID    int     `json:"car_class_id"`
+	ClassPosition int     `json:"class_position"` // 0 if not placed
+	FastestTime   float64 `json:"fastest_time"`   // seconds, 0 without a timed lap
+	Pole          bool    `json:"pole"`           // fastest qualifier in the class
+}
+
+// Round is a race of the championship, one per season and race week
+type Round struct {
+	SeasonID     int      `json:"season_id"`
+	RaceWeek     int      `json:"race_week"`
+	SubSessionID int      `json:"sub_session_id"`
+	Final        bool     `json:"-"` // the race is over
+	Results      []Result `json:"results"`
+}
+
+// Championship is the table of a season, as if the race ended now until it is final
+type Championship struct {
+	SeasonID  int        `json:"season_id"`
+	RaceWeek  int        `json:"race_week"`
+	Final     bool       `json:"final"`
+	Rounds    int        `json:"rounds"`
+	Standings []Standing `json:"standings"`
+}
+
+// Standing is a driver's place in the championship for their class
+type Standing struct {
+	UserID         int    `json:"user_id"`
+	UserName       string `json:"user_name"`
+	CarClassID     int    `json:"car_class_id"`
+	Position       int    `json:"position"`
+	PositionBefore int    `json:"position_before"`
+	Points         int    `json:"points"`
+	RacePoints     int    `json:"race_points"`
+	Dropped        int    `json:"dropped"`
+	Penalties      int    `json:"penalties"`
+}
+
+// NewRound returns the results of the race sessionNum so far. iRacing's results are used once it has published them,
+// before that the live class positions and the best lap of each car, bestLaps, indexed by car_idx.
+func NewRound(weekend Weekend, irSession *iryaml.IRSession, sessionNum int, drivers Drivers, bestLaps []float32) Round {
+	round := Round{SeasonID: weekend.SeasonID, RaceWeek: weekend.RaceWeek, SubSessionID: weekend.SubSessionID}
+	poles := poleSitters(irSession, drivers)
+
+	result := func(driver Driver) Result {
+		return Result{
+			UserID:     driver.UserID,
+			UserName:   driver.UserName,
+			CarClassID: driver.CarClassID,
+			Pole:       poles[driver.CarIdx],
+		}
+	}
+
+	for _, session := range irSession.SessionInfo.Sessions {
+		if session.SessionNum != sessionNum || len(session.ResultsPositions) == 0 {
+			continue
+		}
+
+		for _, rp := range session.ResultsPositions {
+			driver, ok := drivers[rp.CarIdx]
+			if !ok {
+				continue
+			}
+
+			r := result(driver)
+			r.ClassPosition = rp.ClassPosition + 1 // zero based in the YAML
+			r.FastestTime = max(rp.FastestTime, 0)
+			round.Results = append(round.Results, r)
+		}
+
+		return round
+	}
+
+	for _, carIdx := range drivers.carIdxs() {
+		r := result(drivers[carIdx])
+		r.ClassPosition = drivers[carIdx].ClassPosition
+
+		if carIdx < len(bestLaps) {
+			r.FastestTime = max(math.Round(float64(bestLaps[carIdx])*lapTimePrecision)/lapTimePrecision, 0)
+		}
+
+		round.Results = append(round.Results, r)
+	}
+
+	return round
+}
+
+// poleSitters returns the car_idx of the fastest qualifier in each class, from a qualifying session of the weekend or
+// the qualifying results attached to the race
+func poleSitters(irSession *iryaml.IRSession, drivers Drivers) map[int]bool {
+	poles := map[int]bool{}
+
+	for _, session := range irSession.SessionInfo.Sessions {
+		if !strings.Contains(session.SessionType, "Qualify") {
+			continue
+		}
+
+		for _, rp := range session.ResultsPositions {
+			if _, ok := drivers[rp.CarIdx]; ok && rp.ClassPosition == 0 {
+				poles[rp.CarIdx] = true
+			}
+		}
+	}
+
+	if len(poles) > 0 {
+		return poles
+	}
+
+	for _, qr := range irSession.QualifyResultsInfo.Results {
+		if _, ok := drivers[qr.CarIdx]; ok && qr.ClassPosition == 0 {
+			poles[qr.CarIdx] = true
+		}
+	}
+
+	return poles
+}
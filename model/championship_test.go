@@ -0,0 +1,47 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRound(t *testing.T) {
+	weekend := Weekend{SeasonID: 5582, RaceWeek: 3, SubSessionID: 78289018}
+	drivers := Drivers{
+		1: {CarIdx: 1, UserID: 101, UserName: "A", CarClassID: 84, ClassPosition: 2},
+		2: {CarIdx: 2, UserID: 102, UserName: "B", CarClassID: 84, ClassPosition: 1},
+		3: {CarIdx: 3, UserID: 103, UserName: "C", CarClassID: 83, ClassPosition: 1},
+	}
+
+	irSession := iryaml.IRSession{SessionInfo: iryaml.SessionInfo{Sessions: []iryaml.Session{
+		{SessionNum: 1, SessionType: "Lone Qualify", ResultsPositions: []iryaml.ResultsPosition{
+			{CarIdx: 1, ClassPosition: 0}, {CarIdx: 2, ClassPosition: 1}, {CarIdx: 3, ClassPosition: 0},
+		}},
+		{SessionNum: 2, SessionType: "Race"},
+	}}}
+
+	t.Run("A running race should use the live positions and best laps", func(t *testing.T) {
+		round := NewRound(weekend, &irSession, 2, drivers, []float32{-1, 125.5, 125.1, -1})
+
+		assert.Equal(t, Round{SeasonID: 5582, RaceWeek: 3, SubSessionID: 78289018, Results: []Result{
+			{UserID: 101, UserName: "A", CarClassID: 84, ClassPosition: 2, FastestTime: 125.5, Pole: true},
+			{UserID: 102, UserName: "B", CarClassID: 84, ClassPosition: 1, FastestTime: 125.1},
+			{UserID: 103, UserName: "C", CarClassID: 83, ClassPosition: 1, Pole: true},
+		}}, round)
+	})
+
+	t.Run("Results published by iRacing should be used", func(t *testing.T) {
+		irSession.SessionInfo.Sessions[1].ResultsPositions = []iryaml.ResultsPosition{
+			{CarIdx: 1, ClassPosition: 0, FastestTime: 125.3}, {CarIdx: 2, ClassPosition: 1, FastestTime: 125.2}, {CarIdx: 63, ClassPosition: 2},
+		}
+
+		round := NewRound(weekend, &irSession, 2, drivers, nil)
+
+		assert.Equal(t, []Result{
+			{UserID: 101, UserName: "A", CarClassID: 84, ClassPosition: 1, FastestTime: 125.3, Pole: true},
+			{UserID: 102, UserName: "B", CarClassID: 84, ClassPosition: 2, FastestTime: 125.2},
+		}, round.Results)
+	})
+}
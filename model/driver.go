@@ -1,18 +1,33 @@
 package model
 
-import "github.com/ianhaycox/vcrlive/irsdk/iryaml"
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+)
+
+// pacePrecision rounds the class pace to hundredths of a percent
+const pacePrecision = 100
 
 type Driver struct {
-	CarIdx        int    `json:"car_idx"`
-	UserName      string `json:"user_name"`
-	UserID        int    `json:"user_id"`
-	CarClassID    int    `json:"car_class_id"`
-	CarID         int    `json:"car_id"`
-	ClassPosition int    `json:"class_position"`
-	LapsCompleted int    `json:"laps_completed"`
-	IRating       int    `json:"irating"`
-	ClubID        int    `json:"club_id"`
-	CarNumberRaw  int    `json:"car_number_raw"`
+	CarIdx        int     `json:"car_idx"`
+	UserName      string  `json:"user_name"`
+	UserID        int     `json:"user_id"`
+	CarClassID    int     `json:"car_class_id"`
+	CarID         int     `json:"car_id"`
+	ClassPosition int     `json:"class_position"`
+	LapsCompleted int     `json:"laps_completed"`
+	IRating       int     `json:"irating"`
+	ClubID        int     `json:"club_id"`
+	CarNumberRaw  int     `json:"car_number_raw"`
+	IRatingChange int     `json:"irating_change,omitempty"` // projected if the race ended now
+	Position      int     `json:"position"`
+	ClassGap      float64 `json:"class_gap,omitempty"`      // seconds behind the class leader
+	ClassInterval float64 `json:"class_interval,omitempty"` // seconds behind the car ahead in class
+	BestLapTime   float64 `json:"best_lap_time,omitempty"`  // seconds
+	ClassPace     float64 `json:"class_pace,omitempty"`     // best lap as a percentage of the fastest in class
 }
 
 type Drivers map[int]Driver
@@ -58,6 +73,73 @@ func (d Drivers) SetLaps(laps []int) {
 	}
 }
 
+// SetOverallPositions sets the position of each driver across all classes, indexed by car_idx
+func (d Drivers) SetOverallPositions(positions []int) {
+	for carIdx, position := range positions {
+		if driver, ok := d[carIdx]; ok {
+			driver.Position = position
+			d[carIdx] = driver
+		}
+	}
+}
+
+// SetTiming sets the gaps within each class and the pace relative to the fastest in the class, from each car's time
+// behind the overall leader in a race or best lap otherwise, behind, and best lap, bestLaps, indexed by car_idx
+func (d Drivers) SetTiming(behind []float32, bestLaps []float32) {
+	byClass := map[int]map[int]int{} // CarClassID -> ClassPosition -> CarIdx
+	classBest := map[int]float64{}   // CarClassID -> fastest lap
+
+	for _, carIdx := range d.carIdxs() {
+		driver := d[carIdx]
+		driver.ClassGap, driver.ClassInterval, driver.BestLapTime, driver.ClassPace = 0, 0, 0, 0
+
+		if carIdx < len(bestLaps) && bestLaps[carIdx] > 0 {
+			driver.BestLapTime = round(float64(bestLaps[carIdx]), lapTimePrecision)
+
+			if best, ok := classBest[driver.CarClassID]; !ok || driver.BestLapTime < best {
+				classBest[driver.CarClassID] = driver.BestLapTime
+			}
+		}
+
+		if driver.ClassPosition > 0 {
+			if byClass[driver.CarClassID] == nil {
+				byClass[driver.CarClassID] = map[int]int{}
+			}
+
+			byClass[driver.CarClassID][driver.ClassPosition] = carIdx
+		}
+
+		d[carIdx] = driver
+	}
+
+	gap := func(carIdx int, position int, class map[int]int) float64 {
+		other, ok := class[position]
+		if !ok || carIdx >= len(behind) || other >= len(behind) || behind[carIdx] <= 0 || behind[other] < 0 {
+			return 0
+		}
+
+		return max(round(float64(behind[carIdx]-behind[other]), lapTimePrecision), 0)
+	}
+
+	for carIdx, driver := range d {
+		if driver.ClassPosition > 1 {
+			class := byClass[driver.CarClassID]
+			driver.ClassGap = gap(carIdx, 1, class)
+			driver.ClassInterval = gap(carIdx, driver.ClassPosition-1, class)
+		}
+
+		if driver.BestLapTime > 0 {
+			driver.ClassPace = round(driver.BestLapTime/classBest[driver.CarClassID]*100, pacePrecision) //nolint:mnd // percent
+		}
+
+		d[carIdx] = driver
+	}
+}
+
+func (d Drivers) carIdxs() []int {
+	return slices.Sorted(maps.Keys(d))
+}
+
 func (d Drivers) redact(s string, redact bool) string {
 	if !redact {
 		return s
@@ -78,3 +160,7 @@ func (d Drivers) redact(s string, redact bool) string {
 
 	return string(res)
 }
+
+func round(v float64, precision float64) float64 {
+	return math.Round(v*precision) / precision
+}
@@ -47,4 +47,27 @@ func TestDriver(t *testing.T) {
 
 		assert.Equal(t, expectedDrivers, d)
 	})
+
+	t.Run("Timing should be relative to each class", func(t *testing.T) {
+		d := NewDrivers([]iryaml.Driver{
+			{CarIdx: 1, CarClassID: 84},
+			{CarIdx: 2, CarClassID: 84},
+			{CarIdx: 3, CarClassID: 2523},
+			{CarIdx: 4, CarClassID: 84},
+			{CarIdx: 5, CarClassID: 84},
+		}, false)
+		d.SetPositions([]int{0, 1, 2, 1, 3, 0})
+		d.SetOverallPositions([]int{0, 2, 3, 1, 4, 0})
+		d.SetTiming([]float32{0, 10.5, 12.25, 0, 20, -1}, []float32{-1, 100.25, 100.5, 90.1, 101.255, -1})
+
+		expected := Drivers{
+			1: Driver{CarIdx: 1, CarClassID: 84, ClassPosition: 1, Position: 2, BestLapTime: 100.25, ClassPace: 100},
+			2: Driver{CarIdx: 2, CarClassID: 84, ClassPosition: 2, Position: 3, ClassGap: 1.75, ClassInterval: 1.75, BestLapTime: 100.5, ClassPace: 100.25},
+			3: Driver{CarIdx: 3, CarClassID: 2523, ClassPosition: 1, Position: 1, BestLapTime: 90.1, ClassPace: 100},
+			4: Driver{CarIdx: 4, CarClassID: 84, ClassPosition: 3, Position: 4, ClassGap: 9.5, ClassInterval: 7.75, BestLapTime: 101.255, ClassPace: 101},
+			5: Driver{CarIdx: 5, CarClassID: 84},
+		}
+
+		assert.Equal(t, expected, d)
+	})
 }
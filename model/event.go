@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Event types
+const (
+	EventSessionStart = "session_start"
+	EventSessionEnd   = "session_end"
+)
+
+// Reasons a session ended
+const (
+	EndCoolDown     = "cool_down"
+	EndNextSession  = "next_session"
+	EndDisconnected = "disconnected"
+	EndStopped      = "stopped"
+)
+
+// Event is something that happened at a point in time, posted separately from the periodic LivePositions
+type Event struct {
+	SchemaVersion int       `json:"schema_version"`
+	Type          string    `json:"type"`
+	Time          time.Time `json:"time"`
+	SubSessionID  int       `json:"sub_session_id"`
+	Session       Session   `json:"session"`
+	Reason        string    `json:"reason,omitempty"` // Why a session ended
+	Data          any       `json:"data,omitempty"`   // Type specific detail
+}
+
+func NewEvent(eventType string, now time.Time, subSessionID int, session Session) Event {
+	return Event{
+		SchemaVersion: SchemaVersion,
+		Type:          eventType,
+		Time:          now.UTC(),
+		SubSessionID:  subSessionID,
+		Session:       session,
+	}
+}
+
+func (e *Event) String() string {
+	return toJSON(e)
+}
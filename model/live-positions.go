@@ -3,13 +3,23 @@ package model
 import "encoding/json"
 
 type LivePositions struct {
-	Weekend Weekend  `json:"weekend"`
-	Session Session  `json:"session"`
-	Drivers []Driver `json:"drivers,omitempty"`
+	SchemaVersion int           `json:"schema_version"`
+	Weekend       Weekend       `json:"weekend"`
+	Session       Session       `json:"session"`
+	CarClasses    []CarClass    `json:"car_classes,omitempty"`
+	Drivers       []Driver      `json:"drivers,omitempty"`
+	Championship  *Championship `json:"championship,omitempty"`
+	Weather       *Weather      `json:"weather,omitempty"`
+	Tyres         *Tyres        `json:"tyres,omitempty"`
+	Safety        *Safety       `json:"safety,omitempty"`
 }
 
 func (l *LivePositions) String() string {
-	b, _ := json.MarshalIndent(l, "", "  ")
+	return toJSON(l)
+}
+
+func toJSON(v any) string {
+	b, _ := json.MarshalIndent(v, "", "  ")
 
 	return string(b)
 }
@@ -0,0 +1,38 @@
+package model
+
+// Race control event types, from the session flags and the flags shown to each car
+const (
+	EventGreenFlag     = "green_flag"
+	EventCaution       = "caution"
+	EventRestart       = "restart"
+	EventLocalYellow   = "local_yellow"
+	EventWhiteFlag     = "white_flag"
+	EventCheckeredFlag = "checkered_flag"
+	EventBlackFlag     = "black_flag"
+	EventMeatballFlag  = "meatball_flag"
+	EventDisqualified  = "disqualified"
+)
+
+// RaceControlTypes lists the flag events seen while a session is running
+func RaceControlTypes() []string {
+	return []string{
+		EventGreenFlag, EventCaution, EventRestart, EventLocalYellow, EventWhiteFlag, EventCheckeredFlag,
+		EventBlackFlag, EventMeatballFlag, EventDisqualified,
+	}
+}
+
+// RaceControl is the data of a race control event
+type RaceControl struct {
+	LapsCompleted int       `json:"laps_completed"`    // by the leader
+	SessionTime   float64   `json:"session_time"`      // seconds since the session started
+	Cars          []RaceCar `json:"cars,omitempty"`    // shown a flag, or off track for a local yellow
+	Caution       *Caution  `json:"caution,omitempty"` // the full course caution that has just ended
+}
+
+// Caution is a full course caution period
+type Caution struct {
+	FromLap         int     `json:"from_lap"`
+	ToLap           int     `json:"to_lap"`
+	FromSessionTime float64 `json:"from_session_time"`
+	ToSessionTime   float64 `json:"to_session_time"`
+}
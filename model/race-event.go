@@ -0,0 +1,35 @@
+package model
+
+// Race event types, detected from successive telemetry readings
+const (
+	EventOvertake        = "overtake"
+	EventLeadChange      = "lead_change"
+	EventClassLeadChange = "class_lead_change"
+	EventPitIn           = "pit_in"
+	EventPitOut          = "pit_out"
+	EventOffTrack        = "off_track"
+	EventRetired         = "retired"
+)
+
+// RaceEventTypes lists the events detected while a session is running
+func RaceEventTypes() []string {
+	return []string{EventOvertake, EventLeadChange, EventClassLeadChange, EventPitIn, EventPitOut, EventOffTrack, EventRetired}
+}
+
+// RaceEvent is the data of a race event
+type RaceEvent struct {
+	Car   RaceCar  `json:"car"`             // who overtook, took the lead, pitted, went off or retired
+	Other *RaceCar `json:"other,omitempty"` // who was overtaken or lost the lead
+}
+
+// RaceCar is a car at the moment of a race event
+type RaceCar struct {
+	CarIdx        int     `json:"car_idx"`
+	UserName      string  `json:"user_name"`
+	CarNumberRaw  int     `json:"car_number_raw"`
+	CarClassID    int     `json:"car_class_id"`
+	Position      int     `json:"position"`
+	ClassPosition int     `json:"class_position"`
+	LapsCompleted int     `json:"laps_completed"`
+	LapDistPct    float64 `json:"lap_dist_pct"`
+}
@@ -0,0 +1,8 @@
+package model
+
+// Safety is the player's incidents in the race so far against the corners driven, what iRacing bases safety rating on
+type Safety struct {
+	Incidents          int     `json:"incidents"`
+	Corners            int     `json:"corners"`                        // laps completed times the track's turns
+	CornersPerIncident float64 `json:"corners_per_incident,omitempty"` // absent without an incident
+}
@@ -0,0 +1,273 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SchemaVersion of the payloads. Bump it when a change could break a consumer - a field removed, renamed or changing
+// type or meaning - and describe the change in the README. Adding a field does not need a new version.
+const SchemaVersion = 1
+
+// Message kinds with a schema, the same as the kinds of sink messages
+const (
+	KindPositions = "positions"
+	KindEvent     = "event"
+)
+
+const (
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+	schemaIDFormat  = "https://github.com/ianhaycox/vcrlive/schema/v%d/%s.json"
+)
+
+var messages = map[string]any{
+	KindPositions: LivePositions{},
+	KindEvent:     Event{},
+}
+
+// enums documents the values a string may take, by definition and property name
+var enums = map[string][]string{
+	"session.session_state":  {"Invalid", "Get In Car", "Warmup", "Parade Laps", "Racing", "Checkered", "Cool Down", "Stopped"},
+	"event.type":             slices.Concat([]string{EventSessionStart, EventSessionEnd}, RaceEventTypes(), RaceControlTypes()),
+	"event.reason":           {EndCoolDown, EndNextSession, EndDisconnected, EndStopped},
+	"weather_change.changes": ChangeTypes(),
+	"conditions.wetness":     wetness,
+	"conditions.skies":       skies,
+}
+
+// unions lists the types an any property may hold, by definition and property name
+var unions = map[string][]any{
+	"event.data": {RaceEvent{}, RaceControl{}},
+}
+
+// optional properties that are always sent but are not required, as payloads from older versions do not have them
+var optional = []string{"live_positions.schema_version", "event.schema_version"}
+
+// descriptions of the definitions and properties a consumer can not work out from the name
+var descriptions = map[string]string{
+	"live_positions":                "Current positions, posted when something significant changes and as a heartbeat. The final post of a session has an empty weekend and no drivers.",
+	"live_positions.schema_version": "Version of this schema, absent from payloads sent before versioning.",
+	"live_positions.drivers":        "Ordered by car_idx, absent from the final post of a session.",
+	"live_positions.championship":   "Championship table in race sessions when a championship is configured.",
+	"championship":                  "Championship table of the season counting this race, as if it ended now until final is true.",
+	"championship.rounds":           "Rounds counted, including this race.",
+	"standing.position":             "Championship position within the car class.",
+	"standing.position_before":      "Championship position before this race, 0 if this is the driver's first round.",
+	"standing.points":               "Points after drop rounds and penalties.",
+	"standing.race_points":          "Points scored in this race, including bonuses.",
+	"standing.dropped":              "Points not counted from the worst rounds.",
+	"standing.penalties":            "Points deducted.",
+	"event":                         "Something that happened at a point in time, posted to the /events path.",
+	"event.schema_version":          "Version of this schema, absent from payloads sent before versioning.",
+	"event.reason":                  "Why a session ended.",
+	"event.data":                    "Detail specific to the event type, a race_event for the race event types, race_control for the flags.",
+	"race_event.other":              "The car overtaken for an overtake, the previous leader for a lead change.",
+	"race_car.position":             "Overall position, 0 until placed.",
+	"race_car.lap_dist_pct":         "Where the car is around the lap, from 0 at the start/finish line to 1.",
+	"race_control.laps_completed":   "Laps completed by the leader.",
+	"race_control.cars":             "The car shown a black, meatball or disqualify flag, or the cars off track for a local yellow.",
+	"race_control.caution":          "The full course caution that ended, for a restart or a checkered flag under caution.",
+	"caution.from_lap":              "Laps completed by the leader when the caution came out.",
+	"caution.to_lap":                "Laps completed by the leader when the caution ended.",
+	"session.error_text":            "Set when session_state is Invalid so the consumer knows about a problem.",
+	"driver.class_position":         "Position within the car class, 0 until placed.",
+	"live_positions.car_classes":    "The classes of the session, fastest first.",
+	"car_class.name":                "The class name, or the car's name for a class without one.",
+	"car_class.color":               "Colour of the class as #rrggbb, empty if iRacing has not set one.",
+	"car_class.rel_speed":           "Relative speed of the class, higher is faster.",
+	"car_class.est_lap_time":        "iRacing's estimate of a lap in seconds.",
+	"car_class.leader_car_idx":      "car_idx of the class leader, -1 until placed.",
+	"car_class.best_lap_time":       "Fastest lap in the class in seconds, 0 without a timed lap.",
+	"driver.position":               "Overall position across all classes, 0 until placed or when iRacing does not report it.",
+	"driver.class_gap":              "Seconds behind the class leader, absent for the leader or without a time.",
+	"driver.class_interval":         "Seconds behind the car ahead in class, absent for the leader or without a time.",
+	"driver.best_lap_time":          "Fastest lap in seconds, absent without a timed lap.",
+	"driver.class_pace":             "Fastest lap as a percentage of the fastest in the class, 100 for the fastest.",
+	"driver.irating_change":         "Projected iRating change if the race ended now, absent outside races.",
+	"live_positions.safety":         "The player's incidents during a race, absent for a spectator, outside races or when iRacing does not report them.",
+	"safety":                        "The player's incidents against the corners driven, what iRacing bases safety rating on. iRacing has not published how they change safety rating, so no change is projected.",
+	"safety.corners":                "Laps completed times the turns of the track.",
+	"safety.corners_per_incident":   "Corners driven for each incident point, higher is safer, absent without an incident.",
+	"weekend.weather":               "Weather set for the weekend, the start of the session when the weather is dynamic.",
+	"weekend_weather.air_pressure":  "Air pressure in hPa.",
+	"weekend_weather.wind_dir":      "Direction the wind blows from in degrees.",
+	"live_positions.weather":        "Weather now and how it changed during the session, absent when iRacing does not report it.",
+	"weather.timeline":              "The conditions each time they changed, starting with the first of the session.",
+	"conditions.session_time":       "Seconds into the session.",
+	"conditions.wind_dir":           "Direction the wind blows from in degrees.",
+	"conditions.precipitation":      "Rain as a percentage of the heaviest.",
+	"weather_change.changes":        "What changed since the previous entry, absent for the first.",
+	"live_positions.tyres":          "The player's tyres, absent for a spectator or when iRacing does not report them.",
+	"tyres":                         "The player's tyres now and each stint of the session. Values across the tread are inner, middle and outer.",
+	"tyres.stints":                  "Stints by corner, then by set of tyres.",
+	"tyre_corner.corner":            "One of lf, rf, lr or rr.",
+	"tyre_corner.used":              "Tyres fitted to the corner in the session.",
+	"tyre_corner.temps":             "Carcass temperatures now in °C.",
+	"tyre_corner.wear":              "Percent of the tread remaining when iRacing last measured it in the pits.",
+	"tyre_corner.cold_pressure":     "Cold pressure in kPa.",
+	"tyre_stint.set":                "Tyres used on the corner when the set was fitted.",
+	"tyre_stint.from_lap":           "Laps completed by the player when the set was fitted.",
+	"tyre_stint.measurements":       "Measurements made in the pits, those made as tyres are changed being of the tyres taken off.",
+	"tyre_stint.wear_per_lap":       "Percent of the tread worn a lap, averaged across the tread, from the first to the last measurement.",
+	"tyre_stint.worst_per_lap":      "Percent a lap of the most worn part of the tread.",
+	"tyre_measurement.lap":          "Laps completed by the player when measured.",
+	"tyre_measurement.tread":        "Percent of the tread remaining.",
+	"tyre_measurement.temps":        "Temperatures in °C.",
+	"tyre_measurement.hot_pressure": "Hot pressure in kPa.",
+	"tyre_measurement.fitted":       "Set for new tyres, assumed unworn, when the set was fitted.",
+}
+
+// MessageKinds lists the kinds of message with a schema
+func MessageKinds() []string {
+	return slices.Sorted(maps.Keys(messages))
+}
+
+// Schema returns the JSON Schema of a kind of message, or of every message if kind is empty
+func Schema(kind string) ([]byte, error) {
+	s := map[string]any{
+		"$schema":     jsonSchemaDraft,
+		"description": fmt.Sprintf("vcrlive payloads, schema version %d", SchemaVersion),
+	}
+
+	defs := map[string]any{}
+
+	if kind == "" {
+		var oneOf []any
+
+		for _, k := range MessageKinds() {
+			oneOf = append(oneOf, define(defs, reflect.TypeOf(messages[k])))
+		}
+
+		s["$id"] = fmt.Sprintf(schemaIDFormat, SchemaVersion, "vcrlive")
+		s["oneOf"] = oneOf
+	} else {
+		message, ok := messages[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown message kind %q, expected one of %s", kind, strings.Join(MessageKinds(), ", "))
+		}
+
+		s["$id"] = fmt.Sprintf(schemaIDFormat, SchemaVersion, kind)
+		s["$ref"] = define(defs, reflect.TypeOf(message))["$ref"]
+	}
+
+	s["$defs"] = defs
+
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// define adds a struct to defs, returning a reference to it
+func define(defs map[string]any, t reflect.Type) map[string]any {
+	name := snakeCase(t.Name())
+	ref := map[string]any{"$ref": "#/$defs/" + name}
+
+	if _, ok := defs[name]; ok {
+		return ref
+	}
+
+	properties := map[string]any{}
+	required := []string{}
+	def := map[string]any{"type": "object", "properties": properties}
+	defs[name] = def
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		property := propertySchema(defs, field.Type)
+
+		if types, ok := unions[name+"."+tag[0]]; ok {
+			oneOf := make([]any, 0, len(types))
+			for _, t := range types {
+				oneOf = append(oneOf, define(defs, reflect.TypeOf(t)))
+			}
+
+			property = map[string]any{"oneOf": oneOf}
+		}
+
+		if values, ok := enums[name+"."+tag[0]]; ok {
+			if items, ok := property["items"].(map[string]any); ok {
+				items["enum"] = values
+			} else {
+				property["enum"] = values
+			}
+		}
+
+		if description, ok := descriptions[name+"."+tag[0]]; ok {
+			property["description"] = description
+		}
+
+		properties[tag[0]] = property
+
+		if !slices.Contains(tag[1:], "omitempty") && !slices.Contains(optional, name+"."+tag[0]) {
+			required = append(required, tag[0])
+		}
+	}
+
+	if description, ok := descriptions[name]; ok {
+		def["description"] = description
+	}
+
+	def["required"] = required
+
+	return ref
+}
+
+func propertySchema(defs map[string]any, t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() { //nolint:exhaustive // only what the model uses
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": propertySchema(defs, t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": propertySchema(defs, t.Elem())}
+	case reflect.Pointer:
+		return propertySchema(defs, t.Elem())
+	case reflect.Struct:
+		return define(defs, t)
+	}
+
+	return map[string]any{}
+}
+
+// snakeCase turns a type name such as LivePositions into live_positions
+func snakeCase(s string) string {
+	var b strings.Builder
+
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,216 @@
+package model
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the published schema")
+
+func TestSchema(t *testing.T) {
+	t.Run("Schema should match the published version", func(t *testing.T) {
+		got, err := Schema("")
+		require.NoError(t, err)
+
+		path := filepath.Join("testdata", fmt.Sprintf("schema.v%d.json", SchemaVersion))
+		if *update {
+			require.NoError(t, os.WriteFile(path, append(got, '\n'), 0o644)) //nolint:gosec // checked in
+		}
+
+		want, err := os.ReadFile(path)
+		require.NoError(t, err, "bump SchemaVersion for a breaking change, then go test -update in this package")
+		assert.JSONEq(t, string(want), string(got), "payloads changed, bump SchemaVersion if they are not compatible "+
+			"and run go test -update in this package")
+	})
+
+	t.Run("Every message should validate against its schema", func(t *testing.T) {
+		weekend := Weekend{TrackID: 168, TrackDisplayName: "Suzuka International Racing Course", SubSessionID: 78289018}
+		session := Session{SessionNum: 2, SessionLaps: "12", SessionType: "Race", SessionName: "RACE"}
+		session.SetState(Racing)
+
+		final := Session{SessionNum: 2}
+		final.SetState(CoolDown)
+
+		event := NewEvent(EventSessionEnd, time.Date(2025, 7, 12, 19, 45, 2, 0, time.UTC), 78289018, session)
+		event.Reason = EndNextSession
+
+		overtake := NewEvent(EventOvertake, time.Date(2025, 7, 12, 19, 31, 7, 0, time.UTC), 78289018, session)
+		overtake.Data = &RaceEvent{
+			Car:   RaceCar{CarIdx: 4, UserName: "Test driver", CarClassID: 84, Position: 2, ClassPosition: 2, LapsCompleted: 3, LapDistPct: 0.42},
+			Other: &RaceCar{CarIdx: 1, UserName: "Another driver", CarClassID: 84, Position: 3, ClassPosition: 3, LapsCompleted: 3, LapDistPct: 0.41},
+		}
+
+		restart := NewEvent(EventRestart, time.Date(2025, 7, 12, 19, 40, 12, 0, time.UTC), 78289018, session)
+		restart.Data = &RaceControl{
+			LapsCompleted: 9,
+			SessionTime:   1523.4,
+			Caution:       &Caution{FromLap: 6, ToLap: 9, FromSessionTime: 1187.25, ToSessionTime: 1523.4},
+		}
+
+		tests := []struct {
+			kind    string
+			message any
+		}{
+			{KindPositions, &LivePositions{SchemaVersion: SchemaVersion, Weekend: weekend, Session: session, Drivers: []Driver{{CarIdx: 1, UserName: "Test driver"}}}},
+			{KindPositions, &LivePositions{SchemaVersion: SchemaVersion, Session: final}},
+			{KindEvent, &event},
+			{KindEvent, &overtake},
+			{KindEvent, &restart},
+		}
+
+		for _, tt := range tests {
+			assert.NoError(t, validate(t, tt.kind, tt.message), "%s", tt.message)
+		}
+	})
+
+	t.Run("Invalid messages should not validate", func(t *testing.T) {
+		assert.ErrorContains(t, validate(t, KindPositions, map[string]any{"weekend": map[string]any{}}), "required")
+		assert.ErrorContains(t, validate(t, KindEvent, &Event{Type: "lap"}), "not one of")
+		pitIn := Event{Type: EventPitIn, Session: Session{SessionState: "Racing"}, Data: map[string]any{"lap": 1}}
+		assert.ErrorContains(t, validate(t, KindEvent, &pitIn), "matches none")
+	})
+
+	t.Run("Payloads from before versioning should still decode", func(t *testing.T) {
+		var livePositions LivePositions
+
+		payload := `{"weekend":{"track_id":168},"session":{"session_num":1,"session_state":"Racing"},"drivers":[{"car_idx":1,"class_position":3}]}`
+		require.NoError(t, json.Unmarshal([]byte(payload), &livePositions))
+
+		assert.Equal(t, 0, livePositions.SchemaVersion)
+		assert.Equal(t, 168, livePositions.Weekend.TrackID)
+		assert.Equal(t, 3, livePositions.Drivers[0].ClassPosition)
+	})
+
+	t.Run("Payloads from before versioning should validate", func(t *testing.T) {
+		session := Session{SessionNum: 1, SessionType: "Race"}
+		session.SetState(Racing)
+
+		tests := []struct {
+			kind    string
+			message any
+		}{
+			{KindPositions, &LivePositions{Weekend: Weekend{TrackID: 168}, Session: session, Drivers: []Driver{{CarIdx: 1, ClassPosition: 3}}}},
+			{KindEvent, &Event{Type: EventSessionStart, Session: session}},
+		}
+
+		for _, tt := range tests {
+			message := unversioned(t, tt.message)
+			assert.NotContains(t, message, "schema_version")
+			assert.NoError(t, validate(t, tt.kind, message), "%s", message)
+		}
+	})
+
+	t.Run("Unknown kinds should be rejected", func(t *testing.T) {
+		_, err := Schema("laps")
+		assert.ErrorContains(t, err, "event, positions")
+	})
+}
+
+// unversioned is message as sent before versioning, without the schema_version
+func unversioned(t *testing.T, message any) map[string]any {
+	t.Helper()
+
+	b, err := json.Marshal(message)
+	require.NoError(t, err)
+
+	var payload map[string]any
+
+	require.NoError(t, json.Unmarshal(b, &payload))
+	delete(payload, "schema_version")
+
+	return payload
+}
+
+// validate checks a message against the parts of JSON Schema the generated schema uses
+func validate(t *testing.T, kind string, message any) error {
+	t.Helper()
+
+	b, err := Schema(kind)
+	require.NoError(t, err)
+
+	var schema map[string]any
+
+	require.NoError(t, json.Unmarshal(b, &schema))
+
+	b, err = json.Marshal(message)
+	require.NoError(t, err)
+
+	var value any
+
+	require.NoError(t, json.Unmarshal(b, &value))
+
+	return check(schema["$defs"].(map[string]any), schema, value, "$")
+}
+
+func check(defs map[string]any, schema map[string]any, value any, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		return check(defs, defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any), value, path)
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		for _, s := range oneOf {
+			if check(defs, s.(map[string]any), value, path) == nil {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%s matches none of %v", path, oneOf)
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s %v is not one of %v", path, value, enum)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s is not an object", path)
+		}
+
+		for _, name := range schema["required"].([]any) {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+
+		properties := schema["properties"].(map[string]any)
+
+		for name, v := range object {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.%s is not in the schema", path, name)
+			}
+
+			if err := check(defs, property, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		for i, v := range value.([]any) {
+			if err := check(defs, schema["items"].(map[string]any), v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s is not a number", path)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s is not a string", path)
+		}
+	}
+
+	return nil
+}
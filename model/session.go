@@ -12,6 +12,7 @@ const (
 	Racing
 	Checkered
 	CoolDown
+	Stopped // Not an iRacing state, vcrlive was shut down
 )
 
 type Session struct {
@@ -56,5 +57,7 @@ func (s *Session) SetState(state int) {
 		s.SessionState = "Checkered"
 	case CoolDown:
 		s.SessionState = "Cool Down"
+	case Stopped:
+		s.SessionState = "Stopped"
 	}
 }
@@ -0,0 +1,36 @@
+package model
+
+// Tyres is the state of the player's tyres now and the measurements of each stint, values across the tread are
+// inner, middle and outer
+type Tyres struct {
+	Live   []TyreCorner `json:"live"`
+	Stints []TyreStint  `json:"stints"` // by corner then set
+}
+
+// TyreCorner is a corner's tyre now
+type TyreCorner struct {
+	Corner       string     `json:"corner"`
+	Used         int        `json:"used"`          // tyres fitted in the session
+	Temps        [3]float64 `json:"temps"`         // carcass °C
+	Wear         [3]float64 `json:"wear"`          // percent of the tread remaining
+	ColdPressure float64    `json:"cold_pressure"` // kPa
+}
+
+// TyreStint is the life of a set of tyres on a corner
+type TyreStint struct {
+	Corner       string            `json:"corner"`
+	Set          int               `json:"set"` // tyres used on the corner when fitted
+	FromLap      int               `json:"from_lap"`
+	Measurements []TyreMeasurement `json:"measurements"`
+	WearPerLap   float64           `json:"wear_per_lap"`  // percent of the tread a lap, averaged across the tread
+	WorstPerLap  float64           `json:"worst_per_lap"` // percent a lap of the most worn part of the tread
+}
+
+// TyreMeasurement is a tyre as measured in the pits
+type TyreMeasurement struct {
+	Lap         int        `json:"lap"`
+	Tread       [3]float64 `json:"tread"`        // percent remaining
+	Temps       [3]float64 `json:"temps"`        // °C
+	HotPressure float64    `json:"hot_pressure"` // kPa
+	Fitted      bool       `json:"fitted,omitempty"`
+}
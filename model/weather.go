@@ -0,0 +1,190 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+)
+
+// Changes of the weather in the timeline
+const (
+	ChangeRainStarted = "rain_started"
+	ChangeRainStopped = "rain_stopped"
+	ChangeWetter      = "wetter"
+	ChangeDrying      = "drying"
+	ChangeSkies       = "skies"
+	ChangeDeclaredWet = "declared_wet"
+	ChangeDeclaredDry = "declared_dry"
+	ChangeAirTemp     = "air_temp"
+	ChangeTrackTemp   = "track_temp"
+	ChangeWind        = "wind"
+)
+
+const (
+	// weatherPrecision rounds temperatures, speeds and percentages to tenths
+	weatherPrecision = 10
+
+	// sessionTimePrecision rounds the session time to the millisecond
+	sessionTimePrecision = 1000
+
+	// hPaPerInHg converts the air pressure of the weekend
+	hPaPerInHg = 33.8639
+)
+
+// wetness names irsdk_TrackWetness, skies the Skies variable
+var (
+	wetness = []string{"Unknown", "Dry", "Mostly dry", "Very lightly wet", "Lightly wet", "Moderately wet", "Very wet", "Extremely wet"}
+	skies   = []string{"Clear", "Partly cloudy", "Mostly cloudy", "Overcast"}
+)
+
+// ChangeTypes lists the changes of the weather timeline
+func ChangeTypes() []string {
+	return []string{
+		ChangeRainStarted, ChangeRainStopped, ChangeWetter, ChangeDrying, ChangeSkies, ChangeDeclaredWet, ChangeDeclaredDry,
+		ChangeAirTemp, ChangeTrackTemp, ChangeWind,
+	}
+}
+
+// WeekendWeather is the weather set for the weekend, the start of the session when the weather is dynamic
+type WeekendWeather struct {
+	Type        string  `json:"type"` // e.g. Static, Dynamic
+	Skies       string  `json:"skies"`
+	AirTemp     float64 `json:"air_temp"`     // °C
+	TrackTemp   float64 `json:"track_temp"`   // °C
+	AirPressure float64 `json:"air_pressure"` // hPa
+	WindSpeed   float64 `json:"wind_speed"`   // m/s
+	WindDir     float64 `json:"wind_dir"`     // degrees
+	Humidity    float64 `json:"humidity"`     // percent
+	FogLevel    float64 `json:"fog_level"`    // percent
+}
+
+// Weather is the conditions now and the timeline of how they changed during the session
+type Weather struct {
+	Current  Conditions      `json:"current"`
+	Timeline []WeatherChange `json:"timeline"`
+}
+
+// Conditions are the weather and track at a point in the session
+type Conditions struct {
+	SessionTime   float64 `json:"session_time"`
+	AirTemp       float64 `json:"air_temp"`      // °C
+	TrackTemp     float64 `json:"track_temp"`    // °C
+	WindSpeed     float64 `json:"wind_speed"`    // m/s
+	WindDir       float64 `json:"wind_dir"`      // degrees
+	Precipitation float64 `json:"precipitation"` // percent
+	Wetness       string  `json:"wetness"`
+	Skies         string  `json:"skies"`
+	DeclaredWet   bool    `json:"declared_wet"`
+}
+
+// WeatherChange is the conditions when something changed, the first of a session has no changes
+type WeatherChange struct {
+	Conditions Conditions `json:"conditions"`
+	Changes    []string   `json:"changes,omitempty"`
+}
+
+func NewWeekendWeather(weekend *iryaml.WeekendInfo) WeekendWeather {
+	return WeekendWeather{
+		Type:        weekend.TrackWeatherType,
+		Skies:       weekend.TrackSkies,
+		AirTemp:     celsius(weekend.TrackAirTemp),
+		TrackTemp:   celsius(weekend.TrackSurfaceTemp),
+		AirPressure: hPa(weekend.TrackAirPressure),
+		WindSpeed:   number(weekend.TrackWindVel),
+		WindDir:     degrees(parse(weekend.TrackWindDir)),
+		Humidity:    number(weekend.TrackRelativeHumidity),
+		FogLevel:    number(weekend.TrackFogLevel),
+	}
+}
+
+// WeatherReading is the live weather variables, in iRacing's units
+type WeatherReading struct {
+	SessionTime   float64
+	AirTemp       float64 // °C
+	TrackTemp     float64 // °C, TrackTempCrew
+	WindVel       float64 // m/s
+	WindDir       float64 // radians
+	Precipitation float64 // 0 to 1
+	TrackWetness  int     // irsdk_TrackWetness
+	Skies         int
+	DeclaredWet   bool
+}
+
+func NewConditions(r *WeatherReading) Conditions {
+	return Conditions{
+		SessionTime:   round(r.SessionTime, sessionTimePrecision),
+		AirTemp:       round(r.AirTemp, weatherPrecision),
+		TrackTemp:     round(r.TrackTemp, weatherPrecision),
+		WindSpeed:     round(r.WindVel, weatherPrecision),
+		WindDir:       degrees(r.WindDir),
+		Precipitation: round(r.Precipitation*100, weatherPrecision), //nolint:mnd // percent
+		Wetness:       name(wetness, r.TrackWetness),
+		Skies:         name(skies, r.Skies),
+		DeclaredWet:   r.DeclaredWet,
+	}
+}
+
+// Wetter reports whether the track is wetter in c than before
+func (c *Conditions) Wetter(before *Conditions) bool {
+	return wetnessLevel(c.Wetness) > wetnessLevel(before.Wetness)
+}
+
+func wetnessLevel(s string) int {
+	for i, w := range wetness {
+		if w == s {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func name(names []string, i int) string {
+	if i < 0 || i >= len(names) {
+		return names[0]
+	}
+
+	return names[i]
+}
+
+// degrees converts radians to a compass bearing
+func degrees(radians float64) float64 {
+	return round(math.Mod(radians*180/math.Pi+360, 360), weatherPrecision) //nolint:mnd // degrees
+}
+
+// parse reads the number at the start of a weekend value, e.g. 55 from "55 %", 0 if there is none
+func parse(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimRight(fields[0], "CF%"), 64)
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
+
+func number(s string) float64 {
+	return round(parse(s), weatherPrecision)
+}
+
+func celsius(s string) float64 {
+	if strings.HasSuffix(strings.TrimSpace(s), "F") {
+		return round((parse(s)-32)*5/9, weatherPrecision) //nolint:mnd // Fahrenheit
+	}
+
+	return number(s)
+}
+
+func hPa(s string) float64 {
+	if strings.HasSuffix(strings.TrimSpace(s), "Hg") {
+		return round(parse(s)*hPaPerInHg, weatherPrecision)
+	}
+
+	return number(s)
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeather(t *testing.T) {
+	t.Run("Weekend weather should be parsed to metric", func(t *testing.T) {
+		weekend := iryaml.WeekendInfo{
+			TrackWeatherType:      "Dynamic",
+			TrackSkies:            "Partly Cloudy",
+			TrackAirTemp:          "77.00 F",
+			TrackSurfaceTemp:      "31.45 C",
+			TrackAirPressure:      "29.92 Hg",
+			TrackWindVel:          "2.35 m/s",
+			TrackWindDir:          "1.57 rad",
+			TrackRelativeHumidity: "55 %",
+			TrackFogLevel:         "0 %",
+		}
+
+		expected := WeekendWeather{
+			Type: "Dynamic", Skies: "Partly Cloudy", AirTemp: 25, TrackTemp: 31.5, AirPressure: 1013.2, WindSpeed: 2.4,
+			WindDir: 90, Humidity: 55, FogLevel: 0,
+		}
+
+		assert.Equal(t, expected, NewWeekendWeather(&weekend))
+	})
+
+	t.Run("Missing weekend weather should be zero", func(t *testing.T) {
+		assert.Equal(t, WeekendWeather{}, NewWeekendWeather(&iryaml.WeekendInfo{TrackAirTemp: "n/a"}))
+	})
+
+	t.Run("Conditions should be rounded and named", func(t *testing.T) {
+		r := WeatherReading{
+			SessionTime: 123.45678, AirTemp: 21.04, TrackTemp: 28.96, WindVel: 4.44, WindDir: -math.Pi / 2,
+			Precipitation: 0.1234, TrackWetness: 3, Skies: 2, DeclaredWet: true,
+		}
+
+		expected := Conditions{
+			SessionTime: 123.457, AirTemp: 21, TrackTemp: 29, WindSpeed: 4.4, WindDir: 270, Precipitation: 12.3,
+			Wetness: "Very lightly wet", Skies: "Mostly cloudy", DeclaredWet: true,
+		}
+
+		assert.Equal(t, expected, NewConditions(&r))
+	})
+
+	t.Run("Unknown wetness and skies should use the first name", func(t *testing.T) {
+		c := NewConditions(&WeatherReading{TrackWetness: 9, Skies: -1})
+
+		assert.Equal(t, "Unknown", c.Wetness)
+		assert.Equal(t, "Clear", c.Skies)
+	})
+
+	t.Run("Wetter should compare wetness", func(t *testing.T) {
+		dry := Conditions{Wetness: "Dry"}
+		wet := Conditions{Wetness: "Moderately wet"}
+
+		assert.True(t, wet.Wetter(&dry))
+		assert.False(t, dry.Wetter(&wet))
+		assert.False(t, wet.Wetter(&wet))
+	})
+}
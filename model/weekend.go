@@ -3,19 +3,20 @@ package model
 import "github.com/ianhaycox/vcrlive/irsdk/iryaml"
 
 type Weekend struct {
-	TrackID          int    `json:"track_id"`
-	TrackDisplayName string `json:"track_display_name"`
-	TrackConfigName  string `json:"track_config_name"`
-	SeriesID         int    `json:"series_id"`
-	SeasonID         int    `json:"season_id"`
-	SessionID        int    `json:"session_id"`
-	SubSessionID     int    `json:"sub_session_id"`
-	Official         int    `json:"official"`
-	RaceWeek         int    `json:"race_week"`
-	EventType        string `json:"event_type"`
-	Category         string `json:"category"`
-	NumCarClasses    int    `json:"num_car_classes"`
-	NumCarTypes      int    `json:"num_car_types"`
+	TrackID          int            `json:"track_id"`
+	TrackDisplayName string         `json:"track_display_name"`
+	TrackConfigName  string         `json:"track_config_name"`
+	SeriesID         int            `json:"series_id"`
+	SeasonID         int            `json:"season_id"`
+	SessionID        int            `json:"session_id"`
+	SubSessionID     int            `json:"sub_session_id"`
+	Official         int            `json:"official"`
+	RaceWeek         int            `json:"race_week"`
+	EventType        string         `json:"event_type"`
+	Category         string         `json:"category"`
+	NumCarClasses    int            `json:"num_car_classes"`
+	NumCarTypes      int            `json:"num_car_types"`
+	Weather          WeekendWeather `json:"weather"`
 }
 
 func NewWeekend(weekend *iryaml.WeekendInfo) Weekend {
@@ -32,5 +33,6 @@ func NewWeekend(weekend *iryaml.WeekendInfo) Weekend {
 		Category:         weekend.Category,
 		NumCarClasses:    weekend.NumCarClasses,
 		NumCarTypes:      weekend.NumCarTypes,
+		Weather:          NewWeekendWeather(weekend),
 	}
 }
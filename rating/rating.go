@@ -0,0 +1,123 @@
+// Package rating estimates the iRating each driver would gain or lose if a race ended now, using the formula the
+// community has worked out from published results, and the player's corners per incident that safety rating is
+// based on. iRacing only applies them to official races.
+package rating
+
+import (
+	"math"
+
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+const (
+	// br1 is the scale of iRating in the formula
+	br1 = 1600 / math.Ln2
+
+	// gain is the change for beating one more driver than expected, shared by the starters
+	gain = 200
+
+	// fudge keeps the changes of a field from adding up to much more than zero
+	fudge = 100
+
+	// cpiPrecision rounds corners per incident to tenths
+	cpiPrecision = 10
+)
+
+// Entry is a driver's iRating and class position, 0 for a driver who did not start
+type Entry struct {
+	IRating  int
+	Position int
+}
+
+// Changes returns the projected change of each entry, in the same order, for a field racing in one class
+func Changes(entries []Entry) []float64 {
+	n := len(entries)
+	changes := make([]float64, n)
+
+	nonStarters := 0
+
+	for _, e := range entries {
+		if e.Position == 0 {
+			nonStarters++
+		}
+	}
+
+	starters := n - nonStarters
+	if starters < 2 { //nolint:mnd // a race needs two
+		return changes
+	}
+
+	expected := make([]float64, n)
+
+	for i := range entries {
+		for j := range entries {
+			if i != j {
+				expected[i] += chance(entries[i].IRating, entries[j].IRating)
+			}
+		}
+	}
+
+	starterChanges, nonStarterExpected := 0.0, 0.0
+
+	for i, e := range entries {
+		if e.Position == 0 {
+			nonStarterExpected += expected[i]
+			continue
+		}
+
+		f := ((float64(n)-float64(nonStarters)/2)/2 - float64(e.Position)) / fudge
+		changes[i] = (float64(n-e.Position) - expected[i] - f) * gain / float64(starters)
+		starterChanges += changes[i]
+	}
+
+	// Non-starters lose what the starters gained between them, the more so the more they were expected to beat
+	if nonStarters > 0 && nonStarterExpected > 0 {
+		average := nonStarterExpected / float64(nonStarters)
+
+		for i, e := range entries {
+			if e.Position == 0 {
+				changes[i] = -starterChanges / float64(nonStarters) * expected[i] / average
+			}
+		}
+	}
+
+	return changes
+}
+
+// Project sets the projected iRating change of each driver from the running order of their class
+func Project(drivers []model.Driver) {
+	classes := map[int][]int{} // indexes of drivers by class
+
+	for i := range drivers {
+		classes[drivers[i].CarClassID] = append(classes[drivers[i].CarClassID], i)
+	}
+
+	for _, indexes := range classes {
+		entries := make([]Entry, len(indexes))
+		for k, i := range indexes {
+			entries[k] = Entry{IRating: drivers[i].IRating, Position: drivers[i].ClassPosition}
+		}
+
+		for k, change := range Changes(entries) {
+			drivers[indexes[k]].IRatingChange = int(math.Round(change))
+		}
+	}
+}
+
+// Safety is the player's corners per incident from their incidents and laps completed at a track with turns corners
+func Safety(incidents int, lapsCompleted int, turns int) *model.Safety {
+	s := model.Safety{Incidents: incidents, Corners: max(lapsCompleted, 0) * turns}
+
+	if incidents > 0 {
+		s.CornersPerIncident = math.Round(float64(s.Corners)/float64(incidents)*cpiPrecision) / cpiPrecision
+	}
+
+	return &s
+}
+
+// chance is the probability that a driver rated a finishes ahead of one rated b
+func chance(a int, b int) float64 {
+	ea, eb := math.Exp(-float64(max(a, 1))/br1), math.Exp(-float64(max(b, 1))/br1)
+
+	return (1 - ea) * eb / ((1-eb)*ea + (1-ea)*eb)
+}
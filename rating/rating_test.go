@@ -0,0 +1,97 @@
+package rating
+
+import (
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// The expected changes come from the formula itself, so these cases guard against it changing rather than show it
+// matches iRacing. The evenly matched pair can be checked by hand: each expects to beat the other half the time, so
+// the winner gains (1 - 0.5) * 200 / 2 = 50 and the loser (0 - 0.5 + 0.01) * 200 / 2 = -49 after the fudge.
+func TestChanges(t *testing.T) {
+	const tolerance = 0.01
+
+	tests := []struct {
+		name     string
+		entries  []Entry
+		expected []float64
+	}{
+		{
+			name:     "Evenly matched drivers should share the points, less the fudge",
+			entries:  []Entry{{1500, 1}, {1500, 2}},
+			expected: []float64{50, -49},
+		},
+		{
+			name:     "The favourite winning should gain little",
+			entries:  []Entry{{3000, 1}, {1500, 2}},
+			expected: []float64{25.54, -24.54},
+		},
+		{
+			name:     "An upset should cost the favourite a lot",
+			entries:  []Entry{{3000, 2}, {1500, 1}},
+			expected: []float64{-73.46, 74.46},
+		},
+		{
+			name:     "Finishing in iRating order",
+			entries:  []Entry{{2500, 1}, {2000, 2}, {1500, 3}, {1000, 4}},
+			expected: []float64{47.00, 13.38, -16.80, -42.58},
+		},
+		{
+			name:     "Finishing in reverse iRating order",
+			entries:  []Entry{{2500, 4}, {2000, 3}, {1500, 2}, {1000, 1}},
+			expected: []float64{-101.50, -36.12, 32.70, 105.92},
+		},
+		{
+			name:     "Non-starters should lose what the starters gained",
+			entries:  []Entry{{2000, 1}, {2000, 2}, {2000, 3}, {2000, 0}},
+			expected: []float64{99.5, 33.5, -32.5, -100.5},
+		},
+		{
+			name:     "A driver on their own should not change",
+			entries:  []Entry{{2000, 1}, {2000, 0}},
+			expected: []float64{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Changes(tt.entries)
+
+			assert.Len(t, changes, len(tt.expected))
+
+			for i := range tt.expected {
+				assert.InDelta(t, tt.expected[i], changes[i], tolerance, "entry %d", i)
+			}
+		})
+	}
+}
+
+func TestProject(t *testing.T) {
+	t.Run("Each class should be rated on its own running order", func(t *testing.T) {
+		drivers := []model.Driver{
+			{CarIdx: 1, CarClassID: 84, IRating: 1500, ClassPosition: 2},
+			{CarIdx: 2, CarClassID: 83, IRating: 3000, ClassPosition: 1},
+			{CarIdx: 3, CarClassID: 84, IRating: 1500, ClassPosition: 1},
+			{CarIdx: 4, CarClassID: 83, IRating: 1500, ClassPosition: 2},
+		}
+
+		Project(drivers)
+
+		assert.Equal(t, []int{-49, 26, 50, -25}, []int{
+			drivers[0].IRatingChange, drivers[1].IRatingChange, drivers[2].IRatingChange, drivers[3].IRatingChange,
+		})
+	})
+}
+
+func TestSafety(t *testing.T) {
+	t.Run("Corners per incident should count the corners of every lap completed", func(t *testing.T) {
+		assert.Equal(t, &model.Safety{Incidents: 4, Corners: 180, CornersPerIncident: 45}, Safety(4, 10, 18))
+		assert.Equal(t, &model.Safety{Incidents: 3, Corners: 100, CornersPerIncident: 33.3}, Safety(3, 10, 10))
+	})
+
+	t.Run("A clean race should have no corners per incident", func(t *testing.T) {
+		assert.Equal(t, &model.Safety{Corners: 54}, Safety(0, 3, 18))
+	})
+}
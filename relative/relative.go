@@ -0,0 +1,255 @@
+// Package relative lists the cars nearest on track to a focus car with the time to each, like iRacing's relative
+// black box
+package relative
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DefaultCars is how many cars ahead and behind are listed unless asked for more or fewer
+	DefaultCars = 3
+
+	// MaxCars is the most cars ahead and behind that can be listed
+	MaxCars = 10
+
+	// precision rounds deltas to the millisecond
+	precision = 1000
+)
+
+// Focus is the car the board is centred on, when not a CarIdx
+const (
+	FocusPlayer = "player" // the player's car, or the camera car for a spectator
+	FocusCamera = "camera"
+)
+
+// Status of a car compared with the focus car
+const (
+	StatusFocus     = "focus"
+	StatusSameLap   = "same_lap"
+	StatusLapAhead  = "lap_ahead"  // a lap or more ahead in the race, lapping the focus car
+	StatusLapBehind = "lap_behind" // a lap or more behind in the race, being lapped
+)
+
+// Reading is every car on track at a point in time
+type Reading struct {
+	SessionTime  float64
+	PlayerCarIdx int
+	CameraCarIdx int  // -1 if not known
+	Race         bool // laps ahead and behind only count in a race
+	Cars         []Car
+}
+
+// Car is where a car is on track
+type Car struct {
+	CarIdx        int
+	UserName      string
+	CarNumber     string
+	CarClassID    int
+	ClassColor    string  // #rrggbb, empty if not set
+	EstLapTime    float64 // seconds, iRacing's estimate for the class
+	Position      int
+	ClassPosition int
+	IRating       int
+	Lap           int     // lap being driven
+	LapDistPct    float64 // 0 to 1 around the lap, negative when not in the world
+	EstTime       float64 // seconds iRacing estimates the class takes from the line to here
+	OnPitRoad     bool
+}
+
+// Relative is the cars around the focus car on track, furthest ahead first
+type Relative struct {
+	FocusCarIdx int     `json:"focus_car_idx"` // -1 when the focus car is not on track
+	SessionTime float64 `json:"session_time"`
+	Cars        []Entry `json:"cars"` // ahead, the focus car, then behind
+}
+
+// Entry is a car on the board
+type Entry struct {
+	CarIdx        int     `json:"car_idx"`
+	UserName      string  `json:"user_name"`
+	CarNumber     string  `json:"car_number"`
+	CarClassID    int     `json:"car_class_id"`
+	ClassColor    string  `json:"class_color"`
+	Position      int     `json:"position"`
+	ClassPosition int     `json:"class_position"`
+	IRating       int     `json:"irating"`
+	Delta         float64 `json:"delta"` // seconds on track, positive ahead of the focus car
+	Laps          int     `json:"laps"`  // laps ahead of the focus car in a race, negative behind
+	Status        string  `json:"status"`
+	OnPitRoad     bool    `json:"on_pit_road,omitempty"`
+}
+
+// Board keeps the latest reading. It is safe to read the board while it is updated.
+type Board struct {
+	mu     sync.RWMutex
+	latest Reading
+}
+
+func NewBoard() *Board {
+	return &Board{latest: Reading{PlayerCarIdx: -1, CameraCarIdx: -1}}
+}
+
+// Update keeps a new reading
+func (b *Board) Update(r Reading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r.Cars = slices.Clone(r.Cars)
+	b.latest = r
+}
+
+// Query is Around for the local server, the focus is the car parameter, player by default, and the number of cars
+// the n parameter
+func (b *Board) Query(query url.Values) (Relative, error) {
+	n := DefaultCars
+
+	if v := query.Get("n"); v != "" {
+		var err error
+
+		n, err = strconv.Atoi(v)
+		if err != nil || n < 0 || n > MaxCars {
+			return Relative{}, fmt.Errorf("n must be 0 to %d cars", MaxCars)
+		}
+	}
+
+	return b.Around(cmp.Or(query.Get("car"), FocusPlayer), n)
+}
+
+// Around lists the n nearest cars ahead and behind the focus car on track, which is FocusPlayer, FocusCamera or a
+// CarIdx
+func (b *Board) Around(focus string, n int) (Relative, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	r := &b.latest
+	board := Relative{FocusCarIdx: -1, SessionTime: r.SessionTime, Cars: []Entry{}}
+
+	var focusCarIdx int
+
+	switch focus {
+	case FocusPlayer:
+		focusCarIdx = r.PlayerCarIdx
+		if r.find(focusCarIdx) == nil {
+			focusCarIdx = r.CameraCarIdx
+		}
+	case FocusCamera:
+		focusCarIdx = r.CameraCarIdx
+	default:
+		var err error
+
+		focusCarIdx, err = strconv.Atoi(focus)
+		if err != nil {
+			return board, fmt.Errorf("car must be %s, %s or a car_idx, not %q", FocusPlayer, FocusCamera, focus)
+		}
+	}
+
+	f := r.find(focusCarIdx)
+	if f == nil {
+		return board, nil
+	}
+
+	var ahead, behind []Entry
+
+	for i := range r.Cars {
+		c := &r.Cars[i]
+		if c.CarIdx == f.CarIdx || c.LapDistPct < 0 {
+			continue
+		}
+
+		e := compare(f, c, r.Race)
+		if e.Delta > 0 || (e.Delta == 0 && c.CarIdx < f.CarIdx) {
+			ahead = append(ahead, e)
+		} else {
+			behind = append(behind, e)
+		}
+	}
+
+	// Nearest first, then the ones ahead are turned round to go down the board
+	slices.SortFunc(ahead, func(a, b Entry) int { return cmp.Compare(a.Delta, b.Delta) })
+	slices.SortFunc(behind, func(a, b Entry) int { return cmp.Compare(b.Delta, a.Delta) })
+
+	ahead = ahead[:min(n, len(ahead))]
+	slices.Reverse(ahead)
+
+	board.FocusCarIdx = f.CarIdx
+	board.Cars = append(board.Cars, ahead...)
+	board.Cars = append(board.Cars, entry(f, 0, 0, StatusFocus))
+	board.Cars = append(board.Cars, behind[:min(n, len(behind))]...)
+
+	return board, nil
+}
+
+func (r *Reading) find(carIdx int) *Car {
+	for i := range r.Cars {
+		if r.Cars[i].CarIdx == carIdx && r.Cars[i].LapDistPct >= 0 {
+			return &r.Cars[i]
+		}
+	}
+
+	return nil
+}
+
+// compare times car c from the focus car f. Cars of the same class have iRacing's estimates of the time to each
+// point on track, others are timed by the distance between them at the focus car's pace.
+func compare(f, c *Car, race bool) Entry {
+	gap := wrap(c.LapDistPct-f.LapDistPct, 1)
+	delta := gap * f.EstLapTime
+
+	if c.CarClassID == f.CarClassID && c.EstTime > 0 && f.EstTime > 0 && f.EstLapTime > 0 {
+		delta = wrap(c.EstTime-f.EstTime, f.EstLapTime)
+	}
+
+	status, laps := StatusSameLap, 0
+
+	if race {
+		// Laps apart in the race, less the distance apart on track
+		laps = int(math.Round(float64(c.Lap) + c.LapDistPct - float64(f.Lap) - f.LapDistPct - gap))
+
+		switch {
+		case laps > 0:
+			status = StatusLapAhead
+		case laps < 0:
+			status = StatusLapBehind
+		}
+	}
+
+	return entry(c, delta, laps, status)
+}
+
+// wrap brings v into half a lap either way, a lap being span
+func wrap(v, span float64) float64 {
+	v = math.Mod(v, span)
+
+	switch {
+	case v >= span/2: //nolint:mnd // half a lap
+		v -= span
+	case v < -span/2: //nolint:mnd // half a lap
+		v += span
+	}
+
+	return v
+}
+
+func entry(c *Car, delta float64, laps int, status string) Entry {
+	return Entry{
+		CarIdx:        c.CarIdx,
+		UserName:      c.UserName,
+		CarNumber:     c.CarNumber,
+		CarClassID:    c.CarClassID,
+		ClassColor:    c.ClassColor,
+		Position:      c.Position,
+		ClassPosition: c.ClassPosition,
+		IRating:       c.IRating,
+		Delta:         math.Round(delta*precision) / precision,
+		Laps:          laps,
+		Status:        status,
+		OnPitRoad:     c.OnPitRoad,
+	}
+}
@@ -0,0 +1,141 @@
+package relative
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoard(t *testing.T) {
+	// GT3s lap in 100s, GT4s in 110s
+	gt3 := func(carIdx int, lap int, pct float64) Car {
+		return Car{CarIdx: carIdx, CarNumber: "3", CarClassID: 84, ClassColor: "#ffda59", EstLapTime: 100, Lap: lap, LapDistPct: pct, EstTime: pct * 100}
+	}
+
+	gt4 := func(carIdx int, lap int, pct float64) Car {
+		return Car{CarIdx: carIdx, CarNumber: "4", CarClassID: 83, ClassColor: "#33ceff", EstLapTime: 110, Lap: lap, LapDistPct: pct, EstTime: pct * 110}
+	}
+
+	reading := Reading{
+		SessionTime:  1200,
+		PlayerCarIdx: 1,
+		CameraCarIdx: 5,
+		Race:         true,
+		Cars: []Car{
+			gt3(1, 10, 0.5),
+			gt3(2, 10, 0.52),  // just ahead
+			gt3(3, 11, 0.45),  // a lap ahead, just behind on track
+			gt4(4, 9, 0.6),    // a lap behind, ahead on track
+			gt3(5, 10, 0.98),  // nearly half a lap ahead
+			gt4(6, 10, 0.1),   // behind on track
+			gt3(7, 11, 0.02),  // across the line, just over half a lap behind on track
+			gt3(8, 10, -1),    // not in the world
+			gt4(9, 10, 0.499), // right behind
+		},
+	}
+
+	type car struct {
+		carIdx int
+		delta  float64
+		laps   int
+		status string
+	}
+
+	cars := func(r Relative) []car {
+		var cars []car
+		for _, e := range r.Cars {
+			cars = append(cars, car{e.CarIdx, e.Delta, e.Laps, e.Status})
+		}
+
+		return cars
+	}
+
+	b := NewBoard()
+	b.Update(reading)
+
+	t.Run("The nearest cars should be listed ahead then behind the player", func(t *testing.T) {
+		r, err := b.Around(FocusPlayer, 3)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, r.FocusCarIdx)
+		assert.InDelta(t, 1200, r.SessionTime, 0)
+		assert.Equal(t, []car{
+			{5, 48, 0, StatusSameLap},
+			{4, 10, -1, StatusLapBehind},
+			{2, 2, 0, StatusSameLap},
+			{1, 0, 0, StatusFocus},
+			{9, -0.1, 0, StatusSameLap},
+			{3, -5, 1, StatusLapAhead},
+			{6, -40, 0, StatusSameLap},
+		}, cars(r))
+
+		assert.Equal(t, Entry{CarIdx: 2, CarNumber: "3", CarClassID: 84, ClassColor: "#ffda59", Delta: 2, Status: StatusSameLap}, r.Cars[2])
+	})
+
+	t.Run("Every car should be listed when asked for more", func(t *testing.T) {
+		r, err := b.Around("1", MaxCars)
+		require.NoError(t, err)
+
+		assert.Len(t, r.Cars, 8)
+		assert.Equal(t, 7, r.Cars[7].CarIdx)
+		assert.InDelta(t, -48, r.Cars[7].Delta, 0)
+	})
+
+	t.Run("The camera car should be the focus when asked", func(t *testing.T) {
+		r, err := b.Around(FocusCamera, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, []car{{7, 4, 0, StatusSameLap}, {5, 0, 0, StatusFocus}, {4, -38, -1, StatusLapBehind}}, cars(r))
+	})
+
+	t.Run("A spectator should see the camera car", func(t *testing.T) {
+		spectating := reading
+		spectating.PlayerCarIdx = 63
+
+		b := NewBoard()
+		b.Update(spectating)
+
+		r, err := b.Around(FocusPlayer, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []car{{5, 0, 0, StatusFocus}}, cars(r))
+	})
+
+	t.Run("Outside a race every car should be on the same lap", func(t *testing.T) {
+		practice := reading
+		practice.Race = false
+
+		b := NewBoard()
+		b.Update(practice)
+
+		r, err := b.Around(FocusPlayer, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []car{{2, 2, 0, StatusSameLap}, {1, 0, 0, StatusFocus}, {9, -0.1, 0, StatusSameLap}}, cars(r))
+	})
+
+	t.Run("A car not on track should have an empty board", func(t *testing.T) {
+		r, err := b.Around("8", 3)
+		require.NoError(t, err)
+
+		assert.Equal(t, Relative{FocusCarIdx: -1, SessionTime: 1200, Cars: []Entry{}}, r)
+	})
+
+	t.Run("Query parameters should choose the focus and number of cars", func(t *testing.T) {
+		r, err := b.Query(url.Values{"car": {"camera"}, "n": {"1"}})
+		require.NoError(t, err)
+		assert.Len(t, r.Cars, 3)
+		assert.Equal(t, 5, r.FocusCarIdx)
+
+		r, err = b.Query(url.Values{})
+		require.NoError(t, err)
+		assert.Len(t, r.Cars, 2*DefaultCars+1)
+		assert.Equal(t, 1, r.FocusCarIdx)
+
+		_, err = b.Query(url.Values{"n": {"11"}})
+		assert.ErrorContains(t, err, "n must be 0 to 10 cars")
+
+		_, err = b.Query(url.Values{"car": {"leader"}})
+		assert.ErrorContains(t, err, `not "leader"`)
+	})
+}
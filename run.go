@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/config"
+	"github.com/ianhaycox/vcrlive/connectors/server"
+	"github.com/ianhaycox/vcrlive/connectors/sinks"
+	"github.com/ianhaycox/vcrlive/connectors/telemetry"
+	"github.com/ianhaycox/vcrlive/fuel"
+	"github.com/ianhaycox/vcrlive/logging"
+	"github.com/ianhaycox/vcrlive/metrics"
+	"github.com/ianhaycox/vcrlive/relative"
+	"github.com/ianhaycox/vcrlive/standings"
+	"github.com/ianhaycox/vcrlive/trackmap"
+	"github.com/ianhaycox/vcrlive/tyres"
+)
+
+const (
+	defaultWaitMilliseconds        = 100
+	defaultMinIntervalMilliseconds = 1000
+	defaultRefreshSeconds          = 10
+	defaultListen                  = "127.0.0.1:8080"
+	metricsTimeout                 = 5 * time.Second
+)
+
+var (
+	ibtFile                 string
+	waitMilliseconds        int
+	minIntervalMilliseconds int
+	refreshSeconds          int
+	redact                  bool
+	supervise               bool
+	sinkSpecs               sinkList
+	listen                  string
+	metricsAddress          string
+	logLevel                string
+	logFormat               string
+	configFile              string
+	profileName             string
+)
+
+// sinkList collects repeated -sink flags
+type sinkList []string
+
+func (s *sinkList) String() string {
+	return strings.Join(*s, " ")
+}
+
+func (s *sinkList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// feed implements run, serve and replay which all sample the telemetry and post the live positions.
+// serve also hosts the latest positions locally for overlays, replay reads a recording instead of the simulator.
+func feed(mode string, args []string) error {
+	var speed float64
+
+	flags := newFlags(mode)
+	flags.StringVar(&ibtFile, "file", "", "Test data, e.g. race.bin, or a recording")
+	flags.IntVar(&waitMilliseconds, "wait", defaultWaitMilliseconds, "Delay in milliseconds to wait for iRacing data")
+	flags.IntVar(&minIntervalMilliseconds, "interval", defaultMinIntervalMilliseconds, "Minimum milliseconds between posts when positions change")
+	flags.IntVar(&refreshSeconds, "refresh", defaultRefreshSeconds, "Refresh positions every n seconds when nothing changes")
+	flags.BoolVar(&redact, "redact", false, "Obfuscate driver names for testing")
+	flags.BoolVar(&supervise, "supervise", false, "Keep running across sessions until stopped with Ctrl-C")
+	flags.Var(&sinkSpecs, "sink", "Send payloads to this destination, e.g. file:///tmp/live.jsonl or mqtt://broker/vcrlive (repeatable)")
+	flags.StringVar(&listen, "listen", defaultListen, "Address for the local server with serve")
+	flags.StringVar(&metricsAddress, "metrics", "", "Serve Prometheus metrics on http://address/metrics, e.g. 127.0.0.1:9090")
+	flags.StringVar(&logLevel, "log-level", "info", "Log level, "+strings.Join(logging.Levels(), ", "))
+	flags.StringVar(&logFormat, "log-format", logging.FormatText, "Log format, "+strings.Join(logging.Formats(), " or "))
+	flags.StringVar(&configFile, "config", "", "Configuration file (default $"+config.EnvConfig+" or "+config.DefaultFile+" if present)")
+	flags.StringVar(&profileName, "profile", "", "Profile in the configuration file (default $"+config.EnvProfile+" or the file's default_profile)")
+
+	if mode == "replay" {
+		flags.Float64Var(&speed, "speed", 1, "Playback speed, 2 for twice real time, 0 for as fast as possible")
+	}
+
+	_ = flags.Parse(args)
+	positional := flags.Args()
+
+	if mode == "replay" {
+		if len(positional) == 0 {
+			return errors.New("replay needs the recording to play, see -h")
+		}
+
+		_ = flags.Set("file", positional[0])
+		positional = positional[1:]
+	}
+
+	profile, err := loadProfile(flags, positional)
+	if err != nil {
+		return err
+	}
+
+	err = logging.Setup(os.Stderr, profile.LogLevel, profile.LogFormat)
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C or a service stop ends the feed cleanly with a final Stopped payload
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fanout, err := openSinks(profile, mode != "serve")
+	if err != nil {
+		return err
+	}
+
+	defer fanout.Close()
+
+	var (
+		calculator *fuel.Calculator
+		mapper     *trackmap.Mapper
+		board      *relative.Board
+	)
+
+	// The player's tyres go to the team in the positions, wherever they are sent
+	tracker := tyres.NewTracker()
+
+	if mode == "serve" {
+		// The server lives across sessions so overlays keep working all evening
+		profile.Supervise = true
+		srv := server.NewServer()
+		fanout.Add(&sinks.Config{Name: "serve"}, srv)
+
+		// Fuel is only for the driver's second screen, it is not sent anywhere else
+		calculator = fuel.NewCalculator()
+		srv.Handle("GET /fuel", server.JSON(calculator.Estimate))
+		srv.Handle("GET /tyres", server.JSON(tracker.Report))
+
+		// The map is updated every sample, far more often than the positions are posted
+		mapper = trackmap.NewMapper(profile.TrackMaps)
+		srv.Handle("GET /map", server.JSON(mapper.Positions))
+		srv.Handle("GET /map/outline", server.JSON(mapper.Outline))
+		srv.Handle("GET /map/stream", server.Stream("map", mapper.Positions, profile.Wait))
+
+		board = relative.NewBoard()
+		srv.Handle("GET /relative", server.Query(board.Query))
+
+		go func() {
+			err := srv.ListenAndServe(ctx, profile.Listen)
+			if err != nil {
+				slog.Error("Local server stopped", "err", err)
+				stop()
+			}
+		}()
+	}
+
+	if profile.Metrics != "" {
+		go func() {
+			err := serveMetrics(ctx, profile.Metrics)
+			if err != nil {
+				slog.Error("Metrics server stopped", "err", err)
+			}
+		}()
+	}
+
+	sdk, done, err := openSDK(profile.File, speed)
+	if err != nil {
+		return err
+	}
+
+	defer sdk.Close()
+
+	// A recording stops when it has been played
+	go func() {
+		select {
+		case <-done:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	telemetry := telemetry.NewTelemetry(sdk, fanout, profile.Redact).WithRaceEvents().WithRaceControl().WithRatingEstimates().WithWeather().
+		WithTyres(tracker)
+
+	if profile.Championship != nil {
+		championship, err := standings.New(profile.Championship.Rules(), profile.Championship.History)
+		if err != nil {
+			return fmt.Errorf("can not load the championship, err:%w", err)
+		}
+
+		telemetry.WithChampionship(championship)
+	}
+
+	// Fuel, the track map and the relative are only for the driver's second screen
+	if mode == "serve" {
+		telemetry.WithFuel(calculator).WithTrackMap(mapper).WithRelative(board)
+	}
+
+	// Keep sending telemetry data until the simulator session ends or we are told to stop
+	run := telemetry.Run
+	if profile.Supervise {
+		run = telemetry.Supervise
+	}
+
+	err = run(ctx, int(profile.Wait.Milliseconds()), int(profile.MinInterval.Milliseconds()), int(profile.Refresh/time.Second))
+	if err != nil {
+		slog.Error("Telemetry stopped", "err", err)
+	}
+
+	return nil
+}
+
+// loadProfile reads the configuration file and environment, then applies any flags given on the command line
+func loadProfile(flags *flag.FlagSet, positional []string) (*config.Profile, error) {
+	profile, err := config.Load(config.Path(configFile), profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "file":
+			profile.File = ibtFile
+		case "wait":
+			profile.Wait = time.Duration(waitMilliseconds) * time.Millisecond
+		case "interval":
+			profile.MinInterval = time.Duration(minIntervalMilliseconds) * time.Millisecond
+		case "refresh":
+			profile.Refresh = time.Duration(refreshSeconds) * time.Second
+		case "redact":
+			profile.Redact = redact
+		case "supervise":
+			profile.Supervise = supervise
+		case "listen":
+			profile.Listen = listen
+		case "metrics":
+			profile.Metrics = metricsAddress
+		case "log-level":
+			profile.LogLevel = logLevel
+		case "log-format":
+			profile.LogFormat = logFormat
+		}
+	})
+
+	if len(positional) > 0 {
+		profile.URL = positional[0]
+	}
+
+	for _, spec := range sinkSpecs {
+		profile.Sinks = append(profile.Sinks, config.Sink{Spec: spec})
+	}
+
+	err = profile.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings:\n%w", err)
+	}
+
+	return profile, nil
+}
+
+// serveMetrics serves the Prometheus metrics on address until ctx is cancelled
+func serveMetrics(ctx context.Context, address string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: metricsTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), metricsTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving metrics", "url", "http://"+address+"/metrics")
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// openSinks starts the sinks in the profile, or just the console if there are none and console is set
+func openSinks(profile *config.Profile, console bool) (*sinks.Fanout, error) {
+	configs, err := profile.SinkConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configs) == 0 && console {
+		configs = append(configs, &sinks.Config{Name: "stdout", Type: "stdout"})
+	}
+
+	fanout := sinks.NewFanout()
+
+	for _, cfg := range configs {
+		sink, err := sinks.Open(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("can not open sink %s, err:%w", cfg.Name, err)
+		}
+
+		fanout.Add(cfg, sink)
+	}
+
+	return fanout, nil
+}
+
+// configCommand implements vcrlive config validate, checking every profile unless one is named
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New("expected config validate, see -h")
+	}
+
+	flags := newFlags("config")
+	flags.StringVar(&configFile, "config", "", "Configuration file")
+	flags.StringVar(&profileName, "profile", "", "Only validate this profile")
+	_ = flags.Parse(args[1:])
+
+	path := config.Path(configFile)
+	if path == "" {
+		return fmt.Errorf("no configuration file, use -config or $%s, or create %s", config.EnvConfig, config.DefaultFile)
+	}
+
+	b, err := os.ReadFile(path) //nolint:gosec // user supplied configuration
+	if err != nil {
+		return err
+	}
+
+	names := []string{profileName}
+
+	if profileName == "" {
+		names, err = config.ListProfiles(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	// A file with no profiles only has defaults
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	failed := 0
+
+	for _, name := range names {
+		profile, err := config.Parse(b, name)
+		if err == nil {
+			err = config.ApplyEnv(&profile, os.LookupEnv)
+		}
+
+		if err == nil {
+			err = profile.Validate()
+		}
+
+		if name == "" {
+			name = "defaults"
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: profile %s:\n  %s\n", path, name, strings.ReplaceAll(err.Error(), "\n", "\n  "))
+
+			failed++
+
+			continue
+		}
+
+		fmt.Printf("%s: profile %s OK\n", path, name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%s: %d of %d profiles are invalid", path, failed, len(names))
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package standings
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// Championship scores a league's races, remembering the final results of each round in a history file
+type Championship struct {
+	rules  Rules
+	path   string // history file, the rounds are only kept in memory if empty
+	rounds []model.Round
+}
+
+// history is the layout of the history file
+type history struct {
+	Rounds []model.Round `json:"rounds"`
+}
+
+// New returns a championship scored by rules with the rounds recorded in path, which need not exist yet
+func New(rules Rules, path string) (*Championship, error) {
+	c := &Championship{rules: rules, path: path}
+
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path) //nolint:gosec // user supplied history
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var h history
+
+	err = json.Unmarshal(b, &h)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	c.rounds = h.Rounds
+
+	return c, nil
+}
+
+// Table returns the championship of the round's season counting the round, in place of any recorded result of the
+// same race week
+func (c *Championship) Table(round model.Round) *model.Championship {
+	before := c.season(round)
+	after := append(slices.Clone(before), round)
+
+	previous := map[entry]int{}
+	for _, s := range c.rules.table(before) {
+		previous[entry{s.UserID, s.CarClassID}] = s.Position
+	}
+
+	racePoints := c.rules.score(&round)
+	standings := c.rules.table(after)
+
+	for i := range standings {
+		e := entry{standings[i].UserID, standings[i].CarClassID}
+		standings[i].PositionBefore = previous[e]
+		standings[i].RacePoints = racePoints[e]
+	}
+
+	return &model.Championship{
+		SeasonID:  round.SeasonID,
+		RaceWeek:  round.RaceWeek,
+		Final:     round.Final,
+		Rounds:    len(after),
+		Standings: standings,
+	}
+}
+
+// Record keeps the result of a round, replacing any earlier result of the same race week, and saves the history
+func (c *Championship) Record(round model.Round) error {
+	c.rounds = slices.DeleteFunc(c.rounds, func(r model.Round) bool {
+		return r.SeasonID == round.SeasonID && r.RaceWeek == round.RaceWeek
+	})
+	c.rounds = append(c.rounds, round)
+
+	slices.SortFunc(c.rounds, func(a, b model.Round) int {
+		return cmp.Or(cmp.Compare(a.SeasonID, b.SeasonID), cmp.Compare(a.RaceWeek, b.RaceWeek))
+	})
+
+	if c.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(history{c.rounds}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write alongside and rename so a crash never leaves half a history
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), c.path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("can not save championship history %s, err:%w", c.path, err)
+	}
+
+	return nil
+}
+
+// season returns the recorded rounds of the round's season, other than its race week
+func (c *Championship) season(round model.Round) []model.Round {
+	var rounds []model.Round
+
+	for _, r := range c.rounds {
+		if r.SeasonID == round.SeasonID && r.RaceWeek != round.RaceWeek {
+			rounds = append(rounds, r)
+		}
+	}
+
+	return rounds
+}
@@ -0,0 +1,73 @@
+package standings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChampionship(t *testing.T) {
+	rules := Rules{Points: []int{25, 18, 15}}
+
+	round := func(seasonID int, raceWeek int, first int, second int) model.Round {
+		return model.Round{SeasonID: seasonID, RaceWeek: raceWeek, SubSessionID: raceWeek * 100, Final: true, Results: []model.Result{
+			{UserID: first, UserName: "Driver", CarClassID: 84, ClassPosition: 1},
+			{UserID: second, UserName: "Driver", CarClassID: 84, ClassPosition: 2},
+		}}
+	}
+
+	t.Run("A projection should show the table if the race ended now", func(t *testing.T) {
+		c, err := New(rules, "")
+		require.NoError(t, err)
+		require.NoError(t, c.Record(round(5582, 1, 1, 2)))
+		require.NoError(t, c.Record(round(5581, 12, 2, 1)))
+
+		live := round(5582, 2, 2, 1)
+		live.Final = false
+
+		table := c.Table(live)
+		assert.Equal(t, 5582, table.SeasonID)
+		assert.Equal(t, 2, table.RaceWeek)
+		assert.False(t, table.Final)
+		assert.Equal(t, 2, table.Rounds)
+		assert.Equal(t, []model.Standing{
+			{UserID: 1, UserName: "Driver", CarClassID: 84, Position: 1, PositionBefore: 1, Points: 43, RacePoints: 18},
+			{UserID: 2, UserName: "Driver", CarClassID: 84, Position: 2, PositionBefore: 2, Points: 43, RacePoints: 25},
+		}, table.Standings)
+	})
+
+	t.Run("Recorded rounds should be saved and a rerun replace the earlier result", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "championship.json")
+
+		c, err := New(rules, path)
+		require.NoError(t, err)
+		require.NoError(t, c.Record(round(5582, 2, 1, 2)))
+		require.NoError(t, c.Record(round(5582, 1, 1, 2)))
+		require.NoError(t, c.Record(round(5582, 2, 2, 1)))
+
+		reloaded, err := New(rules, path)
+		require.NoError(t, err)
+		require.Len(t, reloaded.rounds, 2)
+		assert.Equal(t, []int{1, 2}, []int{reloaded.rounds[0].RaceWeek, reloaded.rounds[1].RaceWeek})
+		assert.Equal(t, 2, reloaded.rounds[1].Results[0].UserID)
+		assert.False(t, reloaded.rounds[0].Final, "not saved")
+
+		table := reloaded.Table(round(5582, 3, 3, 1))
+		assert.Equal(t, 3, table.Rounds)
+		assert.Equal(t, 1, table.Standings[0].UserID)
+		assert.Equal(t, 61, table.Standings[0].Points)
+		assert.Equal(t, 0, table.Standings[2].PositionBefore, "new driver")
+	})
+
+	t.Run("A corrupt history should be reported", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "championship.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		_, err := New(rules, path)
+		assert.ErrorContains(t, err, path)
+	})
+}
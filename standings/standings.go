@@ -0,0 +1,202 @@
+// Package standings scores league races into championship tables, projected live during a race and final after it
+package standings
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// Rules of a championship
+type Rules struct {
+	Points      []int         // for 1st, 2nd, 3rd... in class
+	ClassPoints map[int][]int // points tables for particular classes, by car class ID
+	FastestLap  int           // bonus for the fastest lap in class
+	Pole        int           // bonus for the fastest qualifier in class
+	Drops       int           // worst rounds not counted
+	Penalties   []Penalty
+}
+
+// Penalty deducts points from a driver after the drop rounds
+type Penalty struct {
+	UserID   int
+	SeasonID int // any season if 0
+	RaceWeek int // the round the penalty was given in
+	Points   int
+	Reason   string
+}
+
+// entry is a driver in a class, the unit the championship is scored by
+type entry struct {
+	userID  int
+	classID int
+}
+
+// tally is an entry's points so far
+type tally struct {
+	userName  string
+	scores    map[int]int // by race week
+	finishes  []int       // class positions, for countback
+	penalties int
+	points    int
+	dropped   int
+}
+
+// score returns the points of every entry in a round
+func (r *Rules) score(round *model.Round) map[entry]int {
+	fastest := map[int]float64{}
+
+	for _, result := range round.Results {
+		if result.FastestTime > 0 && (fastest[result.CarClassID] == 0 || result.FastestTime < fastest[result.CarClassID]) {
+			fastest[result.CarClassID] = result.FastestTime
+		}
+	}
+
+	points := make(map[entry]int, len(round.Results))
+
+	for _, result := range round.Results {
+		table := r.Points
+		if classPoints, ok := r.ClassPoints[result.CarClassID]; ok {
+			table = classPoints
+		}
+
+		p := 0
+		if result.ClassPosition > 0 && result.ClassPosition <= len(table) {
+			p = table[result.ClassPosition-1]
+		}
+
+		if result.FastestTime > 0 && result.FastestTime == fastest[result.CarClassID] {
+			p += r.FastestLap
+		}
+
+		if result.Pole {
+			p += r.Pole
+		}
+
+		points[entry{result.UserID, result.CarClassID}] = p
+	}
+
+	return points
+}
+
+// table scores the rounds of one season into standings by class then position
+func (r *Rules) table(rounds []model.Round) []model.Standing {
+	tallies := map[entry]*tally{}
+	held := map[int][]int{} // race weeks each class raced
+
+	for i := range rounds {
+		for e, points := range r.score(&rounds[i]) {
+			if !slices.Contains(held[e.classID], rounds[i].RaceWeek) {
+				held[e.classID] = append(held[e.classID], rounds[i].RaceWeek)
+			}
+
+			t, ok := tallies[e]
+			if !ok {
+				t = &tally{scores: map[int]int{}}
+				tallies[e] = t
+			}
+
+			t.scores[rounds[i].RaceWeek] = points
+		}
+
+		for _, result := range rounds[i].Results {
+			t := tallies[entry{result.UserID, result.CarClassID}]
+			t.userName = result.UserName
+
+			if result.ClassPosition > 0 {
+				t.finishes = append(t.finishes, result.ClassPosition)
+			}
+		}
+	}
+
+	season := 0
+	if len(rounds) > 0 {
+		season = rounds[0].SeasonID
+	}
+
+	for e, t := range tallies {
+		r.total(t, e, season, held[e.classID])
+	}
+
+	entries := make([]entry, 0, len(tallies))
+	for e := range tallies {
+		entries = append(entries, e)
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		ta, tb := tallies[a], tallies[b]
+
+		return cmp.Or(
+			cmp.Compare(a.classID, b.classID),
+			cmp.Compare(tb.points, ta.points),
+			countback(ta.finishes, tb.finishes),
+			cmp.Compare(a.userID, b.userID),
+		)
+	})
+
+	standings := make([]model.Standing, 0, len(entries))
+	position := 0
+
+	for i, e := range entries {
+		if i == 0 || entries[i-1].classID != e.classID {
+			position = 0
+		}
+
+		position++
+		t := tallies[e]
+
+		standings = append(standings, model.Standing{
+			UserID:     e.userID,
+			UserName:   t.userName,
+			CarClassID: e.classID,
+			Position:   position,
+			Points:     t.points,
+			Dropped:    t.dropped,
+			Penalties:  t.penalties,
+		})
+	}
+
+	return standings
+}
+
+// total adds up the points of an entry from the rounds its class held, dropping the worst and deducting penalties
+func (r *Rules) total(t *tally, e entry, season int, held []int) {
+	// Missed rounds score nothing and are the first to be dropped
+	scores := make([]int, 0, len(held))
+	for _, raceWeek := range held {
+		scores = append(scores, t.scores[raceWeek])
+	}
+
+	slices.Sort(scores)
+
+	// At least one round always counts
+	drops := min(r.Drops, len(scores)-1)
+	for i, points := range scores {
+		if i < drops {
+			t.dropped += points
+		} else {
+			t.points += points
+		}
+	}
+
+	for _, penalty := range r.Penalties {
+		if penalty.UserID == e.userID && (penalty.SeasonID == 0 || penalty.SeasonID == season) && slices.Contains(held, penalty.RaceWeek) {
+			t.penalties += penalty.Points
+		}
+	}
+
+	t.points -= t.penalties
+	slices.Sort(t.finishes)
+}
+
+// countback compares sorted class finishes, the better first. More wins is better, then more seconds and so on.
+func countback(a, b []int) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return cmp.Compare(a[i], b[i])
+		}
+	}
+
+	return cmp.Compare(len(b), len(a))
+}
@@ -0,0 +1,115 @@
+package standings
+
+import (
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	const (
+		gt3 = 84
+		gt4 = 83
+	)
+
+	rules := Rules{
+		Points:      []int{25, 18, 15, 12, 10},
+		ClassPoints: map[int][]int{gt4: {10, 6, 3}},
+		FastestLap:  1,
+		Pole:        2,
+	}
+
+	round := func(raceWeek int, results ...model.Result) model.Round {
+		return model.Round{SeasonID: 5582, RaceWeek: raceWeek, Results: results}
+	}
+
+	result := func(userID int, classID int, classPosition int, fastest float64) model.Result {
+		return model.Result{UserID: userID, UserName: "Driver", CarClassID: classID, ClassPosition: classPosition, FastestTime: fastest}
+	}
+
+	points := func(standings []model.Standing) map[int]int {
+		p := map[int]int{}
+		for _, s := range standings {
+			p[s.UserID] = s.Points
+		}
+
+		return p
+	}
+
+	t.Run("Each class should be scored by its points table with bonuses", func(t *testing.T) {
+		pole := result(1, gt3, 2, 125.4)
+		pole.Pole = true
+
+		score := rules.score(&model.Round{Results: []model.Result{
+			result(2, gt3, 1, 125.1), pole, result(3, gt3, 6, 0),
+			result(4, gt4, 1, 131.2), result(5, gt4, 2, 130.9), result(6, gt4, 0, 0),
+		}})
+
+		assert.Equal(t, map[entry]int{
+			{2, gt3}: 26, {1, gt3}: 20, {3, gt3}: 0,
+			{4, gt4}: 10, {5, gt4}: 7, {6, gt4}: 0,
+		}, score)
+	})
+
+	t.Run("Standings should be by class then points", func(t *testing.T) {
+		standings := rules.table([]model.Round{round(1, result(1, gt3, 2, 0), result(2, gt3, 1, 0), result(4, gt4, 1, 0))})
+
+		assert.Equal(t, []model.Standing{
+			{UserID: 4, UserName: "Driver", CarClassID: gt4, Position: 1, Points: 10},
+			{UserID: 2, UserName: "Driver", CarClassID: gt3, Position: 1, Points: 25},
+			{UserID: 1, UserName: "Driver", CarClassID: gt3, Position: 2, Points: 18},
+		}, standings)
+	})
+
+	t.Run("Ties should be broken by countback", func(t *testing.T) {
+		pole := result(3, gt3, 1, 125.1)
+		pole.Pole = true
+
+		standings := rules.table([]model.Round{
+			round(1, pole, result(1, gt3, 2, 125.3), result(2, gt3, 3, 125.5)),
+			round(2, result(2, gt3, 1, 0), result(1, gt3, 5, 0), result(3, gt3, 6, 0)),
+		})
+
+		// 3 and 1 both have 28 points, 3 has a win
+		assert.Equal(t, []int{2, 3, 1}, []int{standings[0].UserID, standings[1].UserID, standings[2].UserID})
+		assert.Equal(t, 28, standings[1].Points)
+		assert.Equal(t, 28, standings[2].Points)
+	})
+
+	t.Run("The worst rounds, including those missed, should be dropped", func(t *testing.T) {
+		drops := rules
+		drops.Drops = 1
+
+		standings := drops.table([]model.Round{
+			round(1, result(1, gt3, 1, 0), result(2, gt3, 2, 0)),
+			round(2, result(1, gt3, 5, 0), result(2, gt3, 1, 0)),
+			round(3, result(2, gt3, 1, 0)),
+		})
+
+		assert.Equal(t, map[int]int{1: 35, 2: 50}, points(standings))
+		assert.Equal(t, 0, standings[1].Dropped)
+		assert.Equal(t, 18, standings[0].Dropped)
+
+		// Only one round, nothing to drop
+		assert.Equal(t, map[int]int{1: 25}, points(drops.table([]model.Round{round(1, result(1, gt3, 1, 0))})))
+	})
+
+	t.Run("Penalties should be deducted after drops", func(t *testing.T) {
+		penalties := rules
+		penalties.Drops = 1
+		penalties.Penalties = []Penalty{
+			{UserID: 1, RaceWeek: 2, Points: 5, Reason: "Avoidable contact"},
+			{UserID: 1, SeasonID: 9999, RaceWeek: 1, Points: 50, Reason: "Another season"},
+			{UserID: 2, RaceWeek: 7, Points: 50, Reason: "Not raced yet"},
+		}
+
+		standings := penalties.table([]model.Round{
+			round(1, result(1, gt3, 1, 0), result(2, gt3, 2, 0)),
+			round(2, result(1, gt3, 2, 0), result(2, gt3, 1, 0)),
+		})
+
+		assert.Equal(t, map[int]int{1: 20, 2: 25}, points(standings))
+		assert.Equal(t, 5, standings[1].Penalties)
+	})
+}
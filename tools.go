@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ianhaycox/vcrlive/irsdk"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// source is where the diagnostic commands read telemetry from, the simulator unless a file is given
+type source struct {
+	file  string
+	speed float64
+	wait  time.Duration
+}
+
+func sourceFlags(flags *flag.FlagSet) *source {
+	src := &source{}
+
+	flags.StringVar(&src.file, "file", "", "Read a snapshot, e.g. race.bin, or a recording instead of the simulator")
+	flags.Float64Var(&src.speed, "speed", 1, "Playback speed of a recording, 0 for as fast as possible")
+	flags.DurationVar(&src.wait, "wait", defaultWaitMilliseconds*time.Millisecond, "Time to wait for iRacing data")
+
+	return src
+}
+
+// openSDK reads from the simulator's shared memory, or a file which may be a snapshot or a recording.
+// The channel is closed when a recording has been played to the end.
+func openSDK(file string, speed float64) (*irsdk.IRSDK, <-chan struct{}, error) {
+	if file == "" {
+		return irsdk.NewIrSDK(nil), nil, nil
+	}
+
+	f, err := os.Open(file) //nolint:gosec // user supplied test data
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !irsdk.IsRecording(f) {
+		return irsdk.NewIrSDK(f), nil, nil
+	}
+
+	player, err := irsdk.NewPlayer(f, speed)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return irsdk.NewIrSDK(player), player.Done(), nil
+}
+
+// connected waits a moment for the simulator, failing if there is no telemetry
+func connected(sdk *irsdk.IRSDK, wait time.Duration) error {
+	sdk.WaitForData(wait)
+
+	if sdk.GetLastVersion() < 0 {
+		return errors.New("no telemetry, is iRacing running?")
+	}
+
+	return nil
+}
+
+// record writes the simulator's telemetry to a file until Ctrl-C or -duration
+func record(args []string) error {
+	flags := newFlags("record")
+	src := sourceFlags(flags)
+	duration := flags.Duration("duration", 0, "Stop recording after this long, e.g. 30m")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("record needs the file to write, see -h")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *duration > 0 {
+		ctx, stop = context.WithTimeout(ctx, *duration)
+		defer stop()
+	}
+
+	sdk, done, err := openSDK(src.file, src.speed)
+	if err != nil {
+		return err
+	}
+
+	defer sdk.Close()
+
+	out, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	rec, err := irsdk.NewRecorder(sdk, out)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Recording to %s, Ctrl-C to stop\n", flags.Arg(0))
+
+	for ctx.Err() == nil {
+		select {
+		case <-done:
+			stop()
+		default:
+		}
+
+		if !sdk.WaitForData(src.wait) {
+			continue
+		}
+
+		err = rec.Capture()
+		if err != nil {
+			break
+		}
+	}
+
+	err = errors.Join(err, rec.Close())
+
+	fmt.Fprintf(os.Stderr, "Recorded %d ticks and %d session updates over %s\n", rec.Stats.Ticks, rec.Stats.SessionUpdates, rec.Stats.Elapsed.Round(time.Second))
+
+	return err
+}
+
+// vars lists every telemetry variable
+func vars(args []string) error {
+	flags := newFlags("vars")
+	src := sourceFlags(flags)
+	_ = flags.Parse(args)
+
+	sdk, _, err := openSDK(src.file, src.speed)
+	if err != nil {
+		return err
+	}
+
+	defer sdk.Close()
+
+	err = connected(sdk, src.wait)
+	if err != nil {
+		return err
+	}
+
+	variables, err := sdk.GetVars()
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(variables, func(a, b irsdk.Variable) int { return strings.Compare(a.Name, b.Name) })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // padding
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tCOUNT\tUNIT\tDESCRIPTION")
+
+	for _, v := range variables {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", v.Name, v.VarType, v.Count, v.Unit, v.Desc)
+	}
+
+	return w.Flush()
+}
+
+// watch prints the named variables on every tick until Ctrl-C
+func watch(args []string) error {
+	flags := newFlags("watch")
+	src := sourceFlags(flags)
+	_ = flags.Parse(args)
+
+	names := flags.Args()
+	if len(names) == 0 {
+		return errors.New("watch needs the variables to print, see vars for the names")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sdk, done, err := openSDK(src.file, src.speed)
+	if err != nil {
+		return err
+	}
+
+	defer sdk.Close()
+
+	err = connected(sdk, src.wait)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := sdk.GetVar(name); err != nil {
+			return err
+		}
+	}
+
+	for {
+		line := []string{fmt.Sprintf("%8d", sdk.GetLastVersion())}
+
+		for _, name := range names {
+			v, err := sdk.GetVar(name)
+			if err != nil {
+				return err
+			}
+
+			line = append(line, name+"="+format(v))
+		}
+
+		fmt.Println(strings.Join(line, " "))
+
+		for !sdk.WaitForData(src.wait) {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-done:
+				return nil
+			default:
+			}
+		}
+	}
+}
+
+func format(v irsdk.Variable) string {
+	if v.Count == 1 {
+		return v.String()
+	}
+
+	return fmt.Sprint(v.Values)
+}
+
+// session prints the session YAML or the value at a path such as DriverInfo:Drivers:CarIdx:{1}UserName:
+func session(args []string) error {
+	flags := newFlags("session")
+	src := sourceFlags(flags)
+	_ = flags.Parse(args)
+
+	sdk, _, err := openSDK(src.file, src.speed)
+	if err != nil {
+		return err
+	}
+
+	defer sdk.Close()
+
+	err = connected(sdk, src.wait)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Println(sdk.GetYaml())
+		return nil
+	}
+
+	for _, path := range flags.Args() {
+		value, err := sdk.GetSessionData(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		fmt.Println(value)
+	}
+
+	return nil
+}
+
+// inspect describes the shared memory layout of the simulator or a file
+func inspect(args []string) error {
+	flags := newFlags("inspect")
+	_ = flags.Parse(args)
+
+	var (
+		r      irsdk.Source
+		player *irsdk.Player
+	)
+
+	switch flags.NArg() {
+	case 0:
+		sdk := irsdk.NewIrSDK(nil)
+		defer sdk.Close()
+
+		r = sdk.Reader()
+	case 1:
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		r = f
+
+		if irsdk.IsRecording(f) {
+			player, err = irsdk.NewPlayer(f, 0)
+			if err != nil {
+				return err
+			}
+
+			r = player
+		}
+	default:
+		return errors.New("inspect one file at a time")
+	}
+
+	info := irsdk.Inspect(r)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // padding
+	_, _ = fmt.Fprintf(w, "Version\t%d\n", info.Version)
+	_, _ = fmt.Fprintf(w, "Connected\t%t\n", info.Connected)
+	_, _ = fmt.Fprintf(w, "Tick rate\t%d/s\n", info.TickRate)
+	_, _ = fmt.Fprintf(w, "Session info\tupdate %d, %d bytes at %d\n", info.SessionInfoUpdate, info.SessionInfoLen, info.SessionInfoOffset)
+	_, _ = fmt.Fprintf(w, "Variables\t%d headers at %d\n", info.NumVars, info.VarHeaderOffset)
+	_, _ = fmt.Fprintf(w, "Buffers\t%d of %d bytes\n", info.NumBuf, info.BufLen)
+
+	for i, vb := range info.Buffers {
+		_, _ = fmt.Fprintf(w, "  %d\ttick %d at %d\n", i, vb.TickCount, vb.Offset())
+	}
+
+	if player != nil {
+		// Play it all to count what was recorded
+		for player.Next(0) {
+		}
+
+		stats := player.Stats
+		_, _ = fmt.Fprintf(w, "Recording\t%s, %d ticks, %d session updates, %d memory images\n",
+			stats.Elapsed.Round(time.Millisecond), stats.Ticks, stats.SessionUpdates, stats.Images)
+
+		if stats.Elapsed > 0 {
+			_, _ = fmt.Fprintf(w, "Sample rate\t%.1f/s\n", float64(stats.Ticks)/stats.Elapsed.Seconds())
+		}
+	}
+
+	err := w.Flush()
+	if err == nil && player != nil {
+		// What was played is still described
+		err = player.Err()
+	}
+
+	return err
+}
+
+// schema prints the JSON Schema of the payloads
+func schema(args []string) error {
+	flags := newFlags("schema")
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		return errors.New("schema takes at most one kind, see -h")
+	}
+
+	b, err := model.Schema(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(b))
+
+	return nil
+}
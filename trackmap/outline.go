@@ -0,0 +1,247 @@
+package trackmap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// points is how many points of the outline are kept around a lap
+	points = 500
+
+	// maxStep is the most of a lap the player may cover between samples of a lap being learned, more is a reset or
+	// a tow
+	maxStep = 0.05
+
+	// minSamples is the fewest samples of a lap to learn from
+	minSamples = 100
+
+	// earthRadius in metres, to place latitude and longitude
+	earthRadius = 6371000
+
+	// lineWindow is how close to the start/finish line, as a fraction of the lap, a crossing is seen
+	lineWindow = 0.1
+)
+
+// sample is where the player was at a lap distance, in metres from the start of the lap
+type sample struct {
+	pct, x, y float64
+}
+
+// learner follows the player around a clean lap, from line to line on track, to learn the outline
+type learner struct {
+	started  bool // a sample has been seen
+	learning bool // the lap started at the line and is still clean
+	lastTime float64
+	lastPct  float64
+	lastVel  [2]float64 // m/s across the world
+	x, y     float64
+	origin   [2]float64 // latitude and longitude of the start of the lap
+	samples  []sample
+}
+
+// add follows the player, returning the outline when a clean lap has been completed
+func (l *learner) add(sessionTime float64, p *Player) [][2]float64 {
+	if l.started && sessionTime == l.lastTime {
+		return nil
+	}
+
+	vel := [2]float64{
+		p.VelocityX*math.Cos(p.Yaw) - p.VelocityY*math.Sin(p.Yaw),
+		p.VelocityX*math.Sin(p.Yaw) + p.VelocityY*math.Cos(p.Yaw),
+	}
+
+	defer func() {
+		l.started, l.lastTime, l.lastPct, l.lastVel = true, sessionTime, p.LapDistPct, vel
+	}()
+
+	if !p.OnTrack || p.OnPitRoad || sessionTime < l.lastTime {
+		l.learning = false
+		return nil
+	}
+
+	// Between samples the player moved at about the average of the two velocities, unless iRacing gives the position
+	dt := sessionTime - l.lastTime
+	l.x += (l.lastVel[0] + vel[0]) / 2 * dt
+	l.y += (l.lastVel[1] + vel[1]) / 2 * dt
+
+	if p.Lat != 0 || p.Lon != 0 {
+		l.x, l.y = l.project(p.Lat, p.Lon)
+	}
+
+	var outline [][2]float64
+
+	switch {
+	case l.started && l.lastPct > 1-lineWindow && p.LapDistPct < lineWindow:
+		if l.learning && len(l.samples) >= minSamples {
+			l.samples = append(l.samples, sample{pct: p.LapDistPct + 1, x: l.x, y: l.y})
+			outline = shape(l.samples)
+		}
+
+		l.learning, l.x, l.y, l.origin = true, 0, 0, [2]float64{p.Lat, p.Lon}
+		l.samples = []sample{{pct: p.LapDistPct}}
+	case !l.learning:
+		return nil
+	case p.LapDistPct < l.lastPct || p.LapDistPct-l.lastPct > maxStep:
+		l.learning = false
+		return nil
+	default:
+		l.samples = append(l.samples, sample{pct: p.LapDistPct, x: l.x, y: l.y})
+	}
+
+	return outline
+}
+
+// project places a latitude and longitude in metres from the start of the lap, east and north
+func (l *learner) project(lat, lon float64) (float64, float64) {
+	toRadians := math.Pi / 180 //nolint:mnd // degrees
+
+	return (lon - l.origin[1]) * toRadians * earthRadius * math.Cos(l.origin[0]*toRadians),
+		(lat - l.origin[0]) * toRadians * earthRadius
+}
+
+// shape turns the samples of a lap into an outline. The small error left by integrating the velocity is spread
+// around the lap so the outline closes, then the outline is scaled to fit a unit square.
+func shape(samples []sample) [][2]float64 {
+	first, last := samples[0], samples[len(samples)-1]
+	span := last.pct - first.pct
+
+	for i := range samples {
+		f := (samples[i].pct - first.pct) / span
+		samples[i].x -= (last.x - first.x) * f
+		samples[i].y -= (last.y - first.y) * f
+	}
+
+	outline := make([][2]float64, points)
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+
+	for i := range outline {
+		pct := float64(i) / points
+		if pct < first.pct {
+			pct++
+		}
+
+		// Points just before the first sample are a little beyond the last
+		j := min(max(sort.Search(len(samples), func(j int) bool { return samples[j].pct >= pct }), 1), len(samples)-1)
+		a, b := samples[j-1], samples[j]
+
+		f := 0.0
+		if b.pct > a.pct {
+			f = (pct - a.pct) / (b.pct - a.pct)
+		}
+
+		x, y := a.x+(b.x-a.x)*f, a.y+(b.y-a.y)*f
+		outline[i] = [2]float64{x, y}
+
+		minX, minY, maxX, maxY = min(minX, x), min(minY, y), max(maxX, x), max(maxY, y)
+	}
+
+	scale := max(maxX-minX, maxY-minY)
+	if scale == 0 {
+		return nil
+	}
+
+	// Centre the shorter side, y is down the screen
+	offsetX, offsetY := (1-(maxX-minX)/scale)/2, (1-(maxY-minY)/scale)/2 //nolint:mnd // centred
+
+	for i, p := range outline {
+		outline[i] = [2]float64{round(offsetX + (p[0]-minX)/scale), round(offsetY + (maxY-p[1])/scale)}
+	}
+
+	return outline
+}
+
+// path is where the outline of a track is kept, e.g. 168-grand-prix.json
+func path(dir string, trackID int, config string) string {
+	name := strconv.Itoa(trackID)
+
+	slug := strings.Trim(strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			return r
+		}
+
+		return '-'
+	}, strings.ToLower(config)), "-")
+
+	if slug != "" {
+		name += "-" + slug
+	}
+
+	return filepath.Join(dir, name+".json")
+}
+
+// load reads the outline of a track, nil if it has not been learned
+func load(dir string, trackID int, config string) (*Outline, error) {
+	if dir == "" {
+		return nil, nil //nolint:nilnil // not kept
+	}
+
+	file := path(dir, trackID, config)
+
+	b, err := os.ReadFile(file) //nolint:gosec // our own outlines
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil //nolint:nilnil // not learned yet
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var outline Outline
+
+	err = json.Unmarshal(b, &outline)
+	if err != nil || len(outline.Points) != points {
+		return nil, fmt.Errorf("%s is not a track outline, it will be learned again", file)
+	}
+
+	return &outline, nil
+}
+
+// save keeps a learned outline
+func save(dir string, outline Outline) error {
+	if dir == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(outline)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0o750) //nolint:mnd // owner and group
+	if err != nil {
+		return err
+	}
+
+	file := path(dir, outline.TrackID, outline.TrackConfig)
+
+	// Write alongside and rename so a crash never leaves half an outline
+	f, err := os.CreateTemp(dir, filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("can not save track outline %s, err:%w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package trackmap places every car on a map of the track from how far around the lap it is, learning the outline of
+// each track from the player's laps
+package trackmap
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sync"
+)
+
+// precision rounds map coordinates to ten thousandths, about half a metre on a 5km track
+const precision = 10000
+
+// Reading is where the cars are at a point in time
+type Reading struct {
+	TrackID     int
+	TrackConfig string
+	SessionTime float64 // seconds
+	Cars        []Car
+	Player      *Player // nil for a spectator
+}
+
+// Car is where a car is around the lap
+type Car struct {
+	CarIdx     int
+	LapDistPct float64 // 0 to 1 around the lap, negative when not in the world
+	OnPitRoad  bool
+}
+
+// Player is the player's car, which the outline is learned from
+type Player struct {
+	LapDistPct float64
+	OnTrack    bool // in the car and not in the garage
+	OnPitRoad  bool
+	Lat, Lon   float64 // degrees, 0 when iRacing does not give the position
+	VelocityX  float64 // m/s forwards
+	VelocityY  float64 // m/s to the left
+	Yaw        float64 // radians
+}
+
+// Positions is every car on the map
+type Positions struct {
+	TrackID     int        `json:"track_id"`
+	TrackConfig string     `json:"track_config_name"`
+	SessionTime float64    `json:"session_time"`
+	Learned     bool       `json:"learned"` // false while the outline is a circle
+	Cars        []Position `json:"cars"`
+}
+
+// Position is a car on the map, x and y are 0 to 1 from the top left
+type Position struct {
+	CarIdx     int     `json:"car_idx"`
+	LapDistPct float64 `json:"lap_dist_pct"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	OnPitRoad  bool    `json:"on_pit_road,omitempty"`
+}
+
+// Outline is the shape of the track to draw the cars on, as stored for each track
+type Outline struct {
+	TrackID     int          `json:"track_id"`
+	TrackConfig string       `json:"track_config_name"`
+	Learned     bool         `json:"learned"`
+	Points      [][2]float64 `json:"points"` // x, y at equal steps of lap distance from the start/finish line
+}
+
+// Mapper keeps the map of the current track. It is safe to read the positions while they are updated.
+type Mapper struct {
+	mu  sync.RWMutex
+	dir string // where learned outlines are kept, only in memory if empty
+	track
+}
+
+// track is what is known of the current track
+type track struct {
+	id        int
+	config    string
+	outline   [][2]float64 // nil until learned
+	learner   learner
+	positions Positions
+}
+
+func NewMapper(dir string) *Mapper {
+	return &Mapper{dir: dir, track: track{id: -1}}
+}
+
+// Update places the cars of a new reading, learning the outline from the player's car until it is known. Failing to
+// load or save the outline is returned as an error, the cars are still placed.
+func (m *Mapper) Update(r *Reading) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+
+	if r.TrackID != m.id || r.TrackConfig != m.config {
+		m.track = track{id: r.TrackID, config: r.TrackConfig}
+
+		outline, err := load(m.dir, r.TrackID, r.TrackConfig)
+		errs = append(errs, err)
+
+		if outline != nil {
+			m.outline = outline.Points
+		}
+	}
+
+	if m.outline == nil && r.Player != nil {
+		if points := m.learner.add(r.SessionTime, r.Player); points != nil {
+			m.outline = points
+			errs = append(errs, save(m.dir, m.current()))
+		}
+	}
+
+	m.positions = Positions{
+		TrackID:     r.TrackID,
+		TrackConfig: r.TrackConfig,
+		SessionTime: r.SessionTime,
+		Learned:     m.outline != nil,
+		Cars:        []Position{},
+	}
+
+	for _, car := range r.Cars {
+		if car.LapDistPct < 0 {
+			continue
+		}
+
+		x, y := m.place(car.LapDistPct)
+		m.positions.Cars = append(m.positions.Cars, Position{
+			CarIdx: car.CarIdx, LapDistPct: car.LapDistPct, X: x, Y: y, OnPitRoad: car.OnPitRoad,
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+// Positions returns every car on the map at the last update
+func (m *Mapper) Positions() Positions {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	positions := m.positions
+	positions.Cars = slices.Clone(positions.Cars)
+
+	return positions
+}
+
+// Outline returns the outline of the current track, a circle until it has been learned
+func (m *Mapper) Outline() Outline {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.current()
+}
+
+func (t *track) current() Outline {
+	outline := Outline{TrackID: t.id, TrackConfig: t.config, Learned: t.outline != nil, Points: slices.Clone(t.outline)}
+
+	if outline.Points == nil {
+		outline.Points = make([][2]float64, points)
+		for i := range outline.Points {
+			outline.Points[i] = circle(float64(i) / points)
+		}
+	}
+
+	return outline
+}
+
+// place returns where a lap distance is on the map, between the two nearest points of the outline
+func (t *track) place(pct float64) (float64, float64) {
+	if t.outline == nil {
+		p := circle(pct)
+		return p[0], p[1]
+	}
+
+	f := math.Mod(pct, 1) * float64(len(t.outline))
+	i := int(f)
+	a, b := t.outline[i%len(t.outline)], t.outline[(i+1)%len(t.outline)]
+	f -= float64(i)
+
+	return round(a[0] + (b[0]-a[0])*f), round(a[1] + (b[1]-a[1])*f)
+}
+
+// circle places a lap distance clockwise around a circle from the top, for a track without an outline
+func circle(pct float64) [2]float64 {
+	angle := 2 * math.Pi * pct
+
+	return [2]float64{round(0.5 + 0.5*math.Sin(angle)), round(0.5 - 0.5*math.Cos(angle))} //nolint:mnd // unit circle
+}
+
+func round(v float64) float64 {
+	return math.Round(v*precision) / precision
+}
@@ -0,0 +1,151 @@
+package trackmap
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	radius = 100.0 // m, of a round track
+	speed  = 20.0  // m/s
+	tick   = 0.1   // s between samples
+)
+
+// driver goes anticlockwise around a round track, updating the mapper every tick. lat and lon place the track on
+// the earth, or are 0 to leave the player to velocity alone.
+type driver struct {
+	m           *Mapper
+	sessionTime float64
+	lat, lon    float64
+}
+
+// drive laps starting at pct from
+func (d *driver) drive(t *testing.T, from float64, laps float64) {
+	t.Helper()
+
+	lapTime := 2 * math.Pi * radius / speed
+
+	for elapsed := 0.0; elapsed < laps*lapTime; elapsed += tick {
+		pct := math.Mod(from+elapsed/lapTime, 1)
+		angle := 2 * math.Pi * pct
+
+		player := Player{LapDistPct: pct, OnTrack: true, VelocityX: speed, Yaw: angle + math.Pi/2}
+
+		if d.lat != 0 {
+			player.Lat = d.lat + radius*math.Sin(angle)/earthRadius*180/math.Pi
+			player.Lon = d.lon + radius*math.Cos(angle)/(earthRadius*math.Cos(d.lat*math.Pi/180))*180/math.Pi
+			player.VelocityX = 0
+		}
+
+		d.update(t, &player)
+	}
+}
+
+func (d *driver) update(t *testing.T, player *Player) {
+	t.Helper()
+
+	d.sessionTime += tick
+
+	err := d.m.Update(&Reading{TrackID: 168, TrackConfig: "Grand Prix", SessionTime: d.sessionTime, Player: player})
+	require.NoError(t, err)
+}
+
+// assertRound checks the outline is a circle filling the map, anticlockwise from the right at the line
+func assertRound(t *testing.T, outline Outline) {
+	t.Helper()
+
+	require.True(t, outline.Learned)
+	require.Len(t, outline.Points, points)
+
+	for i, p := range outline.Points {
+		angle := 2 * math.Pi * float64(i) / points
+		assert.InDelta(t, 0.5+0.5*math.Cos(angle), p[0], 0.01, "x of point %d", i)
+		assert.InDelta(t, 0.5-0.5*math.Sin(angle), p[1], 0.01, "y of point %d", i)
+	}
+}
+
+func TestMapper(t *testing.T) {
+	t.Run("Cars should go around a circle until the outline is learned", func(t *testing.T) {
+		m := NewMapper("")
+
+		err := m.Update(&Reading{TrackID: 1, SessionTime: 10, Cars: []Car{
+			{CarIdx: 1, LapDistPct: 0}, {CarIdx: 2, LapDistPct: 0.25, OnPitRoad: true}, {CarIdx: 3, LapDistPct: -1},
+		}})
+		require.NoError(t, err)
+
+		expected := Positions{TrackID: 1, SessionTime: 10, Cars: []Position{
+			{CarIdx: 1, X: 0.5, Y: 0}, {CarIdx: 2, LapDistPct: 0.25, X: 1, Y: 0.5, OnPitRoad: true},
+		}}
+
+		assert.Equal(t, expected, m.Positions())
+
+		outline := m.Outline()
+		assert.False(t, outline.Learned)
+		assert.Len(t, outline.Points, points)
+	})
+
+	t.Run("The outline should be learned from a clean lap", func(t *testing.T) {
+		m := NewMapper("")
+		d := driver{m: m}
+		d.drive(t, 0.9, 1.05)
+		assert.False(t, m.Outline().Learned, "the lap is not complete")
+
+		d.drive(t, 0.95, 0.2)
+		assertRound(t, m.Outline())
+
+		require.NoError(t, m.Update(&Reading{TrackID: 168, TrackConfig: "Grand Prix", Cars: []Car{{CarIdx: 4, LapDistPct: 0.25}}}))
+
+		positions := m.Positions()
+		assert.True(t, positions.Learned)
+		assert.InDelta(t, 0.5, positions.Cars[0].X, 0.01)
+		assert.InDelta(t, 0, positions.Cars[0].Y, 0.01)
+	})
+
+	t.Run("The outline should be learned from latitude and longitude", func(t *testing.T) {
+		m := NewMapper("")
+		d := driver{m: m, lat: 34.84, lon: 136.54}
+		d.drive(t, 0.95, 1.1)
+
+		assertRound(t, m.Outline())
+	})
+
+	t.Run("A lap through the pits should not be learned", func(t *testing.T) {
+		m := NewMapper("")
+		d := driver{m: m}
+		d.drive(t, 0.95, 0.5)
+		d.update(t, &Player{LapDistPct: 0.45, OnTrack: true, OnPitRoad: true})
+		d.drive(t, 0.45, 0.6)
+		assert.False(t, m.Outline().Learned)
+	})
+
+	t.Run("Learned outlines should be kept for each track", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "trackmaps")
+
+		d := driver{m: NewMapper(dir)}
+		d.drive(t, 0.95, 1.1)
+		assert.FileExists(t, filepath.Join(dir, "168-grand-prix.json"))
+
+		m := NewMapper(dir)
+		require.NoError(t, m.Update(&Reading{TrackID: 168, TrackConfig: "Grand Prix"}))
+		assertRound(t, m.Outline())
+
+		require.NoError(t, m.Update(&Reading{TrackID: 168, TrackConfig: "East"}))
+		assert.False(t, m.Outline().Learned, "another configuration")
+	})
+
+	t.Run("A corrupt outline should be reported and learned again", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "1.json"), []byte("{"), 0o600))
+
+		m := NewMapper(dir)
+		err := m.Update(&Reading{TrackID: 1, Cars: []Car{{CarIdx: 1, LapDistPct: 0.5}}})
+		assert.ErrorContains(t, err, "1.json")
+
+		assert.Equal(t, []Position{{CarIdx: 1, LapDistPct: 0.5, X: 0.5, Y: 1}}, m.Positions().Cars)
+	})
+}
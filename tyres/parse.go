@@ -0,0 +1,94 @@
+package tyres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// kPaPerPSI and kPaPerBar convert pressures shown in imperial or bar
+	kPaPerPSI = 6.894757
+	kPaPerBar = 100
+)
+
+// quantity splits a setup value such as "165 kPa" or "78C" into its number and unit
+func quantity(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+
+	i := strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune("0123456789.-+", r) })
+	if i < 0 {
+		i = len(s)
+	}
+
+	v, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%q is not a number", s)
+	}
+
+	return v, strings.TrimSpace(s[i:]), nil
+}
+
+// across parses three values across the tread, e.g. "78C, 80C, 82C", converting each with convert
+func across(s string, convert func(float64, string) (float64, error)) ([3]float64, error) {
+	var values [3]float64
+
+	parts := strings.Split(s, ",")
+	if len(parts) != len(values) {
+		return values, fmt.Errorf("%q does not have %d values", s, len(values))
+	}
+
+	for i, part := range parts {
+		v, unit, err := quantity(part)
+		if err != nil {
+			return values, err
+		}
+
+		values[i], err = convert(v, unit)
+		if err != nil {
+			return values, fmt.Errorf("%q: %w", s, err)
+		}
+	}
+
+	return values, nil
+}
+
+// celsius converts a temperature shown in C or F
+func celsius(v float64, unit string) (float64, error) {
+	switch unit {
+	case "C":
+		return v, nil
+	case "F":
+		return (v - 32) * 5 / 9, nil //nolint:mnd // Fahrenheit
+	}
+
+	return 0, fmt.Errorf("unknown temperature unit %q", unit)
+}
+
+// percent reads a percentage of tread remaining
+func percent(v float64, unit string) (float64, error) {
+	if unit != "%" {
+		return 0, fmt.Errorf("unknown tread unit %q", unit)
+	}
+
+	return v, nil
+}
+
+// kPa parses a pressure shown in kPa, psi or bar
+func kPa(s string) (float64, error) {
+	v, unit, err := quantity(s)
+	if err != nil {
+		return 0, err
+	}
+
+	switch unit {
+	case "kPa":
+		return v, nil
+	case "psi":
+		return v * kPaPerPSI, nil
+	case "bar":
+		return v * kPaPerBar, nil
+	}
+
+	return 0, fmt.Errorf("unknown pressure unit %q", unit)
+}
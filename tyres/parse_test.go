@@ -0,0 +1,43 @@
+package tyres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcross(t *testing.T) {
+	t.Run("Temperatures should be read in Celsius", func(t *testing.T) {
+		temps, err := across("78C, 80C, 82C", celsius)
+		assert.NoError(t, err)
+		assert.Equal(t, [3]float64{78, 80, 82}, temps)
+
+		temps, err = across("212F, 32F, 50F", celsius)
+		assert.NoError(t, err)
+		assert.Equal(t, [3]float64{100, 0, 10}, temps)
+	})
+
+	t.Run("Tread should be read as a percentage", func(t *testing.T) {
+		tread, err := across("97%, 96%, 98.5%", percent)
+		assert.NoError(t, err)
+		assert.Equal(t, [3]float64{97, 96, 98.5}, tread)
+	})
+
+	t.Run("Anything else should be an error", func(t *testing.T) {
+		for _, s := range []string{"", "78C, 80C", "78K, 80C, 82C", "C, 80C, 82C"} {
+			_, err := across(s, celsius)
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestKPa(t *testing.T) {
+	for s, expected := range map[string]float64{"165 kPa": 165, "24.0 psi": 165.474168, "1.65 bar": 165} {
+		v, err := kPa(s)
+		assert.NoError(t, err, s)
+		assert.InDelta(t, expected, v, 0.0001, s)
+	}
+
+	_, err := kPa("165 mmHg")
+	assert.Error(t, err)
+}
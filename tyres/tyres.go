@@ -0,0 +1,235 @@
+// Package tyres keeps the history of the player's tyres by stint, from the measurements iRacing makes in the pits
+// and the live carcass temperatures, to plan tyre changes
+package tyres
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/ianhaycox/vcrlive/model"
+)
+
+// Corners of the car, in the order of every per corner array
+var Corners = [4]string{"lf", "rf", "lr", "rr"}
+
+// precision rounds temperatures, pressures and percentages to hundredths
+const precision = 100
+
+// Reading is the player's tyres at a point in time, as iRacing reports them. Values across the tread, live and in the
+// setup, are left to right as seen from the driver's seat, so outer to inner on the left tyres and inner to outer on
+// the right. The report turns them to inner, middle and outer.
+type Reading struct {
+	SubSessionID  int
+	SessionNum    int
+	LapsCompleted int
+	Setup         iryaml.CarSetup // measured by iRacing in the pits
+	Used          [4]int          // tyres fitted to each corner in the session
+	Temps         [4][3]float64   // carcass °C
+	Wear          [4][3]float64   // 0 to 1 of the tread remaining, updated in the pits
+	ColdPressure  [4]float64      // kPa
+}
+
+// Tracker keeps the stints of the current session. It is safe to read the report while it is updated.
+type Tracker struct {
+	mu sync.RWMutex
+	history
+}
+
+// history is what is known of the current session
+type history struct {
+	subSession  int
+	sessionNum  int
+	setupUpdate int  // UpdateCount of the setup last measured
+	read        bool // a reading has been recorded
+	live        [4]model.TyreCorner
+	stints      [4][]model.TyreStint
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{history: history{subSession: -1}}
+}
+
+// Update records a new reading, starting again when the session changes. The setup of a corner that can not be
+// parsed is returned as an error, the rest of the reading is still recorded.
+func (t *Tracker) Update(r *Reading) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r.SubSessionID != t.subSession || r.SessionNum != t.sessionNum {
+		t.history = history{subSession: r.SubSessionID, sessionNum: r.SessionNum, setupUpdate: -1}
+	}
+
+	t.read = true
+
+	for c := range Corners {
+		t.live[c] = model.TyreCorner{
+			Corner:       Corners[c],
+			Used:         r.Used[c],
+			Temps:        inwards(c, r.Temps[c], 1),
+			Wear:         inwards(c, r.Wear[c], 100), //nolint:mnd // percent
+			ColdPressure: round(r.ColdPressure[c]),
+		}
+
+		t.fit(c, r.Used[c], r.LapsCompleted)
+	}
+
+	if r.Setup.UpdateCount == t.setupUpdate {
+		return nil
+	}
+
+	t.setupUpdate = r.Setup.UpdateCount
+
+	var errs []error
+
+	for c, corner := range setupCorners(&r.Setup.TiresAero) {
+		m, err := corner.measure(c, r.LapsCompleted)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", Corners[c], err))
+			continue
+		}
+
+		t.measured(c, m)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Report returns the tyres now and every stint of the session, nil until the first reading
+func (t *Tracker) Report() *model.Tyres {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.read {
+		return nil
+	}
+
+	report := &model.Tyres{Live: append([]model.TyreCorner{}, t.live[:]...), Stints: []model.TyreStint{}}
+
+	for c := range Corners {
+		for _, s := range t.stints[c] {
+			s.Measurements = append([]model.TyreMeasurement{}, s.Measurements...)
+			s.WearPerLap, s.WorstPerLap = wearRates(s.Measurements)
+			report.Stints = append(report.Stints, s)
+		}
+	}
+
+	return report
+}
+
+// fit starts a stint when the tyres of corner c are changed, new tyres are assumed unworn
+func (h *history) fit(c int, used int, lap int) {
+	stints := h.stints[c]
+
+	switch {
+	case len(stints) == 0:
+		h.stints[c] = []model.TyreStint{{Corner: Corners[c], Set: used, FromLap: lap}}
+	case used > stints[len(stints)-1].Set:
+		h.stints[c] = append(stints, model.TyreStint{Corner: Corners[c], Set: used, FromLap: lap, Measurements: []model.TyreMeasurement{
+			{Lap: lap, Tread: [3]float64{100, 100, 100}, Fitted: true},
+		}})
+	}
+}
+
+// measured adds a measurement to the stint of corner c. One made as the tyres are changed is of the tyres taken off.
+func (h *history) measured(c int, m model.TyreMeasurement) {
+	stints := h.stints[c]
+	last := len(stints) - 1
+
+	if last > 0 && len(stints[last].Measurements) == 1 && stints[last].Measurements[0].Fitted && stints[last].FromLap == m.Lap {
+		last--
+	}
+
+	stints[last].Measurements = append(stints[last].Measurements, m)
+}
+
+// setupCorner is a corner of the setup, with its values across the tread left to right
+type setupCorner struct {
+	hotPressure string
+	temps       string
+	tread       string
+}
+
+func setupCorners(setup *iryaml.SetupTiresAero) [4]setupCorner {
+	return [4]setupCorner{
+		{setup.LeftFront.LastHotPressure, setup.LeftFront.LastTempsOMI, setup.LeftFront.TreadRemaining},
+		{setup.RightFront.LastHotPressure, setup.RightFront.LastTempsIMO, setup.RightFront.TreadRemaining},
+		{setup.LeftRear.LastHotPressure, setup.LeftRear.LastTempsOMI, setup.LeftRear.TreadRemaining},
+		{setup.RightRear.LastHotPressure, setup.RightRear.LastTempsIMO, setup.RightRear.TreadRemaining},
+	}
+}
+
+// measure reads corner c of the setup
+func (s setupCorner) measure(c int, lap int) (model.TyreMeasurement, error) {
+	temps, err := across(s.temps, celsius)
+	if err != nil {
+		return model.TyreMeasurement{}, err
+	}
+
+	tread, err := across(s.tread, percent)
+	if err != nil {
+		return model.TyreMeasurement{}, err
+	}
+
+	pressure, err := kPa(s.hotPressure)
+	if err != nil {
+		return model.TyreMeasurement{}, err
+	}
+
+	return model.TyreMeasurement{Lap: lap, Tread: inwards(c, tread, 1), Temps: inwards(c, temps, 1), HotPressure: round(pressure)}, nil
+}
+
+// wearRates returns the average and worst tread worn a lap between the first and last measurements
+func wearRates(measurements []model.TyreMeasurement) (float64, float64) {
+	if len(measurements) < 2 { //nolint:mnd // a rate needs two
+		return 0, 0
+	}
+
+	first, last := measurements[0], measurements[len(measurements)-1]
+
+	laps := float64(last.Lap - first.Lap)
+	if laps <= 0 {
+		return 0, 0
+	}
+
+	worn, worst := 0.0, 0.0
+
+	for i := range first.Tread {
+		w := first.Tread[i] - last.Tread[i]
+		worn += w
+		worst = max(worst, w)
+	}
+
+	return round(worn / float64(len(first.Tread)) / laps), round(worst / laps)
+}
+
+// inwards orders values read left to right across the tread as inner, middle and outer for corner c, scaled by scale
+func inwards(c int, values [3]float64, scale float64) [3]float64 {
+	if Corners[c][0] == 'l' {
+		values = reverse(values)
+	}
+
+	for i := range values {
+		values[i] *= scale
+	}
+
+	return rounds(values)
+}
+
+func reverse(values [3]float64) [3]float64 {
+	return [3]float64{values[2], values[1], values[0]}
+}
+
+func rounds(values [3]float64) [3]float64 {
+	for i := range values {
+		values[i] = round(values[i])
+	}
+
+	return values
+}
+
+func round(v float64) float64 {
+	return math.Round(v*precision) / precision
+}
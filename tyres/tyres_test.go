@@ -0,0 +1,165 @@
+package tyres
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ianhaycox/vcrlive/irsdk/iryaml"
+	"github.com/ianhaycox/vcrlive/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// setup returns the setup iRacing reports after a pit visit, every tyre worn to tread given inner, middle and outer
+// and hotter on the inside. As in iRacing the left tyres are reported outer first and the right tyres inner first.
+func setup(t *testing.T, updateCount int, tread [3]int) iryaml.CarSetup {
+	t.Helper()
+
+	in, mid, out := tread[0], tread[1], tread[2]
+
+	var carSetup iryaml.CarSetup
+
+	err := yaml.Unmarshal(fmt.Appendf(nil, `
+UpdateCount: %[1]d
+TiresAero:
+ LeftFront:
+  StartingPressure: 152 kPa
+  LastHotPressure: 170 kPa
+  LastTempsOMI: 80C, 85C, 90C
+  TreadRemaining: %[4]d%%, %[3]d%%, %[2]d%%
+ LeftRear:
+  StartingPressure: 152 kPa
+  LastHotPressure: 165 kPa
+  LastTempsOMI: 80C, 85C, 90C
+  TreadRemaining: %[4]d%%, %[3]d%%, %[2]d%%
+ RightFront:
+  StartingPressure: 152 kPa
+  LastHotPressure: 170 kPa
+  LastTempsIMO: 90C, 85C, 80C
+  TreadRemaining: %[2]d%%, %[3]d%%, %[4]d%%
+ RightRear:
+  StartingPressure: 152 kPa
+  LastHotPressure: 165 kPa
+  LastTempsIMO: 90C, 85C, 80C
+  TreadRemaining: %[2]d%%, %[3]d%%, %[4]d%%
+`, updateCount, in, mid, out), &carSetup)
+	require.NoError(t, err)
+
+	return carSetup
+}
+
+func TestTracker(t *testing.T) {
+	t.Run("There should be no report before the first reading", func(t *testing.T) {
+		assert.Nil(t, NewTracker().Report())
+	})
+
+	t.Run("Live values should be inner to outer", func(t *testing.T) {
+		tracker := NewTracker()
+		r := Reading{
+			Setup:        setup(t, 1, [3]int{100, 100, 100}),
+			Temps:        [4][3]float64{{70, 75, 80}, {70, 75, 80}},
+			Wear:         [4][3]float64{{0.97, 0.98, 0.99}, {0.97, 0.98, 0.99}},
+			ColdPressure: [4]float64{150.123},
+		}
+
+		require.NoError(t, tracker.Update(&r))
+
+		live := tracker.Report().Live
+		assert.Equal(t, model.TyreCorner{Corner: "lf", Temps: [3]float64{80, 75, 70}, Wear: [3]float64{99, 98, 97}, ColdPressure: 150.12}, live[0])
+		assert.Equal(t, model.TyreCorner{Corner: "rf", Temps: [3]float64{70, 75, 80}, Wear: [3]float64{97, 98, 99}}, live[1])
+	})
+
+	t.Run("Stints should run from one tyre change to the next", func(t *testing.T) {
+		tracker := NewTracker()
+		r := Reading{Setup: setup(t, 1, [3]int{100, 100, 100})}
+		require.NoError(t, tracker.Update(&r))
+
+		// Pit for fuel only
+		r.LapsCompleted = 10
+		r.Setup = setup(t, 2, [3]int{80, 85, 90})
+		require.NoError(t, tracker.Update(&r))
+
+		// Change the fronts, measuring the worn tyres as they come off
+		r.LapsCompleted = 20
+		r.Used = [4]int{1, 1, 0, 0}
+		require.NoError(t, tracker.Update(&r))
+
+		r.Setup = setup(t, 3, [3]int{60, 70, 80})
+		require.NoError(t, tracker.Update(&r))
+
+		r.LapsCompleted = 25
+		r.Setup = setup(t, 4, [3]int{58, 68, 78})
+		require.NoError(t, tracker.Update(&r))
+
+		stints := tracker.Report().Stints
+		require.Len(t, stints, 6)
+
+		lf, lfNew, lr := stints[0], stints[1], stints[4]
+
+		assert.Equal(t, 0, lf.Set)
+		assert.Equal(t, []int{0, 10, 20}, laps(lf.Measurements))
+		assert.Equal(t, [3]float64{60, 70, 80}, lf.Measurements[2].Tread, "inner first")
+		assert.Equal(t, [3]float64{90, 85, 80}, lf.Measurements[2].Temps)
+		assert.InDelta(t, 1.5, lf.WearPerLap, 0.001)
+		assert.InDelta(t, 2, lf.WorstPerLap, 0.001)
+
+		assert.Equal(t, 1, lfNew.Set)
+		assert.Equal(t, 20, lfNew.FromLap)
+		assert.Equal(t, []int{20, 25}, laps(lfNew.Measurements))
+		assert.True(t, lfNew.Measurements[0].Fitted)
+		assert.InDelta(t, 6.4, lfNew.WearPerLap, 0.001)
+		assert.InDelta(t, 8.4, lfNew.WorstPerLap, 0.001)
+
+		assert.Equal(t, []int{0, 10, 20, 25}, laps(lr.Measurements), "the rears were not changed")
+		assert.Equal(t, [3]float64{58, 68, 78}, lr.Measurements[3].Tread)
+		assert.InDelta(t, 165, lr.Measurements[3].HotPressure, 0.001)
+	})
+
+	t.Run("Every corner should be measured inner to outer", func(t *testing.T) {
+		tracker := NewTracker()
+		r := Reading{LapsCompleted: 5, Setup: setup(t, 1, [3]int{91, 94, 97})}
+		require.NoError(t, tracker.Update(&r))
+
+		for _, s := range tracker.Report().Stints {
+			assert.Equal(t, [3]float64{91, 94, 97}, s.Measurements[0].Tread, s.Corner)
+			assert.Equal(t, [3]float64{90, 85, 80}, s.Measurements[0].Temps, s.Corner)
+		}
+	})
+
+	t.Run("A corner that can not be parsed should be an error", func(t *testing.T) {
+		tracker := NewTracker()
+		r := Reading{Setup: setup(t, 1, [3]int{100, 100, 100})}
+		r.Setup.TiresAero.RightRear.TreadRemaining = ""
+
+		err := tracker.Update(&r)
+		assert.ErrorContains(t, err, "rr:")
+
+		stints := tracker.Report().Stints
+		assert.Len(t, stints[0].Measurements, 1)
+		assert.Empty(t, stints[3].Measurements)
+	})
+
+	t.Run("A new session should start again", func(t *testing.T) {
+		tracker := NewTracker()
+		r := Reading{Setup: setup(t, 1, [3]int{100, 100, 100})}
+		require.NoError(t, tracker.Update(&r))
+
+		r.SessionNum++
+		r.Setup.UpdateCount = 5
+		r.LapsCompleted = 0
+		require.NoError(t, tracker.Update(&r))
+
+		assert.Len(t, tracker.Report().Stints[0].Measurements, 1)
+	})
+}
+
+func laps(measurements []model.TyreMeasurement) []int {
+	var l []int
+
+	for _, m := range measurements {
+		l = append(l, m.Lap)
+	}
+
+	return l
+}